@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Tool middleware annotating each returned content block with its audience and
+// priority, so clients can route them: prose is for the user and the model,
+// raw JSON and resource links for the model to act on. Blocks a handler
+// annotated itself are left alone.
+func annotationMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := next(ctx, request)
+		if result == nil {
+			return result, err
+		}
+		for i, content := range result.Content {
+			result.Content[i] = annotateContent(content)
+		}
+		return result, err
+	}
+}
+
+var (
+	forEveryone = []mcp.Role{mcp.RoleUser, mcp.RoleAssistant}
+	forModel    = []mcp.Role{mcp.RoleAssistant}
+)
+
+func annotateContent(content mcp.Content) mcp.Content {
+	switch c := content.(type) {
+	case mcp.TextContent:
+		if c.Annotations == nil {
+			if isJSONText(c.Text) {
+				c.Annotations = contentAnnotations(forModel, 0.8)
+			} else {
+				c.Annotations = contentAnnotations(forEveryone, 1)
+			}
+		}
+		return c
+	case mcp.ImageContent:
+		if c.Annotations == nil {
+			c.Annotations = contentAnnotations(forEveryone, 0.6)
+		}
+		return c
+	case mcp.ResourceLink:
+		if c.Annotations == nil {
+			c.Annotations = contentAnnotations(forModel, 0.4)
+		}
+		return c
+	}
+	return content
+}
+
+func contentAnnotations(audience []mcp.Role, priority float64) *mcp.Annotations {
+	return &mcp.Annotations{Audience: audience, Priority: &priority}
+}
+
+// Whether text is a JSON object or array, or JSON Lines of them, rather than prose
+func isJSONText(text string) bool {
+	text = strings.TrimSpace(text)
+	if isJSONValue(text) {
+		return true
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if !isJSONValue(line) {
+			return false
+		}
+	}
+	return true
+}
+
+func isJSONValue(text string) bool {
+	return (strings.HasPrefix(text, "{") || strings.HasPrefix(text, "[")) && json.Valid([]byte(text))
+}
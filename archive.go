@@ -0,0 +1,412 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	_ "modernc.org/sqlite" // Pure Go SQLite driver, so builds need no C toolchain
+)
+
+const (
+	// Fetches queued for the archive writer; when it falls this far behind,
+	// new fetches go unarchived rather than slowing tool calls down
+	archiveQueue = 256
+	// Characters of each archived body shown in reddit_archive_search text
+	archiveExcerptChars = 500
+	// Most results one archive search returns
+	maxArchiveResults = 100
+)
+
+// Every post and comment fetched, one row per version: a new row is added
+// when the author, title, body or removal state changes, so edits and
+// deletions don't erase what was there before
+const archiveSchema = `
+CREATE TABLE IF NOT EXISTS items (
+	name        TEXT NOT NULL,    -- t3_ (post) or t1_ (comment) fullname
+	kind        TEXT NOT NULL,    -- post or comment
+	subreddit   TEXT NOT NULL,
+	author      TEXT NOT NULL,
+	title       TEXT NOT NULL,    -- posts only
+	body        TEXT NOT NULL,    -- selftext of posts, body of comments
+	url         TEXT NOT NULL,
+	permalink   TEXT NOT NULL,
+	parent_id   TEXT NOT NULL,    -- comments only
+	score       INTEGER NOT NULL, -- as last seen
+	removal     TEXT NOT NULL,    -- why it's gone, or ''
+	created_utc INTEGER NOT NULL,
+	edited_utc  INTEGER NOT NULL,
+	first_seen  INTEGER NOT NULL, -- Unix seconds this version was first fetched
+	last_seen   INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS items_name ON items (name);
+CREATE INDEX IF NOT EXISTS items_created ON items (subreddit, created_utc);
+CREATE INDEX IF NOT EXISTS items_author ON items (author);
+`
+
+// Full-text index over the archive's text, kept in step with items by
+// triggers. Rows are only ever added and their text never changes, so
+// inserts are all it has to follow.
+const archiveIndexSchema = `
+CREATE VIRTUAL TABLE items_fts USING fts5 (
+	title, body, author, subreddit,
+	content = 'items', content_rowid = 'rowid', tokenize = 'porter unicode61'
+);
+CREATE TRIGGER items_fts_insert AFTER INSERT ON items BEGIN
+	INSERT INTO items_fts (rowid, title, body, author, subreddit) VALUES (new.rowid, new.title, new.body, new.author, new.subreddit);
+END;
+INSERT INTO items_fts (items_fts) VALUES ('rebuild');
+`
+
+// bm25 weights of the indexed columns: a match in a title counts for more
+// than one buried in a long body
+const archiveRanking = "bm25(items_fts, 4.0, 1.0, 1.0, 1.0)"
+
+// An item as fetched, waiting to be archived
+type archivedItem struct {
+	name, kind, subreddit, author, title, body, url, permalink, parentID, removal string
+	score                                                                         int
+	createdUTC, editedUTC                                                         int64
+}
+
+// The archive database and the queue feeding it; nil when archiving is off
+type archive struct {
+	db      *sql.DB
+	pending chan []archivedItem
+}
+
+var localArchive *archive
+
+// Open (or create) the archive at path and start writing fetches to it
+func openArchive(path string) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	// One connection: SQLite allows a single writer, and searches are short
+	db.SetMaxOpenConns(1)
+	for _, stmt := range []string{"PRAGMA journal_mode = WAL", "PRAGMA busy_timeout = 5000", archiveSchema} {
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			return fmt.Errorf("failed to set up archive %s: %w", path, err)
+		}
+	}
+	// Archives from before the index get it built from what they hold
+	var indexed int
+	if err := db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE name = 'items_fts'`).Scan(&indexed); err == nil && indexed == 0 {
+		err = execAll(db, archiveIndexSchema)
+	}
+	if err != nil {
+		db.Close()
+		return fmt.Errorf("failed to index archive %s: %w", path, err)
+	}
+	localArchive = &archive{db: db, pending: make(chan []archivedItem, archiveQueue)}
+	go localArchive.write()
+	return nil
+}
+
+// Run a script of statements in one transaction
+func execAll(db *sql.DB, script string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(script); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Queue the posts and comments of a decoded response for archiving
+func archiveResponse(out any) {
+	if localArchive == nil {
+		return
+	}
+	var items []archivedItem
+	switch v := out.(type) {
+	case *listing:
+		items = appendArchived(items, v, "")
+	case *commentsResponse:
+		items = appendArchived(items, &v.Post, "")
+		subreddit := ""
+		if len(v.Post.Children) > 0 {
+			subreddit = v.Post.Children[0].Data.Subreddit
+		}
+		items = appendArchived(items, &v.Comments, subreddit)
+	case *moreChildrenResponse:
+		items = appendArchived(items, &listing{Children: v.JSON.Data.Things}, "")
+	}
+	if len(items) == 0 {
+		return
+	}
+	select {
+	case localArchive.pending <- items:
+	default:
+		slog.Warn("Archive writer is behind, not archiving a response", "items", len(items))
+	}
+}
+
+// Append the posts and comments of l, replies included; comments lacking a
+// subreddit take the post's
+func appendArchived(items []archivedItem, l *listing, subreddit string) []archivedItem {
+	if l == nil {
+		return items
+	}
+	for i := range l.Children {
+		child := &l.Children[i]
+		it := &child.Data
+		a := archivedItem{
+			name:       it.Name,
+			author:     it.Author,
+			subreddit:  it.Subreddit,
+			url:        it.URL,
+			permalink:  it.Permalink,
+			score:      it.Score,
+			removal:    it.Removal,
+			createdUTC: int64(it.CreatedUTC),
+			editedUTC:  int64(it.Edited),
+		}
+		switch child.Kind {
+		case "t3":
+			a.kind, a.title, a.body = "post", it.Title, it.Selftext
+		case "t1":
+			a.kind, a.body, a.parentID = "comment", it.Body, it.ParentID
+			if a.subreddit == "" {
+				a.subreddit = subreddit
+			}
+		default:
+			continue
+		}
+		if a.name == "" {
+			a.name = child.Kind + "_" + it.ID
+		}
+		items = append(items, a)
+		items = appendArchived(items, it.Replies, a.subreddit)
+	}
+	return items
+}
+
+// Write queued fetches, one transaction per response
+func (a *archive) write() {
+	for items := range a.pending {
+		if err := a.store(items, time.Now().Unix()); err != nil {
+			slog.Warn("Failed to archive fetched items", "items", len(items), "error", err)
+		}
+	}
+}
+
+// Record items seen at now: refresh the latest version of each when its
+// content is unchanged, or add a version when it isn't
+func (a *archive) store(items []archivedItem, now int64) error {
+	tx, err := a.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, it := range items {
+		var rowid int64
+		var author, title, body, removal string
+		err := tx.QueryRow(`SELECT rowid, author, title, body, removal FROM items WHERE name = ? ORDER BY rowid DESC LIMIT 1`, it.name).
+			Scan(&rowid, &author, &title, &body, &removal)
+		switch {
+		case err == nil && author == it.author && title == it.title && body == it.body && removal == it.removal:
+			_, err = tx.Exec(`UPDATE items SET score = ?, edited_utc = ?, last_seen = ? WHERE rowid = ?`, it.score, it.editedUTC, now, rowid)
+		case err == nil || errors.Is(err, sql.ErrNoRows):
+			_, err = tx.Exec(`INSERT INTO items (name, kind, subreddit, author, title, body, url, permalink, parent_id, score, removal, created_utc, edited_utc, first_seen, last_seen)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				it.name, it.kind, strings.ToLower(it.subreddit), it.author, it.title, it.body, it.url, it.permalink, it.parentID,
+				it.score, it.removal, it.createdUTC, it.editedUTC, now, now)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+type archiveItemOutput struct {
+	Name       string `json:"name" jsonschema:"Fullname: t3_ for posts, t1_ for comments"`
+	Kind       string `json:"kind" jsonschema:"post or comment"`
+	Subreddit  string `json:"subreddit"`
+	Author     string `json:"author"`
+	Title      string `json:"title,omitempty"`
+	Body       string `json:"body,omitempty"`
+	URL        string `json:"url,omitempty"`
+	Permalink  string `json:"permalink,omitempty"`
+	ParentID   string `json:"parent_id,omitempty" jsonschema:"For comments, fullname of the post or comment replied to"`
+	Score      int    `json:"score" jsonschema:"Score when this version was last fetched"`
+	Removed    string `json:"removed,omitempty" jsonschema:"Why the item was gone in this version"`
+	CreatedUTC int64  `json:"created_utc"`
+	Created    string `json:"created"`
+	EditedUTC  int64  `json:"edited_utc,omitempty"`
+	FirstSeen  string `json:"first_seen" jsonschema:"When this version was first fetched, in RFC 3339 format"`
+	LastSeen   string `json:"last_seen" jsonschema:"When this version was last fetched, in RFC 3339 format"`
+	Versions   int    `json:"versions" jsonschema:"Versions archived for the item; more than 1 means it was edited, deleted or removed meanwhile"`
+	Match      string `json:"match,omitempty" jsonschema:"For a query, the part of the body it matched, terms in **bold**"`
+
+	firstSeen, lastSeen int64
+}
+
+// Output of reddit_archive_search
+type archiveOutput struct {
+	Items []archiveItemOutput `json:"items" jsonschema:"Best match first for a query, otherwise newest first; the latest version of each item unless history was asked for"`
+}
+
+// Search the local archive by subreddit, author, date and text
+func handleRedditArchiveSearch(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if localArchive == nil {
+		return toolError(invalidInput("the archive is off; start the server with --archive <file> to record what it fetches")), nil
+	}
+	args := request.GetArguments()
+	opts, err := newFormatOptions(args)
+	if err != nil {
+		return toolError(err), nil
+	}
+	dates, err := newDateRange(args, opts.loc)
+	if err != nil {
+		return toolError(err), nil
+	}
+
+	var where []string
+	var params []any
+	text, _ := args["query"].(string)
+	if text != "" {
+		where = append(where, "items_fts MATCH ?")
+		params = append(params, text)
+	}
+	if subreddit, _ := args["subreddit"].(string); subreddit != "" {
+		where = append(where, "items.subreddit = ?")
+		params = append(params, subredditKey(subreddit))
+	}
+	if opts.author != "" {
+		where = append(where, "items.author = ? COLLATE NOCASE")
+		params = append(params, opts.author)
+	}
+	switch kind, _ := args["kind"].(string); kind {
+	case "":
+	case "post", "comment":
+		where = append(where, "items.kind = ?")
+		params = append(params, kind)
+	default:
+		return toolError(invalidInput("kind must be post or comment")), nil
+	}
+	if !dates.after.IsZero() {
+		where = append(where, "items.created_utc >= ?")
+		params = append(params, dates.after.Unix())
+	}
+	if !dates.before.IsZero() {
+		where = append(where, "items.created_utc < ?")
+		params = append(params, dates.before.Unix())
+	}
+	history, _ := args["history"].(bool)
+	if !history {
+		where = append(where, "items.rowid IN (SELECT max(rowid) FROM items GROUP BY name)")
+	}
+	order := "items.created_utc DESC, items.rowid DESC"
+	switch sort, _ := args["sort"].(string); sort {
+	case "":
+		if text != "" {
+			order = archiveRanking
+		}
+	case "relevance":
+		if text == "" {
+			return toolError(invalidInput("sort relevance needs a query")), nil
+		}
+		order = archiveRanking
+	case "new":
+	default:
+		return toolError(invalidInput("sort must be relevance or new")), nil
+	}
+	limit := 25
+	if n, ok := args["limit"].(float64); ok {
+		limit = min(max(int(n), 1), maxArchiveResults)
+	}
+
+	// Matches of a query come with the stretch of body around them
+	match, from := "''", "items"
+	if text != "" {
+		match, from = "snippet(items_fts, 1, '**', '**', '...', 24)", "items JOIN items_fts ON items_fts.rowid = items.rowid"
+	}
+	query := `SELECT items.name, items.kind, items.subreddit, items.author, items.title, items.body, items.url, items.permalink, items.parent_id,
+		items.score, items.removal, items.created_utc, items.edited_utc, items.first_seen, items.last_seen,
+		(SELECT count(*) FROM items AS v WHERE v.name = items.name), ` + match + " FROM " + from
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY " + order + " LIMIT ?"
+	rows, err := localArchive.db.QueryContext(ctx, query, append(params, limit)...)
+	if err != nil {
+		// Malformed queries fail as syntax errors, or as filters on unknown columns
+		if msg := err.Error(); text != "" && (strings.Contains(msg, "fts5") || strings.Contains(msg, "no such column")) {
+			return toolError(invalidInput(`query %q isn't valid search syntax; quote words with punctuation, as in "c++" or "go-redis"`, text)), nil
+		}
+		return toolError(fmt.Errorf("archive search failed: %w", err)), nil
+	}
+	defer rows.Close()
+
+	out := &archiveOutput{Items: []archiveItemOutput{}}
+	for rows.Next() {
+		var it archiveItemOutput
+		if err := rows.Scan(&it.Name, &it.Kind, &it.Subreddit, &it.Author, &it.Title, &it.Body, &it.URL, &it.Permalink, &it.ParentID,
+			&it.Score, &it.Removed, &it.CreatedUTC, &it.EditedUTC, &it.firstSeen, &it.lastSeen, &it.Versions, &it.Match); err != nil {
+			return toolError(fmt.Errorf("archive search failed: %w", err)), nil
+		}
+		_, it.Created = createdTimes(float64(it.CreatedUTC))
+		_, it.FirstSeen = createdTimes(float64(it.firstSeen))
+		_, it.LastSeen = createdTimes(float64(it.lastSeen))
+		if it.Permalink != "" {
+			it.Permalink = permalinkURL(it.Permalink)
+		}
+		out.Items = append(out.Items, it)
+	}
+	if err := rows.Err(); err != nil {
+		return toolError(fmt.Errorf("archive search failed: %w", err)), nil
+	}
+	return newStructuredResult(out, formatArchiveResults(out, history, opts)), nil
+}
+
+func formatArchiveResults(out *archiveOutput, history bool, opts formatOptions) string {
+	if len(out.Items) == 0 {
+		return "No archived posts or comments match.\n"
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Found %d archived items:\n\n", len(out.Items))
+	for i, it := range out.Items {
+		fmt.Fprintf(&sb, "%d. %s %s in r/%s by %s | %s | %d points\n", i+1, strings.ToUpper(it.Kind[:1])+it.Kind[1:], it.Name, it.Subreddit, userLabel(it.Author),
+			formatUnixTime(it.CreatedUTC, opts.loc), it.Score)
+		if it.Title != "" {
+			fmt.Fprintf(&sb, "   Title: %s\n", it.Title)
+		}
+		body := it.Body
+		if it.Removed != "" {
+			body = "[" + it.Removed + "]"
+		}
+		if body != "" {
+			excerpt := truncateAt(body, archiveExcerptChars)
+			if len(excerpt) < len(body) {
+				excerpt += "..."
+			}
+			writeIndented(&sb, opts.body(excerpt), "   ")
+			sb.WriteString("\n")
+		}
+		if it.Match != "" && len(it.Body) > archiveExcerptChars {
+			fmt.Fprintf(&sb, "   Match: %s\n", strings.Join(strings.Fields(it.Match), " "))
+		}
+		fmt.Fprintf(&sb, "   Archived: first fetched %s, last %s", formatUnixTime(it.firstSeen, opts.loc), formatUnixTime(it.lastSeen, opts.loc))
+		if it.Versions > 1 && !history {
+			fmt.Fprintf(&sb, " | %d versions; pass history true to see them", it.Versions)
+		}
+		sb.WriteString("\n")
+		if it.Permalink != "" {
+			fmt.Fprintf(&sb, "   Link: %s\n", it.Permalink)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
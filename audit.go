@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// One line of the audit log
+type auditRecord struct {
+	Time       time.Time      `json:"time"`
+	Session    string         `json:"session,omitempty"`
+	Tool       string         `json:"tool"`
+	Arguments  map[string]any `json:"arguments,omitempty"`
+	Endpoints  []string       `json:"endpoints,omitempty"`
+	Outcome    string         `json:"outcome"`
+	Error      string         `json:"error,omitempty"`
+	DurationMS int64          `json:"duration_ms"`
+}
+
+// Append-only JSONL writer; reopens when the configured path changes on reload
+type auditLog struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+var auditor = &auditLog{}
+
+func (a *auditLog) write(path string, record *auditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.file == nil || a.path != path {
+		if a.file != nil {
+			a.file.Close()
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			a.file = nil
+			return fmt.Errorf("failed to open audit log: %w", err)
+		}
+		a.file, a.path = f, path
+	}
+
+	_, err = a.file.Write(append(line, '\n'))
+	return err
+}
+
+// Written in place of secrets in audited arguments
+const redacted = "[redacted]"
+
+// Copy of a call's arguments with secrets blanked out, so the audit log never
+// holds webhook secrets, tokens or passwords; nested arguments, such as
+// reddit_batch queries, are redacted too
+func redactArguments(args map[string]any) map[string]any {
+	if args == nil {
+		return nil
+	}
+	out := make(map[string]any, len(args))
+	for key, value := range args {
+		switch {
+		case secretArgument(key):
+			out[key] = redacted
+		case key == "webhook":
+			text, _ := value.(string)
+			out[key] = redactURL(text)
+		default:
+			out[key] = redactValue(value)
+		}
+	}
+	return out
+}
+
+func redactValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		return redactArguments(v)
+	case []any:
+		out := make([]any, len(v))
+		for i, each := range v {
+			out[i] = redactValue(each)
+		}
+		return out
+	}
+	return value
+}
+
+// Whether an argument holds a credential by its name
+func secretArgument(key string) bool {
+	key = strings.ToLower(key)
+	for _, name := range []string{"password", "secret", "token"} {
+		if key == name || strings.HasSuffix(key, "_"+name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Only the scheme and host of a webhook URL: Slack, Discord and similar hooks
+// carry their token in the path or query
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		if raw == "" {
+			return ""
+		}
+		return redacted
+	}
+	if u.User == nil && (u.Path == "" || u.Path == "/") && u.RawQuery == "" {
+		return u.Scheme + "://" + u.Host
+	}
+	return u.Scheme + "://" + u.Host + "/" + redacted
+}
+
+// Reddit endpoints hit while handling one tool call
+type auditTrail struct {
+	mu        sync.Mutex
+	endpoints []string
+}
+
+type auditTrailKey struct{}
+
+// Note an upstream request against the tool call in ctx, if it is being audited
+func recordEndpoint(ctx context.Context, endpoint string) {
+	trail, ok := ctx.Value(auditTrailKey{}).(*auditTrail)
+	if !ok {
+		return
+	}
+	trail.mu.Lock()
+	trail.endpoints = append(trail.endpoints, endpoint)
+	trail.mu.Unlock()
+}
+
+// Tool middleware recording every call, its arguments, endpoints and outcome
+func auditMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		path := currentConfig().AuditLog
+		if path == "" {
+			return next(ctx, request)
+		}
+
+		trail := &auditTrail{}
+		start := time.Now()
+		result, err := next(context.WithValue(ctx, auditTrailKey{}, trail), request)
+
+		record := &auditRecord{
+			Time:       start.UTC(),
+			Tool:       request.Params.Name,
+			Arguments:  redactArguments(request.GetArguments()),
+			Outcome:    "ok",
+			DurationMS: time.Since(start).Milliseconds(),
+		}
+		if session := server.ClientSessionFromContext(ctx); session != nil {
+			record.Session = session.SessionID()
+		}
+		trail.mu.Lock()
+		record.Endpoints = trail.endpoints
+		trail.mu.Unlock()
+
+		switch {
+		case err != nil:
+			record.Outcome, record.Error = "failed", err.Error()
+		case result != nil && result.IsError:
+			record.Outcome = "tool_error"
+			for _, content := range result.Content {
+				if text, ok := content.(mcp.TextContent); ok {
+					record.Error = text.Text
+					break
+				}
+			}
+		}
+
+		if werr := auditor.write(path, record); werr != nil {
+			slog.ErrorContext(ctx, "Failed to write audit record", "error", werr)
+		}
+		return result, err
+	}
+}
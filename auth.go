@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// Require a known bearer token (or X-API-Key header) on every request.
+// Tokens are read from the live config so a reload takes effect immediately.
+func requireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens := currentConfig().AuthTokens
+		if len(tokens) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token := requestToken(r)
+		if token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="reddit-mcp"`)
+			http.Error(w, "missing bearer token", http.StatusUnauthorized)
+			return
+		}
+
+		if !validToken(token, tokens) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="reddit-mcp", error="invalid_token"`)
+			http.Error(w, "invalid bearer token", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Check token against the accepted set without leaking which one matched or their lengths
+func validToken(token string, tokens []string) bool {
+	sum := sha256.Sum256([]byte(token))
+	valid := 0
+	for _, t := range tokens {
+		digest := sha256.Sum256([]byte(t))
+		valid |= subtle.ConstantTimeCompare(sum[:], digest[:])
+	}
+	return valid == 1
+}
+
+// Extract the client's token from the Authorization or X-API-Key header; feed
+// readers that only know basic auth pass it as the password
+func requestToken(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		scheme, token, ok := strings.Cut(auth, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		if _, password, ok := r.BasicAuth(); ok {
+			return password
+		}
+		return ""
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
+
+type clientTokenKey struct{}
+
+// HTTP context function noting, by fingerprint, which accepted token a request
+// authenticated with, so what a client creates through tools stays its own
+func withClientToken(ctx context.Context, r *http.Request) context.Context {
+	if fingerprint := clientFingerprint(r); fingerprint != "" {
+		return context.WithValue(ctx, clientTokenKey{}, fingerprint)
+	}
+	return ctx
+}
+
+// Fingerprint of the token a request authenticated with; "" when the
+// transports don't require one
+func clientFingerprint(r *http.Request) string {
+	if len(currentConfig().AuthTokens) == 0 {
+		return ""
+	}
+	if token := requestToken(r); token != "" {
+		return tokenFingerprint(token)
+	}
+	return ""
+}
+
+// Fingerprint of the token the client calling in ctx authenticated with
+func clientToken(ctx context.Context) string {
+	fingerprint, _ := ctx.Value(clientTokenKey{}).(string)
+	return fingerprint
+}
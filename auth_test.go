@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireAuth(t *testing.T) {
+	tests := []struct {
+		name   string
+		tokens []string
+		header map[string]string
+		basic  string // password sent with basic auth
+		want   int
+	}{
+		{"no tokens configured", nil, nil, "", http.StatusOK},
+		{"missing token", []string{"s3cret"}, nil, "", http.StatusUnauthorized},
+		{"bearer", []string{"other", "s3cret"}, map[string]string{"Authorization": "Bearer s3cret"}, "", http.StatusOK},
+		{"bearer scheme in any case", []string{"s3cret"}, map[string]string{"Authorization": "bearer s3cret"}, "", http.StatusOK},
+		{"wrong bearer", []string{"s3cret"}, map[string]string{"Authorization": "Bearer nope"}, "", http.StatusUnauthorized},
+		{"api key", []string{"s3cret"}, map[string]string{"X-API-Key": "s3cret"}, "", http.StatusOK},
+		{"basic auth password", []string{"s3cret"}, nil, "s3cret", http.StatusOK},
+		{"unknown scheme", []string{"s3cret"}, map[string]string{"Authorization": "Token s3cret"}, "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := liveConfig.Swap(&config{AuthTokens: tt.tokens})
+			t.Cleanup(func() { liveConfig.Store(old) })
+
+			handler := requireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			r := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+			for k, v := range tt.header {
+				r.Header.Set(k, v)
+			}
+			if tt.basic != "" {
+				r.SetBasicAuth("reader", tt.basic)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+			if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without a WWW-Authenticate header")
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Most sub-requests one reddit_batch call may carry
+const maxBatchRequests = 10
+
+// Tools (by base name) reddit_batch can run; all are read-only lookups
+var batchTools = map[string]server.ToolHandlerFunc{
+	"search":          handleRedditSearch,
+	"post":            handleRedditPost,
+	"comments":        handleRedditComments,
+	"subreddit_posts": handleRedditSubredditPosts,
+}
+
+type batchResult struct {
+	Key        string `json:"key" jsonschema:"The sub-request's key, or its index when none was given"`
+	Tool       string `json:"tool"`
+	IsError    bool   `json:"is_error"`
+	Text       string `json:"text"`
+	Structured any    `json:"structured,omitempty" jsonschema:"The tool's structured output, when it succeeded"`
+}
+
+// Output of reddit_batch
+type batchOutput struct {
+	Results []batchResult `json:"results" jsonschema:"One result per sub-request, in request order"`
+}
+
+// A parsed sub-request
+type batchRequest struct {
+	key     string
+	base    string
+	handler server.ToolHandlerFunc
+	args    map[string]any
+}
+
+// Run several tool calls concurrently; Reddit requests still queue for the
+// shared upstream slots, so a batch can't exceed -max-concurrency
+func handleRedditBatch(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	requests, err := parseBatchRequests(request.GetArguments()["requests"])
+	if err != nil {
+		return toolError(err), nil
+	}
+
+	// Sub-calls don't report progress of their own; the batch reports one step per call
+	childCtx := context.WithValue(ctx, progressTrackerKey{}, nil)
+
+	// Sub-calls finish in any order, so each keeps its listing aside
+	results := make([]batchResult, len(requests))
+	listings := make([]listingRecord, len(requests))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	finished := 0
+	for i, sub := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runBatchRequest(context.WithValue(childCtx, listingRecordKey{}, &listings[i]), sub)
+
+			mu.Lock()
+			finished++
+			done := finished
+			mu.Unlock()
+			reportProgress(ctx, float64(done), float64(len(requests)), "Finished "+results[i].Key)
+		}()
+	}
+	wg.Wait()
+
+	// reddit_next_page continues the last listing in request order
+	for i := len(listings) - 1; i >= 0; i-- {
+		if listings[i].set {
+			lastListings.remember(ctx, listings[i].next)
+			break
+		}
+	}
+
+	var sb strings.Builder
+	for i, result := range results {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		status := ""
+		if result.IsError {
+			status = " (failed)"
+		}
+		fmt.Fprintf(&sb, "=== %s: %s%s ===\n%s\n", result.Key, result.Tool, status, strings.TrimRight(result.Text, "\n"))
+	}
+	return newStructuredResult(&batchOutput{Results: results}, sb.String()), nil
+}
+
+// Validate every sub-request up front so a typo fails the batch before any work
+func parseBatchRequests(raw any) ([]batchRequest, error) {
+	list, ok := raw.([]any)
+	if !ok || len(list) == 0 {
+		return nil, invalidInput("requests must be a non-empty array")
+	}
+	if len(list) > maxBatchRequests {
+		return nil, invalidInput("at most %d requests can be batched, got %d", maxBatchRequests, len(list))
+	}
+
+	cfg := currentConfig()
+	requests := make([]batchRequest, 0, len(list))
+	keys := make(map[string]bool)
+	for i, entry := range list {
+		fields, ok := entry.(map[string]any)
+		if !ok {
+			return nil, invalidInput("requests[%d] must be an object", i)
+		}
+		sub := batchRequest{key: fmt.Sprintf("%d", i)}
+		if key, _ := fields["key"].(string); key != "" {
+			sub.key = key
+		}
+		if keys[sub.key] {
+			return nil, invalidInput("requests[%d] repeats key %q", i, sub.key)
+		}
+		keys[sub.key] = true
+
+		tool, _ := fields["tool"].(string)
+		for base, handler := range batchTools {
+			if tool == base || tool == toolName(cfg, base) {
+				sub.base, sub.handler = base, handler
+			}
+		}
+		if sub.handler == nil || !toolEnabled(cfg, sub.base) {
+			return nil, invalidInput("requests[%d]: %q can't be batched; use one of %s", i, tool, batchableTools(cfg))
+		}
+
+		sub.args, _ = fields["arguments"].(map[string]any)
+		if sub.args == nil {
+			sub.args = map[string]any{}
+		}
+		requests = append(requests, sub)
+	}
+	return requests, nil
+}
+
+// Advertised names of the tools that can be batched under cfg
+func batchableTools(cfg *config) string {
+	var names []string
+	for _, base := range []string{"search", "subreddit_posts", "post", "comments"} {
+		if toolEnabled(cfg, base) {
+			names = append(names, toolName(cfg, base))
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+func runBatchRequest(ctx context.Context, sub batchRequest) batchResult {
+	out := batchResult{Key: sub.key, Tool: toolName(currentConfig(), sub.base)}
+
+	call := mcp.CallToolRequest{}
+	call.Params.Name = out.Tool
+	call.Params.Arguments = sub.args
+	// The middleware applies the sub-call's own output_format and output limits
+	result, err := toolMiddleware(sub.handler)(ctx, call)
+	if err != nil {
+		result = toolError(err)
+	}
+
+	var texts []string
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			texts = append(texts, text.Text)
+		}
+	}
+	out.Text = strings.Join(texts, "\n")
+	out.IsError = result.IsError
+	if !result.IsError {
+		out.Structured = result.StructuredContent
+	}
+	return out
+}
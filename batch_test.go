@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestBatchSubCalls(t *testing.T) {
+	// A stand-in listing tool that remembers its listing after a delay
+	listingTool := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := request.GetArguments()
+		delay, _ := args["delay"].(float64)
+		time.Sleep(time.Duration(delay) * time.Millisecond)
+		query, _ := args["query"].(string)
+		lastListings.remember(ctx, &listingCursor{Tool: "search", After: query})
+		out := &listingOutput{Posts: []postOutput{{ID: query, Title: "Post " + query}}}
+		return newStructuredResult(out, "text for "+query), nil
+	}
+	for _, base := range []string{"search", "subreddit_posts"} {
+		old := batchTools[base]
+		batchTools[base] = listingTool
+		t.Cleanup(func() { batchTools[base] = old })
+	}
+
+	call := mcp.CallToolRequest{}
+	call.Params.Arguments = map[string]any{"requests": []any{
+		map[string]any{"key": "slow", "tool": "search", "arguments": map[string]any{"query": "first", "delay": float64(50), "output_format": "json"}},
+		map[string]any{"key": "fast", "tool": "subreddit_posts", "arguments": map[string]any{"query": "second"}},
+	}}
+	result, err := handleRedditBatch(context.Background(), call)
+	if err != nil || result.IsError {
+		t.Fatalf("handleRedditBatch() = %v, %v", result, err)
+	}
+	out := result.StructuredContent.(*batchOutput)
+
+	if text := out.Results[0].Text; !strings.HasPrefix(text, "{") {
+		t.Errorf("sub-call output_format json ignored, text = %q", text)
+	}
+	if text := out.Results[1].Text; text != "text for second" {
+		t.Errorf("text = %q, want the handler's own", text)
+	}
+	// The fast call finishes first, but the second in request order wins
+	if next, _ := lastListings.last(context.Background()); next == nil || next.After != "second" {
+		t.Errorf("last listing = %+v, want the second request's", next)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"container/heap"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Characters set aside for the headers, summary and notes around a comment list
+const commentBudgetOverhead = 400
+
+// The max_output_chars argument of the comments tool
+func commentBudgetArgument() mcp.ToolOption {
+	return mcp.WithNumber("max_output_chars",
+		mcp.Description("Budget for the comment text: the highest-scoring comments are kept until it's spent, replies only under kept comments, and the rest are counted as omitted"),
+		mcp.Min(500),
+	)
+}
+
+// Rough formatted size of one comment, matching commentTreeSize
+func commentCost(comment *item) int {
+	return 160 + len(comment.Author) + len(comment.Body) + len(comment.Permalink)
+}
+
+// Comments waiting to be kept, highest score first
+type commentQueue []*item
+
+func (q commentQueue) Len() int           { return len(q) }
+func (q commentQueue) Less(i, j int) bool { return q[i].Score > q[j].Score }
+func (q commentQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *commentQueue) Push(x any)        { *q = append(*q, x.(*item)) }
+func (q *commentQueue) Pop() any {
+	old := *q
+	last := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return last
+}
+
+// Prune a comment tree down to maxDepth levels to fit budget characters,
+// keeping the highest-scoring comments whose parents are kept; returns a note
+// of how many were omitted ("" when everything fit)
+func fitCommentBudget(replies *listing, budget, maxDepth int) string {
+	var all []flatComment
+	flattenComments(&all, replies, "", 1, maxDepth)
+
+	depth := map[*item]int{}
+	queue := &commentQueue{}
+	enqueue := func(children *listing, level int) {
+		if children == nil || level > maxDepth {
+			return
+		}
+		for i := range children.Children {
+			if children.Children[i].Kind != "more" {
+				comment := &children.Children[i].Data
+				depth[comment] = level
+				heap.Push(queue, comment)
+			}
+		}
+	}
+	enqueue(replies, 1)
+
+	// Comments are kept by ID, since pruning moves them around
+	kept := map[string]bool{}
+	spent := commentBudgetOverhead
+	for queue.Len() > 0 {
+		comment := heap.Pop(queue).(*item)
+		if cost := commentCost(comment); spent+cost <= budget {
+			spent += cost
+			kept[comment.ID] = true
+			enqueue(comment.Replies, depth[comment]+1)
+		}
+	}
+
+	omitted := len(all) - len(kept)
+	if omitted <= 0 {
+		return ""
+	}
+	dropComments(replies, func(comment *item) bool {
+		return !kept[comment.ID]
+	})
+	return fmt.Sprintf("%d lower-scoring comments were omitted to stay within max_output_chars %d; raise it to see more.\n", omitted, budget)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// Upper bound on cached responses, so a long session can't grow the cache without limit
+	maxCacheEntries = 512
+	// Largest response body cached; bigger ones are decoded as they stream in
+	// without keeping a copy
+	maxCachedBodyBytes = 1 << 20
+)
+
+// A cached Reddit response body
+type cacheEntry struct {
+	body    []byte
+	expires time.Time
+}
+
+// TTL cache of raw Reddit responses keyed by request URL
+type responseCache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+var redditCache = &responseCache{entries: make(map[string]cacheEntry)}
+
+// Copy of a response body for the cache, dropped as soon as it outgrows
+// maxCachedBodyBytes; writes never fail so the decode isn't interrupted
+type bodyCopy struct {
+	buf      bytes.Buffer
+	overflow bool
+}
+
+func (c *bodyCopy) Write(p []byte) (int, error) {
+	if c.overflow {
+		return len(p), nil
+	}
+	if c.buf.Len()+len(p) > maxCachedBodyBytes {
+		c.overflow = true
+		c.buf = bytes.Buffer{}
+		return len(p), nil
+	}
+	return c.buf.Write(p)
+}
+
+// Return the cached body for key if it hasn't expired
+func (c *responseCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.body, true
+}
+
+// Store body under key for ttl, evicting expired (then arbitrary) entries when full
+func (c *responseCache) put(key string, body []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if len(c.entries) >= maxCacheEntries {
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	for k := range c.entries {
+		if len(c.entries) < maxCacheEntries {
+			break
+		}
+		delete(c.entries, k)
+	}
+
+	c.entries[key] = cacheEntry{body: body, expires: now.Add(ttl)}
+}
+
+type cacheRefreshKey struct{}
+
+// Mark ctx so requests skip cached responses and overwrite them with fresh ones
+func withCacheRefresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheRefreshKey{}, true)
+}
+
+func isCacheRefresh(ctx context.Context) bool {
+	refresh, _ := ctx.Value(cacheRefreshKey{}).(bool)
+	return refresh
+}
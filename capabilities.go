@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Output of reddit_capabilities; never includes credentials
+type capabilitiesOutput struct {
+	Version    string   `json:"version"`
+	Commit     string   `json:"commit,omitempty"`
+	BuildDate  string   `json:"build_date,omitempty"`
+	AuthMode   string   `json:"auth_mode" jsonschema:"anonymous, app_only, user or session (a token this session supplied)"`
+	WriteTools bool     `json:"write_tools" jsonschema:"Whether any tool can post, vote or otherwise change Reddit"`
+	Tools      []string `json:"tools" jsonschema:"Tools currently offered"`
+
+	Transport     string `json:"transport"`
+	TLS           bool   `json:"tls"`
+	Authenticated bool   `json:"authenticated" jsonschema:"Whether network clients must present a token"`
+
+	CacheTTLSeconds  int      `json:"cache_ttl_seconds" jsonschema:"0 when caching is off"`
+	PinnedSubreddits []string `json:"pinned_subreddits"`
+	Archive          bool     `json:"archive" jsonschema:"Whether fetched content is archived locally for offline search"`
+	StreamState      bool     `json:"stream_state" jsonschema:"Whether post_stream checkpoints survive restarts"`
+	Pullpush         bool     `json:"pullpush" jsonschema:"Whether search can use backend pullpush for historical posts"`
+	RSSFallback      bool     `json:"rss_fallback" jsonschema:"Whether listings and searches fall back to Reddit's RSS feeds when the API refuses"`
+
+	MaxOutputChars      int  `json:"max_output_chars"`
+	SummarizeOversized  bool `json:"summarize_oversized"`
+	MaxImages           int  `json:"max_images"`
+	SubscriptionPollSec int  `json:"subscription_poll_seconds" jsonschema:"0 when resource subscriptions aren't polled"`
+	WatchPollSec        int  `json:"watch_poll_seconds" jsonschema:"0 when watched subreddits aren't polled"`
+	TrackPollSec        int  `json:"track_poll_seconds" jsonschema:"0 when tracked posts aren't snapshotted"`
+	AuditLog            bool `json:"audit_log"`
+}
+
+// Report which features the live config enables, so agents can plan around them
+func handleRedditCapabilities(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	cfg := currentConfig()
+
+	out := &capabilitiesOutput{
+		Version:             version,
+		Commit:              commit,
+		BuildDate:           buildDate,
+		AuthMode:            "anonymous",
+		Tools:               []string{},
+		Transport:           cfg.Transport,
+		TLS:                 cfg.Transport != "stdio" && (cfg.TLSSelfSigned || cfg.TLSCert != ""),
+		Authenticated:       cfg.Transport != "stdio" && len(cfg.AuthTokens) > 0,
+		CacheTTLSeconds:     max(cfg.CacheTTL, 0),
+		PinnedSubreddits:    cfg.PinnedSubreddits,
+		MaxOutputChars:      cfg.MaxOutputChars,
+		SummarizeOversized:  cfg.SummarizeOversized && clientSupportsSampling(ctx),
+		MaxImages:           cfg.MaxImages,
+		SubscriptionPollSec: cfg.SubscriptionPoll,
+		WatchPollSec:        cfg.WatchPoll,
+		TrackPollSec:        cfg.TrackPoll,
+		AuditLog:            cfg.AuditLog != "",
+		Archive:             localArchive != nil,
+		StreamState:         cfg.StreamState != "",
+		Pullpush:            cfg.Pullpush != "",
+		RSSFallback:         cfg.RSSFallback,
+	}
+	if out.PinnedSubreddits == nil {
+		out.PinnedSubreddits = []string{}
+	}
+	switch {
+	case sessionRedditToken(ctx) != "":
+		out.AuthMode = "session"
+	case cfg.Reddit.configured() && cfg.Reddit.Username != "":
+		out.AuthMode = "user"
+	case cfg.Reddit.configured():
+		out.AuthMode = "app_only"
+	}
+	if s := server.ServerFromContext(ctx); s != nil {
+		localWrites := make(map[string]bool, len(localWriteTools))
+		for base := range localWriteTools {
+			localWrites[toolName(cfg, base)] = true
+		}
+		for name, tool := range s.ListTools() {
+			out.Tools = append(out.Tools, name)
+			// Tools keeping state in this server alone, such as watches and
+			// exports, don't count
+			readOnly, openWorld := tool.Tool.Annotations.ReadOnlyHint, tool.Tool.Annotations.OpenWorldHint
+			if (readOnly == nil || !*readOnly) && (openWorld == nil || *openWorld) && !localWrites[name] {
+				out.WriteTools = true
+			}
+		}
+	}
+	slices.Sort(out.Tools)
+
+	return newStructuredResult(out, formatCapabilities(out)), nil
+}
+
+func formatCapabilities(c *capabilitiesOutput) string {
+	onOff := func(on bool) string {
+		if on {
+			return "on"
+		}
+		return "off"
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Server version: %s", c.Version)
+	if c.Commit != "" {
+		fmt.Fprintf(&sb, " (%s)", c.Commit)
+	}
+	if c.BuildDate != "" {
+		fmt.Fprintf(&sb, " built %s", c.BuildDate)
+	}
+	sb.WriteString("\n")
+	fmt.Fprintf(&sb, "Reddit access: %s\n", c.AuthMode)
+	fmt.Fprintf(&sb, "Write tools: %s\n", onOff(c.WriteTools))
+	fmt.Fprintf(&sb, "Tools: %s\n", strings.Join(c.Tools, ", "))
+	fmt.Fprintf(&sb, "Transport: %s (TLS %s, client authentication %s)\n", c.Transport, onOff(c.TLS), onOff(c.Authenticated))
+	if c.CacheTTLSeconds > 0 {
+		fmt.Fprintf(&sb, "Cache: %d seconds", c.CacheTTLSeconds)
+		if len(c.PinnedSubreddits) > 0 {
+			fmt.Fprintf(&sb, ", pinned r/%s", strings.Join(c.PinnedSubreddits, ", r/"))
+		}
+		sb.WriteString("\n")
+	} else {
+		sb.WriteString("Cache: off\n")
+	}
+	fmt.Fprintf(&sb, "Archive: %s\n", onOff(c.Archive))
+	fmt.Fprintf(&sb, "Persistent post streams: %s\n", onOff(c.StreamState))
+	fmt.Fprintf(&sb, "Historical search (pullpush): %s\n", onOff(c.Pullpush))
+	fmt.Fprintf(&sb, "RSS fallback: %s\n", onOff(c.RSSFallback))
+	fmt.Fprintf(&sb, "Output limit: %d characters (summarizing oversized threads %s)\n", c.MaxOutputChars, onOff(c.SummarizeOversized))
+	fmt.Fprintf(&sb, "Images per post: %d\n", c.MaxImages)
+	if c.SubscriptionPollSec > 0 {
+		fmt.Fprintf(&sb, "Feed subscription polling: every %d seconds\n", c.SubscriptionPollSec)
+	} else {
+		sb.WriteString("Feed subscription polling: off\n")
+	}
+	if c.WatchPollSec > 0 {
+		fmt.Fprintf(&sb, "Watch polling: every %d seconds\n", c.WatchPollSec)
+	} else {
+		sb.WriteString("Watch polling: off\n")
+	}
+	if c.TrackPollSec > 0 {
+		fmt.Fprintf(&sb, "Post tracking: snapshots every %d seconds\n", c.TrackPollSec)
+	} else {
+		sb.WriteString("Post tracking: off\n")
+	}
+	fmt.Fprintf(&sb, "Audit log: %s\n", onOff(c.AuditLog))
+	return sb.String()
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestCapabilitiesWriteTools(t *testing.T) {
+	noop := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText(""), nil
+	}
+	tests := []struct {
+		name  string
+		tools []mcp.Tool
+		want  bool
+	}{
+		{"read-only", []mcp.Tool{mcp.NewTool("search", readOnlyTool("Search"))}, false},
+		{"local state", []mcp.Tool{mcp.NewTool("watch_add", localStateTool("Add a watch", false, false))}, false},
+		{"reads Reddit, writes locally", []mcp.Tool{mcp.NewTool("export_thread", localWriteTool("Export"))}, false},
+		{"writes Reddit", []mcp.Tool{mcp.NewTool("reply", mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			ReadOnlyHint: mcp.ToBoolPtr(false), OpenWorldHint: mcp.ToBoolPtr(true),
+		}))}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := server.NewMCPServer("test", "0", server.WithToolCapabilities(true))
+			s.AddTool(mcp.NewTool("capabilities", readOnlyTool("Capabilities")), handleRedditCapabilities)
+			for _, tool := range tt.tools {
+				s.AddTool(tool, noop)
+			}
+			reply := s.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"capabilities"}}`))
+			response, ok := reply.(mcp.JSONRPCResponse)
+			if !ok {
+				t.Fatalf("reply = %#v", reply)
+			}
+			result := response.Result.(*mcp.CallToolResult)
+			if got := result.StructuredContent.(*capabilitiesOutput).WriteTools; got != tt.want {
+				t.Errorf("write_tools = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
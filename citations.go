@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// The shared citations argument of tools that render posts or comments
+func citationsArgument() mcp.ToolOption {
+	return mcp.WithBoolean("citations",
+		mcp.Description("Tag each post [r1], [r2]... and comment [c1], [c2]... and end with a legend mapping the tags to permalinks, for citing sources in a summary"),
+		mcp.DefaultBool(false),
+	)
+}
+
+// Citation tags handed out while rendering one result, in order
+type citations struct {
+	tags    map[string]string // fullname to tag
+	entries []citation
+	counts  map[byte]int // tags handed out per prefix
+}
+
+type citation struct {
+	tag, fullname, link string
+}
+
+func newCitations() *citations {
+	return &citations{tags: map[string]string{}, counts: map[byte]int{}}
+}
+
+// Tag for it, such as "[r1] ", the same each time it's cited; prefix is
+// 'r' for posts and 'c' for comments
+func (c *citations) cite(prefix byte, it *item) string {
+	fullname := it.Name
+	if fullname == "" {
+		kind := "t3_"
+		if prefix == 'c' {
+			kind = "t1_"
+		}
+		fullname = kind + it.ID
+	}
+	if tag, ok := c.tags[fullname]; ok {
+		return tag + " "
+	}
+	c.counts[prefix]++
+	tag := fmt.Sprintf("[%c%d]", prefix, c.counts[prefix])
+	c.tags[fullname] = tag
+	link := ""
+	if it.Permalink != "" {
+		link = permalinkURL(it.Permalink)
+	}
+	c.entries = append(c.entries, citation{tag, fullname, link})
+	return tag + " "
+}
+
+// Write the legend of every tag handed out
+func (c *citations) writeLegend(sb *strings.Builder) {
+	if len(c.entries) == 0 {
+		return
+	}
+	sb.WriteString("Sources:\n")
+	for _, e := range c.entries {
+		fmt.Fprintf(sb, "%s %s", e.tag, e.fullname)
+		if e.link != "" {
+			fmt.Fprintf(sb, " %s", e.link)
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n")
+}
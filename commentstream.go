@@ -0,0 +1,214 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// Comments read per poll of a stream, and the reply depth they're read to
+	maxStreamComments = 100
+	streamDepth       = 10
+	// Posts a session follows at once; the longest unread is forgotten first
+	maxStreamsPerSession = 20
+)
+
+// Where a session is in a post's comments: the newest comment it has seen
+type commentCheckpoint struct {
+	lastID string
+	polled time.Time
+}
+
+// The comment streams of each session, by post ID
+type commentStreams struct {
+	mu       sync.Mutex
+	sessions map[string]map[string]*commentCheckpoint
+}
+
+var streams = &commentStreams{sessions: make(map[string]map[string]*commentCheckpoint)}
+
+// Forget the streams of sessions when they disconnect
+func (s *commentStreams) addHooks(hooks *server.Hooks) {
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		s.mu.Lock()
+		delete(s.sessions, session.SessionID())
+		s.mu.Unlock()
+	})
+}
+
+// The newest comment ctx's session has seen on a post; "" when it hasn't
+// followed the post yet
+func (s *commentStreams) last(ctx context.Context, postID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c := s.sessions[listingSessionKey(ctx)][postID]; c != nil {
+		return c.lastID
+	}
+	return ""
+}
+
+// Move the session's checkpoint on a post forward to lastID
+func (s *commentStreams) advance(ctx context.Context, postID, lastID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := listingSessionKey(ctx)
+	posts := s.sessions[key]
+	if posts == nil {
+		posts = make(map[string]*commentCheckpoint)
+		s.sessions[key] = posts
+	}
+	c := posts[postID]
+	if c == nil {
+		if len(posts) >= maxStreamsPerSession {
+			var stalest string
+			for id, other := range posts {
+				if stalest == "" || other.polled.Before(posts[stalest].polled) {
+					stalest = id
+				}
+			}
+			delete(posts, stalest)
+		}
+		c = &commentCheckpoint{}
+		posts[postID] = c
+	}
+	if commentAfter(lastID, c.lastID) {
+		c.lastID = lastID
+	}
+	c.polled = time.Now()
+}
+
+// Forget the session's checkpoint on a post
+func (s *commentStreams) reset(ctx context.Context, postID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions[listingSessionKey(ctx)], postID)
+}
+
+// Whether comment ID a was posted after b. IDs are base-36 counters, so a
+// longer ID is newer and equal lengths compare as strings; any ID is after ""
+func commentAfter(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) > len(b)
+	}
+	return a > b
+}
+
+// Output of reddit_comment_stream
+type commentStreamOutput struct {
+	PostID   string          `json:"post_id"`
+	Since    string          `json:"since,omitempty" jsonschema:"ID of the newest comment seen before this call; absent on the first call for the post"`
+	Latest   string          `json:"latest,omitempty" jsonschema:"ID of the newest comment seen now, where the next call continues from"`
+	Comments []commentOutput `json:"comments" jsonschema:"Comments newer than since, oldest first"`
+}
+
+// Return the comments on a post made since this session's last call for it
+func handleRedditCommentStream(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	postID, _ := args["post_id"].(string)
+	postID = strings.TrimPrefix(postID, "t3_")
+	if postID == "" {
+		return toolError(invalidInput("post_id is required")), nil
+	}
+	opts, err := newFormatOptions(args)
+	if err != nil {
+		return toolError(err), nil
+	}
+	limit := maxStreamComments
+	if n, ok := args["limit"].(float64); ok {
+		limit = min(max(int(n), 1), maxStreamComments)
+	}
+	if reset, _ := args["reset"].(bool); reset {
+		streams.reset(ctx, postID)
+	}
+	since := streams.last(ctx, postID)
+
+	// Newest first and never from the cache, so each call sees what's new
+	var result commentsResponse
+	endpoint, params := commentsRequest(postID, "new", limit, streamDepth)
+	if err := makeRedditRequest(withCacheRefresh(ctx), endpoint, params, &result); err != nil {
+		return toolError(err), nil
+	}
+	// The checkpoint covers comments the filters hide, so they aren't reconsidered
+	latest := since
+	for _, comment := range commentItems(&result.Comments) {
+		if commentAfter(comment.ID, latest) {
+			latest = comment.ID
+		}
+	}
+	streams.advance(ctx, postID, latest)
+	redactCommentSpoilers(&result.Comments, opts)
+
+	// Filter the new comments on their own, so a reply isn't lost with an old
+	// parent the filters hide and notes only count what's new
+	var comments []flatComment
+	flattenComments(&comments, &result.Comments, "", 1, streamDepth)
+	comments = slices.DeleteFunc(comments, func(c flatComment) bool {
+		return !commentAfter(c.comment.ID, since)
+	})
+	fresh := &listing{}
+	for _, c := range comments {
+		comment := *c.comment
+		comment.Replies = nil
+		fresh.Children = append(fresh.Children, thing{Kind: "t1", Data: comment})
+	}
+	leftOut := filterComments(fresh, opts)
+	kept := make(map[string]bool, len(fresh.Children))
+	for _, child := range fresh.Children {
+		kept[child.Data.ID] = true
+	}
+	comments = slices.DeleteFunc(comments, func(c flatComment) bool {
+		return !kept[c.comment.ID]
+	})
+	slices.SortFunc(comments, func(a, b flatComment) int {
+		return cmp.Or(cmp.Compare(a.comment.CreatedUTC, b.comment.CreatedUTC), strings.Compare(a.comment.ID, b.comment.ID))
+	})
+
+	out := &commentStreamOutput{PostID: postID, Since: since, Latest: latest, Comments: []commentOutput{}}
+	all := &commentsOutput{}
+	appendComments(all, &result.Comments, "", 1, streamDepth)
+	byID := make(map[string]commentOutput, len(all.Comments))
+	for _, c := range all.Comments {
+		byID[c.ID] = c
+	}
+	for _, c := range comments {
+		out.Comments = append(out.Comments, byID[c.comment.ID])
+	}
+	return newStructuredResult(out, formatCommentStream(&result, postID, comments, since, opts)+leftOut), nil
+}
+
+func formatCommentStream(result *commentsResponse, postID string, comments []flatComment, since string, opts formatOptions) string {
+	var sb strings.Builder
+	writeThreadStatus(&sb, result)
+	switch {
+	case len(comments) == 0 && since == "":
+		fmt.Fprintf(&sb, "No comments on post %s yet. Call again to check for new ones.\n", postID)
+	case len(comments) == 0:
+		fmt.Fprintf(&sb, "No new comments on post %s since comment %s. Call again to check for more.\n", postID, since)
+	case since == "":
+		fmt.Fprintf(&sb, "Latest %d comments on post %s, oldest first; the next call returns only newer ones:\n\n", len(comments), postID)
+	default:
+		fmt.Fprintf(&sb, "%d new comments on post %s since comment %s, oldest first:\n\n", len(comments), postID, since)
+	}
+	for i, c := range comments {
+		fmt.Fprintf(&sb, "%d. %s", i+1, commentSummary(c.comment, opts))
+		if c.replyTo != "" {
+			fmt.Fprintf(&sb, " | reply to %s", userLabel(c.replyTo))
+		}
+		sb.WriteString(":\n")
+		writeIndented(&sb, opts.body(shownText(c.comment, c.comment.Body)), "   ")
+		sb.WriteString("\n")
+		writeCommentRef(&sb, c.comment, "   ")
+	}
+	if len(comments) > 0 {
+		opts.writeLegend(&sb)
+	}
+	return sb.String()
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Clients accept at most 100 completion values; a handful is plenty for names
+const maxSubredditCompletions = 10
+
+// Completes subreddit arguments of prompts and the subreddit feed template
+type subredditCompleter struct{}
+
+func (subredditCompleter) CompletePromptArgument(ctx context.Context, promptName string, argument mcp.CompleteArgument, _ mcp.CompleteContext) (*mcp.Completion, error) {
+	switch argument.Name {
+	case "subreddit":
+		return completeSubreddit(ctx, "", argument.Value), nil
+	case "subreddits":
+		// Complete the last entry of the comma-separated list, keeping the ones before it
+		head, last := "", argument.Value
+		if i := strings.LastIndex(last, ","); i >= 0 {
+			head, last = last[:i+1], last[i+1:]
+		}
+		trimmed := strings.TrimLeft(last, " ")
+		head += last[:len(last)-len(trimmed)]
+		return completeSubreddit(ctx, head, trimmed), nil
+	}
+	return &mcp.Completion{Values: []string{}}, nil
+}
+
+func (subredditCompleter) CompleteResourceArgument(ctx context.Context, uri string, argument mcp.CompleteArgument, _ mcp.CompleteContext) (*mcp.Completion, error) {
+	if uri == subredditFeedTemplate && argument.Name == "subreddit" {
+		return completeSubreddit(ctx, "", argument.Value), nil
+	}
+	return &mcp.Completion{Values: []string{}}, nil
+}
+
+// Suggest subreddit names starting with prefix: matching favorites first, then
+// Reddit's autocomplete. Each value is prepended with head.
+func completeSubreddit(ctx context.Context, head, prefix string) *mcp.Completion {
+	prefix = strings.TrimPrefix(prefix, "r/")
+	key := subredditKey(prefix)
+
+	var names []string
+	add := func(name string) {
+		for _, existing := range names {
+			if strings.EqualFold(existing, name) {
+				return
+			}
+		}
+		names = append(names, name)
+	}
+	for _, favorite := range currentConfig().FavoriteSubreddits {
+		if strings.HasPrefix(subredditKey(favorite), key) {
+			add(strings.TrimPrefix(favorite, "r/"))
+		}
+	}
+
+	if prefix != "" {
+		suggestions, err := fetchSubredditSuggestions(ctx, prefix)
+		if err != nil {
+			slog.WarnContext(ctx, "Subreddit autocomplete failed", "prefix", prefix, "error", err)
+		}
+		for _, name := range suggestions {
+			add(name)
+		}
+	}
+
+	completion := &mcp.Completion{Values: []string{}, Total: len(names)}
+	if len(names) > maxSubredditCompletions {
+		names = names[:maxSubredditCompletions]
+		completion.HasMore = true
+	}
+	for _, name := range names {
+		completion.Values = append(completion.Values, head+name)
+	}
+	return completion
+}
+
+// Query Reddit's subreddit autocomplete endpoint
+func fetchSubredditSuggestions(ctx context.Context, prefix string) ([]string, error) {
+	params := url.Values{}
+	params.Add("query", prefix)
+	params.Add("limit", "10")
+	params.Add("include_over_18", "false")
+	params.Add("include_profiles", "false")
+
+	var result struct {
+		Data struct {
+			Children []struct {
+				Kind string `json:"kind"`
+				Data struct {
+					DisplayName string `json:"display_name"`
+				} `json:"data"`
+			} `json:"children"`
+		} `json:"data"`
+	}
+	if err := makeRedditRequest(ctx, "/api/subreddit_autocomplete_v2.json", params, &result); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, child := range result.Data.Children {
+		if child.Kind == "t5" && child.Data.DisplayName != "" {
+			names = append(names, child.Data.DisplayName)
+		}
+	}
+	return names, nil
+}
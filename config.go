@@ -0,0 +1,374 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Runtime settings, read from an optional JSON config file and the command line
+type config struct {
+	ConfigFile  string `json:"-"`
+	ShowVersion bool   `json:"-"`
+
+	Transport string `json:"transport"`
+	Addr      string `json:"addr"`
+	Path      string `json:"path"`
+	BasePath  string `json:"base_path"`
+	LogLevel  string `json:"log_level"`
+
+	// Bearer tokens / API keys accepted on the network transports
+	AuthTokens     []string `json:"auth_tokens"`
+	AllowAnonymous bool     `json:"allow_anonymous"`
+
+	// TLS for the network transports
+	TLSCert       string `json:"tls_cert"`
+	TLSKey        string `json:"tls_key"`
+	TLSSelfSigned bool   `json:"tls_self_signed"`
+
+	// Browser origins and extra request headers allowed cross-origin
+	CORSOrigins []string `json:"cors_origins"`
+	CORSHeaders []string `json:"cors_headers"`
+
+	// Seconds between keepalive pings on open streams, and seconds a session may
+	// go without a message before it's closed (0 disables either; fixed at startup)
+	KeepAlive          int `json:"keep_alive"`
+	SessionIdleTimeout int `json:"session_idle_timeout"`
+
+	// Per-client request quota on the network transports (0 disables it)
+	ClientRate  int `json:"client_rate"`
+	ClientBurst int `json:"client_burst"`
+
+	// Simultaneous requests to Reddit across all clients (fixed at startup)
+	MaxConcurrency int `json:"max_concurrency"`
+
+	// Seconds to cache Reddit responses (0 disables caching), and subreddits kept warm
+	CacheTTL         int      `json:"cache_ttl"`
+	PinnedSubreddits []string `json:"pinned_subreddits"`
+
+	// Subreddits whose hot feeds are listed as MCP resources
+	FavoriteSubreddits []string `json:"favorite_subreddits"`
+
+	// Seconds between polls of subscribed feed resources (0 pauses polling)
+	SubscriptionPoll int `json:"subscription_poll"`
+
+	// Caps on upstream response bytes and formatted tool output (0 disables either)
+	MaxResponseBytes int64 `json:"max_response_bytes"`
+	MaxOutputChars   int   `json:"max_output_chars"`
+
+	// Post bodies longer than this are linked as a resource instead of inlined (0 always inlines)
+	InlineBodyChars int `json:"inline_body_chars"`
+
+	// Images attached to image and gallery posts (0 disables them)
+	MaxImages int `json:"max_images"`
+
+	// IANA timezone for times shown in tool output
+	Timezone string `json:"timezone"`
+
+	// Render Reddit markdown in post and comment bodies as plain text
+	StripMarkdown bool `json:"strip_markdown"`
+
+	// Clean flair and usernames of emoji and non-printable characters: "strip"
+	// drops them, "ascii" also folds accented letters to ASCII ("" leaves them be)
+	SanitizeNames string `json:"sanitize_names"`
+
+	// Show NSFW posts and their bodies unless a call says otherwise
+	IncludeNSFW bool `json:"include_nsfw"`
+
+	// Accounts whose comments exclude_bots drops, besides AutoModerator
+	BotAccounts []string `json:"bot_accounts"`
+
+	// Summarize oversized comment threads with the client's model instead of truncating
+	SummarizeOversized bool `json:"summarize_oversized"`
+
+	// Tools to advertise (empty for all) and tools to withhold
+	EnabledTools  []string `json:"enabled_tools"`
+	DisabledTools []string `json:"disabled_tools"`
+
+	// Prefix for advertised tool names, and per-tool names replacing it (keyed by base name)
+	ToolPrefix string            `json:"tool_prefix"`
+	ToolNames  map[string]string `json:"tool_names"`
+
+	// JSONL file recording every tool call (empty disables auditing)
+	AuditLog string `json:"audit_log"`
+
+	// SQLite database keeping every post and comment fetched (empty disables
+	// archiving); only read at startup
+	Archive string `json:"archive"`
+
+	// SQLite database keeping reddit_post_stream checkpoints across restarts
+	// (empty keeps them in memory)
+	StreamState string `json:"stream_state"`
+
+	// Directory reddit_export_thread writes files to (empty returns exports in
+	// the tool result instead)
+	ExportDir string `json:"export_dir"`
+
+	// Keyword and pattern watches on subreddits' new posts, and seconds
+	// between polls of the watched subreddits (0 pauses polling)
+	Watches   []watchRule `json:"watches"`
+	WatchPoll int         `json:"watch_poll"`
+
+	// Base URL of the pullpush.io archive searched with backend pullpush
+	// (empty disables it)
+	Pullpush string `json:"pullpush"`
+
+	// Read subreddit listings and searches from Reddit's RSS feeds when its
+	// JSON API refuses or rate limits the request
+	RSSFallback bool `json:"rss_fallback"`
+
+	// Seconds between snapshots of posts followed with reddit_track_post (0
+	// pauses tracking)
+	TrackPoll int `json:"track_poll"`
+
+	// Recurring search, subreddit_posts, post and comments calls on cron schedules
+	Jobs []jobSpec `json:"jobs"`
+
+	// Optional Reddit OAuth account used for API requests
+	Reddit redditAccount `json:"reddit"`
+
+	// Let network clients act as their own Reddit user with an X-Reddit-Access-Token header
+	SessionRedditTokens bool `json:"session_reddit_tokens"`
+}
+
+// The config currently in effect; replaced wholesale on reload
+var liveConfig atomic.Pointer[config]
+
+// Return the config currently in effect
+func currentConfig() *config {
+	if cfg := liveConfig.Load(); cfg != nil {
+		return cfg
+	}
+	return &config{}
+}
+
+// Flag value that can be repeated or given as a comma-separated list
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// Flag value of name=value pairs that can be repeated or comma-separated
+type stringMap map[string]string
+
+func (m *stringMap) String() string {
+	pairs := make([]string, 0, len(*m))
+	for key, value := range *m {
+		pairs = append(pairs, key+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (m *stringMap) Set(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("expected name=value, got %q", pair)
+		}
+		if *m == nil {
+			*m = make(map[string]string)
+		}
+		(*m)[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+	return nil
+}
+
+// Bind every flag to cfg, setting the defaults
+func newFlagSet(cfg *config, output io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("reddit_mcp_server", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.StringVar(&cfg.ConfigFile, "config", "", "JSON config file; reloaded on SIGHUP (command-line flags take precedence)")
+	fs.BoolVar(&cfg.ShowVersion, "version", false, "Print version information and exit")
+	fs.StringVar(&cfg.Transport, "transport", "stdio", "Transport to serve on: stdio, sse or http")
+	fs.StringVar(&cfg.Addr, "addr", ":8080", "Listen address for the sse and http transports")
+	fs.StringVar(&cfg.Path, "path", "", "URL path to mount the server on (defaults to /mcp for http and / for sse)")
+	fs.StringVar(&cfg.BasePath, "base-path", "", "Prefix for every HTTP route, including health checks, when mounted under a shared host (e.g. /reddit)")
+	fs.StringVar(&cfg.LogLevel, "log-level", "info", "Log level: debug, info, warn or error")
+	fs.Var((*stringList)(&cfg.AuthTokens), "auth-token", "Bearer token accepted on the sse and http transports (repeatable, or comma-separated)")
+	fs.BoolVar(&cfg.AllowAnonymous, "allow-anonymous", false, "Serve the sse and http transports without authentication")
+	fs.StringVar(&cfg.TLSCert, "tls-cert", "", "TLS certificate file for the sse and http transports")
+	fs.StringVar(&cfg.TLSKey, "tls-key", "", "TLS private key file for the sse and http transports")
+	fs.BoolVar(&cfg.TLSSelfSigned, "tls-self-signed", false, "Serve TLS with a generated self-signed certificate")
+	fs.Var((*stringList)(&cfg.CORSOrigins), "cors-origin", "Browser origin allowed to connect, or * for any (repeatable, or comma-separated)")
+	fs.Var((*stringList)(&cfg.CORSHeaders), "cors-header", "Extra request header allowed cross-origin (repeatable, or comma-separated)")
+	fs.IntVar(&cfg.KeepAlive, "keep-alive", 0, "Seconds between keepalive pings on sse and http streams, for proxies that drop quiet connections (0 disables them)")
+	fs.IntVar(&cfg.SessionIdleTimeout, "session-idle-timeout", 0, "Close sse and http sessions that send no message for this many seconds (0 keeps them open)")
+	fs.IntVar(&cfg.ClientRate, "client-rate", 60, "Requests per minute allowed for each client on the sse and http transports (0 for unlimited)")
+	fs.IntVar(&cfg.ClientBurst, "client-burst", 10, "Requests a client may make in a burst before -client-rate applies")
+	fs.IntVar(&cfg.MaxConcurrency, "max-concurrency", 4, "Maximum simultaneous requests to Reddit")
+	fs.IntVar(&cfg.CacheTTL, "cache-ttl", 60, "Seconds to cache Reddit responses (0 disables caching)")
+	fs.Int64Var(&cfg.MaxResponseBytes, "max-response-bytes", 8<<20, "Maximum size of a Reddit response body in bytes (0 for unlimited)")
+	fs.IntVar(&cfg.MaxOutputChars, "max-output-chars", 60000, "Maximum size of a tool's text output; longer output is truncated with a notice (0 for unlimited)")
+	fs.IntVar(&cfg.InlineBodyChars, "inline-body-chars", 2000, "Post bodies longer than this are shortened, with a link to the full post resource (0 to always inline; reddit_post's max_body_length overrides it)")
+	fs.IntVar(&cfg.MaxImages, "max-images", 4, "Maximum images returned with an image or gallery post (0 to return none)")
+	fs.StringVar(&cfg.Timezone, "timezone", "UTC", "IANA timezone for times shown in tool output, e.g. Europe/Berlin (tools also take a timezone argument)")
+	fs.BoolVar(&cfg.StripMarkdown, "strip-markdown", false, "Render Reddit markdown in post and comment bodies as plain text, for clients that show output verbatim")
+	fs.StringVar(&cfg.SanitizeNames, "sanitize-names", "", "Clean flair and usernames for terminals and CSV exports: strip drops emoji and non-printable characters, ascii also folds accented letters to ASCII")
+	fs.BoolVar(&cfg.IncludeNSFW, "include-nsfw", false, "Show NSFW posts and their bodies by default (calls can still pass include_nsfw)")
+	fs.BoolVar(&cfg.SummarizeOversized, "summarize-oversized", false, "Ask clients that support sampling to summarize comment threads over -max-output-chars instead of truncating them")
+	fs.Var((*stringList)(&cfg.BotAccounts), "bot-account", "Bot account whose comments are left out unless a call passes exclude_bots false; AutoModerator always is (repeatable, or comma-separated)")
+	fs.Var((*stringList)(&cfg.FavoriteSubreddits), "favorite-subreddit", "Subreddit whose hot feed is listed as an MCP resource (repeatable, or comma-separated)")
+	fs.IntVar(&cfg.SubscriptionPoll, "subscription-poll", 60, "Seconds between checks of subscribed subreddit feeds for new posts (0 pauses polling)")
+	fs.IntVar(&cfg.WatchPoll, "watch-poll", 300, "Seconds between checks of watched subreddits for new matching posts (0 pauses polling)")
+	fs.StringVar(&cfg.Pullpush, "pullpush", "https://api.pullpush.io", "Base URL of the pullpush.io archive that search can use for historical posts (empty disables it)")
+	fs.BoolVar(&cfg.RSSFallback, "rss-fallback", false, "Fall back to Reddit's RSS feeds, without scores or comment counts, when the JSON API refuses or rate limits a subreddit listing or search")
+	fs.IntVar(&cfg.TrackPoll, "track-poll", 300, "Seconds between snapshots of tracked posts' scores and comment counts (0 pauses tracking)")
+	fs.Var((*stringList)(&cfg.EnabledTools), "enable-tool", "Only offer this tool (repeatable, or comma-separated; default all tools)")
+	fs.Var((*stringList)(&cfg.DisabledTools), "disable-tool", "Don't offer this tool (repeatable, or comma-separated)")
+	fs.StringVar(&cfg.ToolPrefix, "tool-prefix", "reddit_", "Prefix for every tool name (e.g. reddit_ gives reddit_search)")
+	fs.Var((*stringMap)(&cfg.ToolNames), "tool-name", "Rename a tool, as base=name (e.g. search=find_reddit_posts; repeatable, or comma-separated)")
+	fs.BoolVar(&cfg.SessionRedditTokens, "session-reddit-tokens", false, "On the sse and http transports, use a Reddit OAuth access token sent by the client in the X-Reddit-Access-Token header for that session's requests")
+	fs.StringVar(&cfg.AuditLog, "audit-log", "", "Append a JSONL record of every tool call to this file")
+	fs.StringVar(&cfg.ExportDir, "export-dir", "", "Directory reddit_export_thread writes exported threads to (by default exports are returned in the tool result)")
+	fs.StringVar(&cfg.StreamState, "stream-state", "", "Keep the posts reddit_post_stream has handed out in this SQLite database, so restarts don't hand them out again")
+	fs.StringVar(&cfg.Archive, "archive", "", "Keep every post and comment fetched in this SQLite database, searchable with reddit_archive_search")
+	fs.Var((*stringList)(&cfg.PinnedSubreddits), "pin-subreddit", "Subreddit whose about and hot data is kept warm in the cache (repeatable, or comma-separated)")
+	return fs
+}
+
+// Parse the config file (if any) and command-line arguments into a config
+func parseConfig(args []string, output io.Writer) (*config, error) {
+	cfg := &config{}
+	fs := newFlagSet(cfg, output)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// With a config file, start from the defaults, overlay the file, then re-apply the flags
+	if cfg.ConfigFile != "" {
+		path := cfg.ConfigFile
+		cfg = &config{}
+		fs = newFlagSet(cfg, output)
+		if err := loadConfigFile(path, cfg); err != nil {
+			fmt.Fprintln(output, err)
+			return nil, err
+		}
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+	}
+
+	// Secrets can also come from the environment to keep them out of process listings
+	if env := os.Getenv("REDDIT_MCP_AUTH_TOKENS"); env != "" {
+		(*stringList)(&cfg.AuthTokens).Set(env)
+	}
+	cfg.Reddit.fromEnv()
+	if env := os.Getenv("REDDIT_MCP_TIMEZONE"); env != "" && !flagSet(fs, "timezone") {
+		cfg.Timezone = env
+	}
+
+	if err := cfg.validate(); err != nil {
+		fmt.Fprintln(output, err)
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Whether the named flag was given on the command line
+func flagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+	return set
+}
+
+// Decode a JSON config file over cfg
+func loadConfigFile(path string, cfg *config) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Check the settings are consistent
+func (cfg *config) validate() error {
+	switch cfg.Transport {
+	case "stdio", "sse", "http":
+	default:
+		return fmt.Errorf("unknown transport %q (expected stdio, sse or http)", cfg.Transport)
+	}
+
+	if cfg.Transport != "stdio" && len(cfg.AuthTokens) == 0 && !cfg.AllowAnonymous {
+		return fmt.Errorf("the %s transport requires -auth-token (or REDDIT_MCP_AUTH_TOKENS); pass -allow-anonymous to serve without authentication", cfg.Transport)
+	}
+
+	if cfg.ToolPrefix != "" && !validToolName(cfg.ToolPrefix) {
+		return fmt.Errorf("invalid tool prefix %q (use letters, digits, _, - or .)", cfg.ToolPrefix)
+	}
+	for base, name := range cfg.ToolNames {
+		if !validToolName(name) {
+			return fmt.Errorf("invalid name %q for tool %s (use up to 64 letters, digits, _, - or .)", name, base)
+		}
+	}
+
+	switch cfg.SanitizeNames {
+	case "", "strip", "ascii":
+	default:
+		return fmt.Errorf("unknown sanitize-names mode %q (expected strip or ascii)", cfg.SanitizeNames)
+	}
+
+	for _, rule := range cfg.Watches {
+		if _, err := rule.compile(); err != nil {
+			return fmt.Errorf("invalid watch on r/%s: %w", subredditKey(rule.Subreddit), err)
+		}
+	}
+
+	for _, spec := range cfg.Jobs {
+		if _, _, _, err := spec.compile(cfg); err != nil {
+			return fmt.Errorf("invalid job: %w", err)
+		}
+	}
+
+	if _, err := time.LoadLocation(cfg.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", cfg.Timezone)
+	}
+
+	if cfg.Pullpush != "" {
+		if u, err := url.Parse(cfg.Pullpush); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid pullpush URL %q (expected an http or https URL)", cfg.Pullpush)
+		}
+	}
+
+	if cfg.ExportDir != "" {
+		if info, err := os.Stat(cfg.ExportDir); err != nil || !info.IsDir() {
+			return fmt.Errorf("export directory %q is not a directory", cfg.ExportDir)
+		}
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+		return fmt.Errorf("invalid log level %q", cfg.LogLevel)
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Headers MCP clients need to send cross-origin
+var defaultCORSHeaders = []string{
+	"Authorization",
+	"Content-Type",
+	"X-API-Key",
+	redditTokenHeader,
+	"Mcp-Session-Id",
+	"Mcp-Protocol-Version",
+	"Last-Event-ID",
+}
+
+// Answer preflights and add CORS headers for the browser origins in the live config
+func withCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := currentConfig()
+		origin := r.Header.Get("Origin")
+		if origin == "" || !(slices.Contains(cfg.CORSOrigins, "*") || slices.Contains(cfg.CORSOrigins, origin)) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Set("Access-Control-Expose-Headers", "Mcp-Session-Id")
+
+		// Preflights carry no credentials, so answer them before authentication
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			h.Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+			h.Set("Access-Control-Allow-Headers", strings.Join(append(slices.Clone(defaultCORSHeaders), cfg.CORSHeaders...), ", "))
+			h.Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
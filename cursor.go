@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Position in a listing tool's results: the tool (by base name) and arguments that produced
+// it plus Reddit's after token. Cursors carry arguments rather than endpoints
+// so a crafted cursor can't make the server request arbitrary URLs.
+type listingCursor struct {
+	Tool  string         `json:"t"`
+	Args  map[string]any `json:"a"`
+	After string         `json:"n"`
+	Count int            `json:"c"`
+}
+
+// Opaque form handed to clients
+func (c *listingCursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (*listingCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, invalidInput("cursor is not valid")
+	}
+	c := &listingCursor{}
+	if err := json.Unmarshal(b, c); err != nil || c.After == "" {
+		return nil, invalidInput("cursor is not valid")
+	}
+	return c, nil
+}
+
+// Arguments for a call to the listing tool: those stored in the cursor
+// argument when one is given, otherwise the call's own
+func listingArguments(tool string, request mcp.CallToolRequest) (map[string]any, *listingCursor, error) {
+	raw, _ := request.GetArguments()["cursor"].(string)
+	if raw == "" {
+		return request.GetArguments(), nil, nil
+	}
+	c, err := decodeCursor(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.Tool != tool {
+		cfg := currentConfig()
+		return nil, nil, invalidInput("cursor belongs to %s, not %s", toolName(cfg, c.Tool), toolName(cfg, tool))
+	}
+	return c.Args, c, nil
+}
+
+// Continue from the cursor's position, if any
+func applyCursor(params url.Values, c *listingCursor) {
+	if c == nil {
+		return
+	}
+	params.Set("after", c.After)
+	params.Set("count", fmt.Sprintf("%d", c.Count))
+}
+
+// Cursor for the page after result, or nil at the end of the listing
+func nextCursor(tool string, args map[string]any, prev *listingCursor, result *listing) *listingCursor {
+	if result.After == "" {
+		return nil
+	}
+	count := len(result.Children)
+	if prev != nil {
+		count += prev.Count
+	}
+	next := &listingCursor{Tool: tool, Args: make(map[string]any, len(args)), After: result.After, Count: count}
+	for key, value := range args {
+		if key != "cursor" {
+			next.Args[key] = value
+		}
+	}
+	return next
+}
+
+// Text telling the model how to get the next page
+func formatNextCursor(c *listingCursor) string {
+	if c == nil {
+		return ""
+	}
+	return fmt.Sprintf("More results are available: call %s with cursor %q for the next page.\n", toolName(currentConfig(), c.Tool), c.encode())
+}
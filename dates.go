@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Pages of search results read looking for posts in a date range, since
+// Reddit can only narrow search to coarse buckets like "past month"
+const maxDatePages = 5
+
+// Creation time bounds; zero times leave that side open
+type dateRange struct {
+	after, before time.Time
+}
+
+func (r dateRange) set() bool {
+	return !r.after.IsZero() || !r.before.IsZero()
+}
+
+func (r dateRange) contains(post *item) bool {
+	created := time.Unix(int64(post.CreatedUTC), 0)
+	return (r.after.IsZero() || !created.Before(r.after)) && (r.before.IsZero() || created.Before(r.before))
+}
+
+func (r dateRange) String() string {
+	var bounds []string
+	if !r.after.IsZero() {
+		bounds = append(bounds, "from "+r.after.Format(time.RFC3339))
+	}
+	if !r.before.IsZero() {
+		bounds = append(bounds, "before "+r.before.Format(time.RFC3339))
+	}
+	return strings.Join(bounds, ", ")
+}
+
+func newDateRange(args map[string]any, loc *time.Location) (dateRange, error) {
+	var r dateRange
+	var err error
+	if r.after, err = parseDateArg(args, "after_date", loc); err != nil {
+		return r, err
+	}
+	if r.before, err = parseDateArg(args, "before_date", loc); err != nil {
+		return r, err
+	}
+	if !r.after.IsZero() && !r.before.IsZero() && !r.after.Before(r.before) {
+		return r, invalidInput("after_date must be earlier than before_date")
+	}
+	return r, nil
+}
+
+func parseDateArg(args map[string]any, name string, loc *time.Location) (time.Time, error) {
+	raw, _ := args[name].(string)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", raw, loc); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	return time.Time{}, invalidInput("%s must be a date like 2024-03-01 or an RFC 3339 time, not %q", name, raw)
+}
+
+// Narrow result to at most limit posts in r, reading further pages of the
+// same request until limit posts are found or maxDatePages have been read.
+// result is left holding the posts found and the position after the last page
+// read, or after the last post kept when the page held more. Returns a line
+// noting the range and how much was searched; when a later page fails, the
+// posts found so far are kept and the note says so.
+func filterDateRange(ctx context.Context, endpoint string, params url.Values, result *listing, r dateRange, limit int) string {
+	var found []thing
+	pages := 1
+	stopped := ""
+	for {
+		for _, child := range result.Children {
+			if r.contains(&child.Data) {
+				found = append(found, child)
+			}
+		}
+		if len(found) >= limit || result.After == "" || pages == maxDatePages {
+			break
+		}
+		// Newest-first results older than the range won't be followed by newer ones
+		if n := len(result.Children); n > 0 && params.Get("sort") == "new" && !r.after.IsZero() &&
+			time.Unix(int64(result.Children[n-1].Data.CreatedUTC), 0).Before(r.after) {
+			result.After = ""
+			break
+		}
+
+		params.Set("after", result.After)
+		page := listing{}
+		if err := makeRedditRequest(ctx, endpoint, params, &page); err != nil {
+			if ctx.Err() != nil {
+				stopped = cancelledNote("the results cover the pages searched until then")
+			} else {
+				stopped = fmt.Sprintf("The search stopped early when page %d failed (%v); the results cover the pages before it.\n", pages+1, err)
+			}
+			break
+		}
+		pages++
+		reportProgress(ctx, float64(pages), maxDatePages, fmt.Sprintf("Searched %d pages for the date range", pages))
+		*result = page
+	}
+	if len(found) > limit {
+		found = found[:limit]
+		result.After = found[limit-1].Data.Name
+	}
+	result.Children = found
+	return fmt.Sprintf("Date range %s; searched %d of up to %d pages.\n", r, pages, maxDatePages) + stopped
+}
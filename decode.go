@@ -0,0 +1,311 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+)
+
+// Response types that decode themselves from a token stream, so large
+// listings are read one child at a time instead of buffering the whole body
+type streamDecoder interface {
+	decodeFrom(dec *json.Decoder) error
+}
+
+// A Reddit listing: {"kind": "Listing", "data": {"after": ..., "children": [...]}}
+type listing struct {
+	After    string
+	Before   string
+	Children []thing
+}
+
+// A single listing child: a post (t3), comment (t1), or "more" stub
+type thing struct {
+	Kind string `json:"kind"`
+	Data item   `json:"data"`
+}
+
+// Fields of posts, comments and "more" stubs; each kind fills the subset it has
+type item struct {
+	ID               string     `json:"id"`
+	Name             string     `json:"name"`
+	ParentID         string     `json:"parent_id"` // comments and "more" stubs: t1_ or t3_ fullname
+	Author           string     `json:"author"`
+	Title            string     `json:"title"`
+	Selftext         string     `json:"selftext"`
+	Body             string     `json:"body"`
+	URL              string     `json:"url"`
+	Permalink        string     `json:"permalink"`
+	Score            int        `json:"score"`
+	ScoreHidden      bool       `json:"score_hidden"`     // comments only; score then reads 1
+	Controversiality int        `json:"controversiality"` // comments only; 1 when votes are evenly split
+	UpvoteRatio      float64    `json:"upvote_ratio"`
+	NumComments      int        `json:"num_comments"`
+	CreatedUTC       float64    `json:"created_utc"`
+	Edited           editedTime `json:"edited"`
+
+	// Who the author is in the community: flair, mod or admin, original poster
+	AuthorFlairText string `json:"author_flair_text"`
+	Distinguished   string `json:"distinguished"` // "moderator" or "admin" when speaking officially
+	IsSubmitter     bool   `json:"is_submitter"`
+
+	TotalAwardsReceived int     `json:"total_awards_received"`
+	AllAwardings        []award `json:"all_awardings"`
+
+	// Posts only: where and how the post is labelled
+	Subreddit     string `json:"subreddit"`
+	LinkFlairText string `json:"link_flair_text"`
+	Over18        bool   `json:"over_18"`
+	Spoiler       bool   `json:"spoiler"`
+	Stickied      bool   `json:"stickied"`
+
+	// Posts only: who took the post down, e.g. "moderator" or "deleted"
+	RemovedByCategory string `json:"removed_by_category"`
+
+	// Why the post or comment is gone, derived on decoding; "" if it isn't
+	Removal string `json:"-"`
+	// The gone text as archived by pullpush, when recover_removed found it
+	Recovered string `json:"-"`
+
+	// Posts only: whether the thread still takes comments and votes
+	Locked      bool `json:"locked"`
+	Archived    bool `json:"archived"`
+	ContestMode bool `json:"contest_mode"`
+
+	// Posts only: media for image and gallery posts
+	PostHint      string                   `json:"post_hint"`
+	IsGallery     bool                     `json:"is_gallery"`
+	Preview       *preview                 `json:"preview"`
+	GalleryData   *galleryData             `json:"gallery_data"`
+	MediaMetadata map[string]mediaMetadata `json:"media_metadata"`
+	Thumbnail     string                   `json:"thumbnail"` // a URL, or "self", "default", "nsfw" etc.
+	Media         *postMedia               `json:"secure_media"`
+
+	// Crossposts only: the original post, body and media included
+	CrosspostParentList []item `json:"crosspost_parent_list"`
+
+	// Search results only: other subreddits the same story was posted to,
+	// filled in by collapseDuplicates
+	AlsoIn []string `json:"-"`
+
+	// Comments only; Reddit sends "" instead of a listing when there are no replies
+	Replies *listing `json:"replies"`
+
+	// "more" stubs only
+	Count    int      `json:"count"`
+	Children []string `json:"children"`
+}
+
+// Reddit HTML-escapes text fields (&amp;, &lt;, &gt;) even in its JSON API;
+// decode them to the characters they stand for so entities never reach output
+func (it *item) UnmarshalJSON(b []byte) error {
+	type plain item // without this method, to avoid recursing
+	if err := json.Unmarshal(b, (*plain)(it)); err != nil {
+		return err
+	}
+	for _, field := range []*string{&it.Title, &it.Selftext, &it.Body, &it.LinkFlairText, &it.AuthorFlairText} {
+		*field = html.UnescapeString(*field)
+	}
+	if mode := currentConfig().SanitizeNames; mode != "" {
+		for _, field := range []*string{&it.Author, &it.AuthorFlairText, &it.LinkFlairText} {
+			*field = sanitizeName(*field, mode)
+		}
+	}
+	it.Removal = removalReason(it)
+	return nil
+}
+
+// Unix seconds of the last edit; Reddit sends false for content never edited
+type editedTime float64
+
+func (e *editedTime) UnmarshalJSON(b []byte) error {
+	var ts float64
+	if err := json.Unmarshal(b, &ts); err != nil {
+		// false, or anything else that isn't a timestamp
+		*e = 0
+		return nil
+	}
+	*e = editedTime(ts)
+	return nil
+}
+
+// An award given to a post or comment, count times
+type award struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Preview renditions of a post's link target; URLs are HTML-escaped
+type preview struct {
+	Images []struct {
+		Source imageSource `json:"source"`
+	} `json:"images"`
+}
+
+type imageSource struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// Order of a gallery's images, keyed into media_metadata
+type galleryData struct {
+	Items []struct {
+		MediaID string `json:"media_id"`
+	} `json:"items"`
+}
+
+// The original of a crosspost, or nil for other posts
+func (it *item) crosspostParent() *item {
+	if len(it.CrosspostParentList) == 0 {
+		return nil
+	}
+	return &it.CrosspostParentList[0]
+}
+
+// Hosted video or an embed from another site such as YouTube
+type postMedia struct {
+	RedditVideo *struct {
+		FallbackURL string `json:"fallback_url"`
+		Duration    int    `json:"duration"` // seconds
+		Height      int    `json:"height"`
+	} `json:"reddit_video"`
+	OEmbed *struct {
+		ProviderName string `json:"provider_name"`
+		Title        string `json:"title"`
+	} `json:"oembed"`
+}
+
+type mediaMetadata struct {
+	Status string `json:"status"`
+	Kind   string `json:"e"`
+	MIME   string `json:"m"`
+	Source struct {
+		URL string `json:"u"`
+	} `json:"s"`
+}
+
+// Response of the comments endpoint: the post listing followed by the comment listing
+type commentsResponse struct {
+	Post     listing
+	Comments listing
+}
+
+// Nested listings (comment replies) are small enough to decode in one go
+func (l *listing) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		return nil
+	}
+	var raw struct {
+		Data struct {
+			After    string  `json:"after"`
+			Before   string  `json:"before"`
+			Children []thing `json:"children"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	l.After, l.Before, l.Children = raw.Data.After, raw.Data.Before, raw.Data.Children
+	return nil
+}
+
+// Walk a listing object, decoding each child individually
+func (l *listing) decodeFrom(dec *json.Decoder) error {
+	return walkObject(dec, func(key string) error {
+		if key != "data" {
+			return skipValue(dec)
+		}
+		return walkObject(dec, func(key string) error {
+			switch key {
+			case "after":
+				return dec.Decode(&l.After)
+			case "before":
+				return dec.Decode(&l.Before)
+			case "children":
+				return walkArray(dec, func() error {
+					var child thing
+					if err := dec.Decode(&child); err != nil {
+						return err
+					}
+					l.Children = append(l.Children, child)
+					return nil
+				})
+			default:
+				return skipValue(dec)
+			}
+		})
+	})
+}
+
+func (r *commentsResponse) decodeFrom(dec *json.Decoder) error {
+	i := 0
+	err := walkArray(dec, func() error {
+		defer func() { i++ }()
+		switch i {
+		case 0:
+			return r.Post.decodeFrom(dec)
+		case 1:
+			return r.Comments.decodeFrom(dec)
+		default:
+			return skipValue(dec)
+		}
+	})
+	if err == nil && i < 2 {
+		return fmt.Errorf("expected post and comment listings, got %d", i)
+	}
+	return err
+}
+
+// Call field for each key of the next object; field must consume the value
+func walkObject(dec *json.Decoder, field func(key string) error) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return fmt.Errorf("unexpected object key %v", tok)
+		}
+		if err := field(key); err != nil {
+			return err
+		}
+	}
+	_, err := dec.Token()
+	return err
+}
+
+// Call elem for each element of the next array; elem must consume the value
+func walkArray(dec *json.Decoder, elem func() error) error {
+	if err := expectDelim(dec, '['); err != nil {
+		return err
+	}
+	for dec.More() {
+		if err := elem(); err != nil {
+			return err
+		}
+	}
+	_, err := dec.Token()
+	return err
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != want {
+		return fmt.Errorf("expected %v, got %v", want, tok)
+	}
+	return nil
+}
+
+// Discard the next value without decoding it into Go values
+func skipValue(dec *json.Decoder) error {
+	var skip json.RawMessage
+	return dec.Decode(&skip)
+}
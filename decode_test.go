@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// A listing of n posts shaped like Reddit's, with the fields decoding reads
+func listingJSON(n int) []byte {
+	children := make([]map[string]any, n)
+	for i := range children {
+		children[i] = map[string]any{
+			"kind": "t3",
+			"data": map[string]any{
+				"id":              fmt.Sprintf("p%d", i),
+				"name":            fmt.Sprintf("t3_p%d", i),
+				"author":          "gopher",
+				"title":           "Generics &amp; iterators in Go 1.23",
+				"selftext":        "Some &lt;body&gt; text that goes on for a while. ",
+				"url":             "https://example.com/post",
+				"permalink":       fmt.Sprintf("/r/golang/comments/p%d/title/", i),
+				"subreddit":       "golang",
+				"score":           100 + i,
+				"num_comments":    i,
+				"created_utc":     1.7e9 + float64(i),
+				"edited":          false,
+				"link_flair_text": "discussion",
+				"all_awardings":   []any{},
+			},
+		}
+	}
+	body, _ := json.Marshal(map[string]any{
+		"kind": "Listing",
+		"data": map[string]any{"after": "t3_next", "children": children},
+	})
+	return body
+}
+
+// A comments response with n top-level comments each carrying one reply
+func commentsJSON(n int) []byte {
+	comment := func(id, parent string, replies any) map[string]any {
+		return map[string]any{"kind": "t1", "data": map[string]any{
+			"id": id, "name": "t1_" + id, "parent_id": parent, "author": "gopher",
+			"body": "I agree &amp; disagree.", "score": 3, "created_utc": 1.7e9, "replies": replies,
+		}}
+	}
+	children := make([]any, n)
+	for i := range children {
+		id := fmt.Sprintf("c%d", i)
+		reply := comment(id+"r", "t1_"+id, "")
+		children[i] = comment(id, "t3_p0", map[string]any{"kind": "Listing", "data": map[string]any{"children": []any{reply}}})
+	}
+	body, _ := json.Marshal([]any{
+		json.RawMessage(listingJSON(1)),
+		map[string]any{"kind": "Listing", "data": map[string]any{"children": children}},
+	})
+	return body
+}
+
+func BenchmarkDecodeListing(b *testing.B) {
+	for _, n := range []int{25, 100} {
+		body := listingJSON(n)
+		b.Run(fmt.Sprintf("stream/%d", n), func(b *testing.B) {
+			b.SetBytes(int64(len(body)))
+			b.ReportAllocs()
+			for b.Loop() {
+				var result listing
+				if err := decodeResponse(bytes.NewReader(body), &result, 0); err != nil {
+					b.Fatal(err)
+				}
+				if len(result.Children) != n {
+					b.Fatalf("decoded %d children, want %d", len(result.Children), n)
+				}
+			}
+		})
+		// The whole-body path nested reply listings take, for comparison
+		b.Run(fmt.Sprintf("unmarshal/%d", n), func(b *testing.B) {
+			b.SetBytes(int64(len(body)))
+			b.ReportAllocs()
+			for b.Loop() {
+				var result listing
+				if err := json.Unmarshal(body, &result); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDecodeComments(b *testing.B) {
+	body := commentsJSON(200)
+	b.SetBytes(int64(len(body)))
+	b.ReportAllocs()
+	for b.Loop() {
+		var result commentsResponse
+		if err := decodeResponse(bytes.NewReader(body), &result, 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Streaming through the response size limit, as makeRedditRequest does
+func BenchmarkDecodeListingLimited(b *testing.B) {
+	body := listingJSON(100)
+	b.SetBytes(int64(len(body)))
+	b.ReportAllocs()
+	for b.Loop() {
+		var result listing
+		if err := decodeResponse(newLimitedReader(bytes.NewReader(body), 8<<20), &result, 8<<20); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFormatSearchResults(b *testing.B) {
+	var result listing
+	if err := json.Unmarshal(listingJSON(100), &result); err != nil {
+		b.Fatal(err)
+	}
+	opts := defaultFormatOptions()
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := formatSearchResults(&result, opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
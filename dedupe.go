@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Collapse search results sharing a link target or crosspost parent into their
+// first (best ranked) entry, recording the other subreddits on it
+func collapseDuplicates(result *listing) {
+	first := map[string]*item{}
+	kept := result.Children[:0]
+	for _, child := range result.Children {
+		post := &child.Data
+		keys := duplicateKeys(post)
+		var original *item
+		for _, key := range keys {
+			if original = first[key]; original != nil {
+				break
+			}
+		}
+		if original != nil {
+			if post.Subreddit != "" && post.Subreddit != original.Subreddit && !slices.Contains(original.AlsoIn, post.Subreddit) {
+				original.AlsoIn = append(original.AlsoIn, post.Subreddit)
+			}
+			continue
+		}
+		kept = append(kept, child)
+		for _, key := range keys {
+			first[key] = &kept[len(kept)-1].Data
+		}
+	}
+	result.Children = kept
+}
+
+// What makes two posts the same story: the original post's ID, and the link
+// target for posts linking off Reddit
+func duplicateKeys(post *item) []string {
+	id := post.ID
+	if parent := post.crosspostParent(); parent != nil {
+		id = parent.ID
+	}
+	keys := []string{"id:" + id}
+	if u, err := url.Parse(post.URL); err == nil && u.Host != "" && !strings.HasSuffix(u.Hostname(), "reddit.com") && !strings.HasSuffix(u.Hostname(), "redd.it") {
+		// Ignore the scheme and trailing slashes, which vary between submitters
+		keys = append(keys, "url:"+strings.ToLower(u.Host)+strings.TrimSuffix(u.Path, "/")+"?"+u.RawQuery)
+	}
+	return keys
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	maxDigestSubreddits = 10
+	// Top posts listed per subreddit by default, and at most
+	defaultDigestPosts = 5
+	maxDigestPosts     = 10
+	// Posts of each subreddit whose top comment is quoted
+	digestCommentPosts = 3
+	// Posts of each window sampled for topics
+	digestSampleSize = 100
+	// Topics listed in a digest
+	maxDigestTopics = 8
+	// Characters of a notable comment quoted in text output
+	digestQuoteChars = 300
+)
+
+// Digest windows, the longer period each is compared with to find emerging
+// topics, and how long a digest is reused before it's built again
+var digestWindows = map[string]struct {
+	label, baseline string
+	ttl             time.Duration
+}{
+	"day":   {"Daily", "week", 15 * time.Minute},
+	"week":  {"Weekly", "month", time.Hour},
+	"month": {"Monthly", "year", 6 * time.Hour},
+}
+
+// Built digests as JSON, keyed by their arguments and the session's token if
+// it has one; repeated requests within a window's TTL are answered from here
+// without touching Reddit
+var digestCache = &responseCache{entries: make(map[string]cacheEntry)}
+
+// Words too common to make a topic
+var digestStopwords = func() map[string]bool {
+	words := map[string]bool{}
+	for _, w := range strings.Fields(`about after again also any are because been before being but can cant could did
+		does dont for from get got had has have her here his how its just like made make many more most much new not now
+		off old one only other our out over own really same she should some still such than that the their them then
+		there these they thing things this those through too very was way were what when where which while who why will
+		with would year years you your all and into any anyone anything ask help need question thoughts using use want
+		what's i'm it's you're don't can't doesn't isn't`) {
+		words[w] = true
+	}
+	return words
+}()
+
+type digestComment struct {
+	PostID    string        `json:"post_id"`
+	PostTitle string        `json:"post_title"`
+	Comment   commentOutput `json:"comment"`
+}
+
+type digestSubreddit struct {
+	Name     string          `json:"name"`
+	Posts    []postOutput    `json:"posts" jsonschema:"Top posts of the window, highest score first"`
+	Comments []digestComment `json:"comments" jsonschema:"The top comment of each of the leading posts"`
+	Error    string          `json:"error,omitempty" jsonschema:"Why the subreddit couldn't be read; the rest of the digest is still filled in"`
+}
+
+type digestTopic struct {
+	Term       string   `json:"term"`
+	Posts      int      `json:"posts" jsonschema:"Posts of the window whose titles use the term"`
+	Baseline   float64  `json:"baseline" jsonschema:"Posts expected to use it at the rate of the longer period before"`
+	Subreddits []string `json:"subreddits"`
+}
+
+// Output of reddit_digest
+type digestOutput struct {
+	Window     string            `json:"window" jsonschema:"day, week or month"`
+	Generated  string            `json:"generated" jsonschema:"When the digest was built, in RFC 3339 format; repeated requests reuse it for a while"`
+	Subreddits []digestSubreddit `json:"subreddits"`
+	Topics     []digestTopic     `json:"topics" jsonschema:"Terms in more post titles this window than the longer period before it would predict, most surprising first"`
+	Cancelled  bool              `json:"cancelled,omitempty" jsonschema:"The call was cancelled part way; subreddits not read by then carry an error"`
+}
+
+// Title words counted by the number of posts using them
+type termCounts struct {
+	counts map[string]int
+	posts  int
+}
+
+func countTerms(posts []thing, skip string) termCounts {
+	tc := termCounts{counts: map[string]int{}, posts: len(posts)}
+	for i := range posts {
+		seen := map[string]bool{}
+		for _, word := range textTerms(posts[i].Data.Title) {
+			if word == skip || seen[word] {
+				continue
+			}
+			seen[word] = true
+			tc.counts[word]++
+		}
+	}
+	return tc
+}
+
+// The words of text worth counting: lowercased, without links, stopwords or
+// anything shorter than three letters. Terms like "c++" and "c#" stay whole.
+func textTerms(text string) []string {
+	var terms []string
+	for _, field := range strings.Fields(strings.ToLower(text)) {
+		if strings.Contains(field, "://") || strings.HasPrefix(field, "www.") {
+			continue
+		}
+		for _, word := range strings.FieldsFunc(field, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '+' && r != '#' && r != '\''
+		}) {
+			word = strings.Trim(word, "'")
+			if len([]rune(word)) >= 3 && !digestStopwords[word] {
+				terms = append(terms, word)
+			}
+		}
+	}
+	return terms
+}
+
+// What one subreddit contributes to a digest
+type digestPart struct {
+	sub              digestSubreddit
+	window, baseline termCounts
+}
+
+// Build a digest of the top posts, top comments and new topics of subreddits
+func handleRedditDigest(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	opts, err := newFormatOptions(args)
+	if err != nil {
+		return toolError(err), nil
+	}
+	var subreddits []string
+	if list, ok := args["subreddits"].([]any); ok {
+		for _, v := range list {
+			if name, _ := v.(string); subredditKey(name) != "" && !slices.Contains(subreddits, subredditKey(name)) {
+				subreddits = append(subreddits, subredditKey(name))
+			}
+		}
+	}
+	if len(subreddits) == 0 {
+		return toolError(invalidInput("subreddits needs at least one subreddit")), nil
+	}
+	if len(subreddits) > maxDigestSubreddits {
+		return toolError(invalidInput("a digest covers at most %d subreddits", maxDigestSubreddits)), nil
+	}
+	window, _ := args["window"].(string)
+	if window == "" {
+		window = "day"
+	}
+	period, ok := digestWindows[window]
+	if !ok {
+		return toolError(invalidInput("window must be day, week or month")), nil
+	}
+	perSub := defaultDigestPosts
+	if n, ok := args["posts_per_subreddit"].(float64); ok {
+		perSub = min(max(int(n), 1), maxDigestPosts)
+	}
+
+	// The filters shape the digest, so they're part of what it's cached under,
+	// as is the session's own token, which may see private subreddits
+	key := sessionCacheScope(ctx) + fmt.Sprintf("%s %s %d nsfw=%t stickied=%t bots=%t", window, strings.Join(subreddits, "+"), perSub, opts.includeNSFW, opts.excludeStickied, opts.excludeBots)
+	if opts.minScore != nil {
+		key += fmt.Sprintf(" min=%d", *opts.minScore)
+	}
+	var out digestOutput
+	if body, ok := digestCache.get(key); ok && json.Unmarshal(body, &out) == nil {
+		return newStructuredResult(&out, formatDigest(&out, opts)), nil
+	}
+
+	parts := make([]digestPart, len(subreddits))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	finished := 0
+	for i, subreddit := range subreddits {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			parts[i] = buildDigestPart(ctx, subreddit, window, period.baseline, perSub, opts)
+
+			mu.Lock()
+			finished++
+			done := finished
+			mu.Unlock()
+			reportProgress(ctx, float64(done), float64(len(subreddits)), "Read r/"+subreddit)
+		}()
+	}
+	wg.Wait()
+
+	// A cancelled digest covers the subreddits read before it; it isn't cached
+	out = digestOutput{Window: window, Topics: emergingTopics(parts), Cancelled: ctx.Err() != nil}
+	_, out.Generated = createdTimes(float64(time.Now().Unix()))
+	failed := 0
+	for _, part := range parts {
+		out.Subreddits = append(out.Subreddits, part.sub)
+		if part.sub.Error != "" {
+			failed++
+		}
+	}
+	switch {
+	case failed == len(parts) && out.Cancelled:
+		return toolError(fmt.Errorf("request cancelled: %w", ctx.Err())), nil
+	case failed == len(parts):
+		return toolError(fmt.Errorf("no subreddit could be read: %s", parts[0].sub.Error)), nil
+	}
+	// A digest missing a subreddit isn't kept, so the next request tries again
+	if body, err := json.Marshal(&out); err == nil && failed == 0 && !out.Cancelled {
+		digestCache.put(key, body, period.ttl)
+	}
+	return newStructuredResult(&out, formatDigest(&out, opts)), nil
+}
+
+func buildDigestPart(ctx context.Context, subreddit, window, baseline string, perSub int, opts formatOptions) digestPart {
+	part := digestPart{sub: digestSubreddit{Name: subreddit, Posts: []postOutput{}, Comments: []digestComment{}}}
+	top, err := topPosts(ctx, subreddit, window)
+	if err != nil {
+		part.sub.Error = err.Error()
+		return part
+	}
+	filterPosts(&top, opts)
+	part.window = countTerms(top.Children, subreddit)
+	if before, err := topPosts(ctx, subreddit, baseline); err == nil {
+		filterPosts(&before, opts)
+		part.baseline = countTerms(before.Children, subreddit)
+	}
+
+	for i := range top.Children[:min(perSub, len(top.Children))] {
+		part.sub.Posts = append(part.sub.Posts, *newPostOutput(&top.Children[i].Data, false))
+	}
+	for _, post := range part.sub.Posts[:min(digestCommentPosts, len(part.sub.Posts))] {
+		if c, ok := topComment(ctx, post.ID, opts); ok {
+			part.sub.Comments = append(part.sub.Comments, digestComment{PostID: post.ID, PostTitle: post.Title, Comment: c})
+		}
+	}
+	return part
+}
+
+// A subreddit's top posts over a period
+func topPosts(ctx context.Context, subreddit, period string) (listing, error) {
+	var result listing
+	endpoint, params := subredditListingRequest(subreddit, "top", digestSampleSize)
+	params.Set("t", period)
+	err := makeRedditRequest(ctx, endpoint, params, &result)
+	return result, err
+}
+
+// The highest-scoring top-level comment of a post that is still there and
+// not from a bot
+func topComment(ctx context.Context, postID string, opts formatOptions) (commentOutput, bool) {
+	var result commentsResponse
+	endpoint, params := commentsRequest(postID, "top", 10, 1)
+	if err := makeRedditRequest(ctx, endpoint, params, &result); err != nil {
+		return commentOutput{}, false
+	}
+	comments := &commentsOutput{}
+	appendComments(comments, &result.Comments, "", 1, 1)
+	bots := currentConfig().BotAccounts
+	comments.Comments = slices.DeleteFunc(comments.Comments, func(c commentOutput) bool {
+		return c.Removed != "" || (opts.excludeBots && isBot(c.Author, bots)) || c.Distinguished != ""
+	})
+	if len(comments.Comments) == 0 {
+		return commentOutput{}, false
+	}
+	return slices.MaxFunc(comments.Comments, func(a, b commentOutput) int { return cmp.Compare(a.Score, b.Score) }), true
+}
+
+// Terms used in more of the window's titles than the baseline period's rate
+// predicts, added up across subreddits
+func emergingTopics(parts []digestPart) []digestTopic {
+	byTerm := map[string]*digestTopic{}
+	for _, part := range parts {
+		for term, n := range part.window.counts {
+			topic := byTerm[term]
+			if topic == nil {
+				topic = &digestTopic{Term: term}
+				byTerm[term] = topic
+			}
+			topic.Posts += n
+			if part.baseline.posts > 0 {
+				topic.Baseline += float64(part.baseline.counts[term]) / float64(part.baseline.posts) * float64(part.window.posts)
+			}
+			topic.Subreddits = append(topic.Subreddits, part.sub.Name)
+		}
+	}
+	// How far above expectation, damped so one-off words don't lead
+	surprise := func(t *digestTopic) float64 {
+		return float64(t.Posts) / (t.Baseline + 1) * math.Log1p(float64(t.Posts))
+	}
+	var topics []*digestTopic
+	for _, topic := range byTerm {
+		if topic.Posts >= 2 && float64(topic.Posts) > 1.5*topic.Baseline {
+			topic.Baseline = math.Round(topic.Baseline*10) / 10
+			topics = append(topics, topic)
+		}
+	}
+	slices.SortFunc(topics, func(a, b *digestTopic) int {
+		return cmp.Or(cmp.Compare(surprise(b), surprise(a)), cmp.Compare(a.Term, b.Term))
+	})
+	out := []digestTopic{}
+	for _, topic := range topics[:min(maxDigestTopics, len(topics))] {
+		out = append(out, *topic)
+	}
+	return out
+}
+
+func formatDigest(out *digestOutput, opts formatOptions) string {
+	var sb strings.Builder
+	generated, _ := time.Parse(time.RFC3339, out.Generated)
+	fmt.Fprintf(&sb, "%s digest of r/", digestWindows[out.Window].label)
+	for i, sub := range out.Subreddits {
+		if i > 0 {
+			sb.WriteString(", r/")
+		}
+		sb.WriteString(sub.Name)
+	}
+	fmt.Fprintf(&sb, " | generated %s\n\n", formatUnixTime(generated.Unix(), opts.loc))
+
+	for _, sub := range out.Subreddits {
+		fmt.Fprintf(&sb, "r/%s\n", sub.Name)
+		if sub.Error != "" {
+			fmt.Fprintf(&sb, "Couldn't read this subreddit: %s\n\n", sub.Error)
+			continue
+		}
+		if len(sub.Posts) == 0 {
+			sb.WriteString("No posts this window.\n\n")
+			continue
+		}
+		sb.WriteString("Top posts:\n")
+		for i, post := range sub.Posts {
+			fmt.Fprintf(&sb, "%d. %s (%d points, %d comments) by %s\n   %s | Post ID: %s\n", i+1, post.Title, post.Score, post.NumComments,
+				userLabel(post.Author), post.Permalink, post.ID)
+		}
+		if len(sub.Comments) > 0 {
+			sb.WriteString("Notable comments:\n")
+			for _, c := range sub.Comments {
+				quote := strings.Join(strings.Fields(c.Comment.Body), " ")
+				if cut := truncateAt(quote, digestQuoteChars); len(cut) < len(quote) {
+					quote = cut + "..."
+				}
+				fmt.Fprintf(&sb, "- %s on %q (%d points): %s\n", userLabel(c.Comment.Author), c.PostTitle, c.Comment.Score, opts.body(quote))
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(out.Topics) > 0 {
+		sb.WriteString("Emerging topics:\n")
+		for _, topic := range out.Topics {
+			fmt.Fprintf(&sb, "- %s: %d posts (about %.1f expected) in r/%s\n", topic.Term, topic.Posts, topic.Baseline, strings.Join(topic.Subreddits, ", r/"))
+		}
+	} else {
+		sb.WriteString("No emerging topics stood out.\n")
+	}
+	if out.Cancelled {
+		sb.WriteString(cancelledNote("subreddits and comments not read by then are missing"))
+	}
+	return sb.String()
+}
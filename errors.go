@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Error categories shared by the Reddit client and tool handlers; wrap them
+// with fmt.Errorf("%w: ...") and test with errors.Is
+var (
+	errNotFound     = errors.New("not found")
+	errForbidden    = errors.New("forbidden")
+	errRateLimited  = errors.New("rate limited")
+	errUpstreamDown = errors.New("reddit unavailable")
+	errAuthRequired = errors.New("authentication required")
+	errInvalidInput = errors.New("invalid input")
+	errPullpush     = errors.New("pullpush unavailable")
+)
+
+// Classify a non-200 Reddit response
+func statusError(resp *http.Response) error {
+	switch code := resp.StatusCode; {
+	case code == http.StatusNotFound:
+		return fmt.Errorf("%w: Reddit returned %d", errNotFound, code)
+	case code == http.StatusForbidden:
+		return fmt.Errorf("%w: Reddit returned %d", errForbidden, code)
+	case code == http.StatusUnauthorized:
+		return fmt.Errorf("%w: Reddit returned %d", errAuthRequired, code)
+	case code == http.StatusTooManyRequests:
+		if wait := resp.Header.Get("Retry-After"); wait != "" {
+			return fmt.Errorf("%w: retry after %s seconds", errRateLimited, wait)
+		}
+		return fmt.Errorf("%w: Reddit returned %d", errRateLimited, code)
+	case code >= 500:
+		return fmt.Errorf("%w: Reddit returned %d", errUpstreamDown, code)
+	case code == http.StatusBadRequest:
+		return fmt.Errorf("%w: Reddit returned %d", errInvalidInput, code)
+	default:
+		return fmt.Errorf("API returned error status: %d", code)
+	}
+}
+
+// Reddit redirects unknown subreddits to its subreddit search instead of returning 404
+func checkRedditRedirect(req *http.Request, via []*http.Request) error {
+	if strings.HasPrefix(req.URL.Path, "/subreddits/search") {
+		return fmt.Errorf("%w: no such subreddit", errNotFound)
+	}
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	return nil
+}
+
+// Turn an error into an actionable tool error for the model
+func toolError(err error) *mcp.CallToolResult {
+	var msg string
+	switch {
+	case errors.Is(err, errInvalidInput):
+		return mcp.NewToolResultError("Invalid input: " + strings.TrimPrefix(err.Error(), errInvalidInput.Error()+": "))
+	case errors.Is(err, errNotFound):
+		msg = "Not found on Reddit. Check that the post ID or subreddit name is correct (IDs look like 'abc123', subreddits are given without 'r/')."
+	case errors.Is(err, errForbidden):
+		msg = "Reddit denied access. The subreddit or post may be private, quarantined, or banned."
+	case errors.Is(err, errAuthRequired):
+		msg = "Reddit requires authentication for this request. Configure a Reddit account (client ID and secret) on the server, or check its credentials."
+	case errors.Is(err, errRateLimited):
+		msg = "Reddit is rate limiting this server. Wait a minute before retrying, and avoid repeating identical requests."
+	case errors.Is(err, errUpstreamDown):
+		msg = "Reddit is unavailable or not responding right now. Try again shortly."
+	case errors.Is(err, errPullpush):
+		msg = "The pullpush archive is unavailable or not keeping up right now. Try again later, or search Reddit itself with backend reddit."
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		msg = "The request was cancelled before Reddit responded."
+	default:
+		return mcp.NewToolResultErrorFromErr("Reddit API error", err)
+	}
+	return mcp.NewToolResultError(fmt.Sprintf("%s (%v)", msg, err))
+}
+
+// Build an invalid-input error for a bad tool argument
+func invalidInput(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", errInvalidInput, fmt.Sprintf(format, args...))
+}
+
+// Note on the results of a call that was cancelled part way, saying what
+// they cover; tools looping over pages or subreddits return what they have
+func cancelledNote(covered string) string {
+	return fmt.Sprintf("The call was cancelled before it finished; %s.\n", covered)
+}
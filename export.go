@@ -0,0 +1,626 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// Comments asked for in the first fetch of an exported thread
+	exportCommentLimit = 500
+	// Deepest replies Reddit returns in one fetch; deeper ones come as
+	// "continue this thread" stubs
+	exportCommentDepth = 10
+	// Stub IDs sent in one /api/morechildren request, Reddit's own limit
+	moreChildrenBatch = 100
+	// Follow-up fetches made to fill in collapsed comments before giving up
+	// and exporting what's there
+	maxExportRequests = 30
+)
+
+// Reddit's /api/morechildren response: the requested comments, flattened, in
+// thread order, along with stubs for any they collapse in turn
+type moreChildrenResponse struct {
+	JSON struct {
+		Errors [][]string `json:"errors"`
+		Data   struct {
+			Things []thing `json:"things"`
+		} `json:"data"`
+	} `json:"json"`
+}
+
+// A comment tree being filled in: where each comment's replies live, keyed by
+// fullname, with the thread's top level under the post's
+type threadExpansion struct {
+	linkID    string
+	sort      string
+	replies   map[string]*listing
+	continued map[string]bool // comments whose thread has been refetched
+	requests  int
+}
+
+// Replace the "more" stubs of result with the comments they stand for, as
+// far as maxExportRequests allows; returns how many comments are still
+// collapsed
+func expandThread(ctx context.Context, result *commentsResponse, sort string) (int, error) {
+	if len(result.Post.Children) == 0 {
+		return 0, nil
+	}
+	e := &threadExpansion{
+		linkID:    result.Post.Children[0].Data.Name,
+		sort:      sort,
+		replies:   map[string]*listing{},
+		continued: map[string]bool{},
+	}
+	e.index(e.linkID, &result.Comments)
+	for e.requests < maxExportRequests {
+		stubs := e.stubs()
+		if len(stubs) == 0 {
+			break
+		}
+		reportProgress(ctx, float64(e.requests), maxExportRequests, fmt.Sprintf("Expanding collapsed comments (%d stubs left)", len(stubs)))
+		if err := e.expand(ctx, stubs[0]); err != nil {
+			return 0, err
+		}
+	}
+	left := 0
+	for _, stub := range e.stubs() {
+		left += max(stub.Count, len(stub.Children), 1)
+	}
+	return left, nil
+}
+
+// Note where the replies of each comment under l go, creating empty reply
+// listings so fetched replies can be attached
+func (e *threadExpansion) index(parent string, l *listing) {
+	e.replies[parent] = l
+	for i := range l.Children {
+		child := &l.Children[i]
+		if child.Kind != "t1" {
+			continue
+		}
+		if child.Data.Name == "" {
+			child.Data.Name = "t1_" + child.Data.ID
+		}
+		if child.Data.Replies == nil {
+			child.Data.Replies = &listing{}
+		}
+		e.index(child.Data.Name, child.Data.Replies)
+	}
+}
+
+// The "more" stubs left in the tree, outermost first
+func (e *threadExpansion) stubs() []item {
+	var stubs []item
+	var walk func(l *listing)
+	walk = func(l *listing) {
+		for i := range l.Children {
+			switch child := &l.Children[i]; child.Kind {
+			case "more":
+				stubs = append(stubs, child.Data)
+			case "t1":
+				walk(child.Data.Replies)
+			}
+		}
+	}
+	walk(e.replies[e.linkID])
+	return stubs
+}
+
+// Fetch the comments behind stub and put them in its place. Stubs list the
+// IDs they collapse; "continue this thread" stubs list none, and are filled
+// in by refetching the thread from their parent.
+func (e *threadExpansion) expand(ctx context.Context, stub item) error {
+	parent := e.parent(stub.ParentID)
+	removeStub(parent, stub.ID)
+	if len(stub.Children) == 0 {
+		return e.continueThread(ctx, stub.ParentID)
+	}
+
+	// Stubs can overlap what an earlier fetch brought in
+	ids := slices.DeleteFunc(slices.Clone(stub.Children), func(id string) bool {
+		return e.replies["t1_"+id] != nil
+	})
+	if len(ids) == 0 {
+		return nil
+	}
+	if len(ids) > moreChildrenBatch {
+		// Leave the rest collapsed for the next round
+		rest := stub
+		rest.Children = ids[moreChildrenBatch:]
+		rest.Count = len(rest.Children)
+		parent.Children = append(parent.Children, thing{Kind: "more", Data: rest})
+		ids = ids[:moreChildrenBatch]
+	}
+	params := url.Values{}
+	params.Set("api_type", "json")
+	params.Set("link_id", e.linkID)
+	params.Set("children", strings.Join(ids, ","))
+	params.Set("sort", e.sort)
+	params.Set("limit_children", "false")
+	var result moreChildrenResponse
+	e.requests++
+	if err := makeRedditRequest(ctx, "/api/morechildren.json", params, &result); err != nil {
+		return err
+	}
+	if len(result.JSON.Errors) > 0 {
+		return fmt.Errorf("failed to expand comments: %s", strings.Join(result.JSON.Errors[0], ": "))
+	}
+	e.attach(result.JSON.Data.Things)
+	return nil
+}
+
+// Fetch the replies under a comment the thread stopped short of
+func (e *threadExpansion) continueThread(ctx context.Context, commentName string) error {
+	if !strings.HasPrefix(commentName, "t1_") || e.continued[commentName] {
+		return nil
+	}
+	e.continued[commentName] = true
+	var result commentsResponse
+	endpoint, params := commentsRequest(e.linkID, e.sort, exportCommentLimit, exportCommentDepth)
+	params.Set("comment", strings.TrimPrefix(commentName, "t1_"))
+	e.requests++
+	if err := makeRedditRequest(ctx, endpoint, params, &result); err != nil {
+		return err
+	}
+	// The thread comes back rooted at the comment itself
+	for _, child := range result.Comments.Children {
+		if child.Kind == "t1" && child.Data.Replies != nil {
+			e.attach(child.Data.Replies.Children)
+		}
+	}
+	return nil
+}
+
+// Attach fetched comments and stubs under their parents; parents come
+// before their replies, so nested replies find theirs already attached
+func (e *threadExpansion) attach(things []thing) {
+	for _, t := range things {
+		parent := e.parent(t.Data.ParentID)
+		switch t.Kind {
+		case "t1":
+			if t.Data.Name == "" {
+				t.Data.Name = "t1_" + t.Data.ID
+			}
+			if e.replies[t.Data.Name] != nil {
+				continue
+			}
+			// Replies sent nested, as by continueThread, are attached in turn
+			nested := t.Data.Replies
+			t.Data.Replies = &listing{}
+			e.replies[t.Data.Name] = t.Data.Replies
+			parent.Children = append(parent.Children, t)
+			if nested != nil {
+				e.attach(nested.Children)
+			}
+		case "more":
+			if len(t.Data.Children) > 0 || !e.continued[t.Data.ParentID] {
+				parent.Children = append(parent.Children, t)
+			}
+		}
+	}
+}
+
+// Where replies to the comment or post named go
+func (e *threadExpansion) parent(name string) *listing {
+	if l := e.replies[name]; l != nil {
+		return l
+	}
+	return e.replies[e.linkID]
+}
+
+func removeStub(l *listing, id string) {
+	for i := range l.Children {
+		if l.Children[i].Kind == "more" && l.Children[i].Data.ID == id {
+			l.Children = append(l.Children[:i], l.Children[i+1:]...)
+			return
+		}
+	}
+}
+
+// A thread as exported to JSON, and what the other formats are rendered from
+type threadExport struct {
+	Post      postOutput      `json:"post"`
+	Comments  []commentOutput `json:"comments" jsonschema:"Every comment in thread order, each reply following its parent"`
+	Collapsed int             `json:"collapsed,omitempty" jsonschema:"Comments Reddit kept collapsed that couldn't be fetched"`
+	Exported  string          `json:"exported" jsonschema:"When the thread was fetched, in RFC 3339 format"`
+}
+
+// Output of reddit_export_thread
+type exportOutput struct {
+	PostID    string `json:"post_id"`
+	Format    string `json:"format" jsonschema:"markdown, html or json"`
+	Comments  int    `json:"comments" jsonschema:"Comments exported"`
+	Collapsed int    `json:"collapsed,omitempty" jsonschema:"Comments Reddit kept collapsed that couldn't be fetched"`
+	Bytes     int    `json:"bytes" jsonschema:"Size of the export"`
+	File      string `json:"file,omitempty" jsonschema:"Path written, when the server has an export directory; otherwise the export is embedded in the result"`
+	URI       string `json:"uri,omitempty" jsonschema:"URI of the embedded export"`
+}
+
+// File extensions and MIME types of the export formats
+var exportFormats = map[string]struct{ ext, mimeType string }{
+	"markdown": {"md", "text/markdown"},
+	"html":     {"html", "text/html"},
+	"json":     {"json", "application/json"},
+	"jsonl":    {"jsonl", "application/jsonl"},
+	"csv":      {"csv", "text/csv"},
+}
+
+// The file name an export is saved under: the filename argument, which only
+// makes sense with an export directory, or name
+func exportFilename(args map[string]any, name string) (string, error) {
+	filename, _ := args["filename"].(string)
+	switch {
+	case filename == "":
+		return name, nil
+	case currentConfig().ExportDir == "":
+		return "", invalidInput("filename needs the server started with --export-dir; without it the export is returned in the result")
+	case filepath.Base(filename) != filename || filename == "." || filename == "..":
+		return "", invalidInput("filename must be a plain file name, without directories")
+	}
+	return filename, nil
+}
+
+// Write doc to filename in the export directory, returning the path, or with
+// no export directory return it as a resource to embed in the result
+func saveExport(filename, mimeType, doc string) (path string, embedded mcp.Content, err error) {
+	dir := currentConfig().ExportDir
+	if dir == "" {
+		return "", mcp.NewEmbeddedResource(mcp.TextResourceContents{
+			URI:      "reddit://export/" + filename,
+			MIMEType: mimeType,
+			Text:     doc,
+		}), nil
+	}
+	// Never replace an earlier export, so repeating a call can't lose one
+	path = filepath.Join(dir, filename)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		if os.IsExist(err) {
+			return "", nil, invalidInput("%s already exists; pass another filename", path)
+		}
+		return "", nil, fmt.Errorf("failed to write export: %w", err)
+	}
+	_, err = f.WriteString(doc)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to write export: %w", err)
+	}
+	return path, nil, nil
+}
+
+// Export a post and its whole comment tree as a document
+func handleRedditExportThread(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	postID, _ := args["post_id"].(string)
+	postID = strings.TrimPrefix(postID, "t3_")
+	if postID == "" {
+		return toolError(invalidInput("post_id is required")), nil
+	}
+	format, _ := args["format"].(string)
+	if format == "" {
+		format = "markdown"
+	}
+	if format != "markdown" && format != "html" && format != "json" {
+		return toolError(invalidInput("format must be markdown, html or json")), nil
+	}
+	kind := exportFormats[format]
+	sort, _ := args["sort"].(string)
+	if sort == "" {
+		sort = "top"
+	}
+	opts, err := newFormatOptions(args)
+	if err != nil {
+		return toolError(err), nil
+	}
+	filename, err := exportFilename(args, postID+"."+kind.ext)
+	if err != nil {
+		return toolError(err), nil
+	}
+
+	var result commentsResponse
+	endpoint, params := commentsRequest(postID, sort, exportCommentLimit, exportCommentDepth)
+	if err := makeRedditRequest(ctx, endpoint, params, &result); err != nil {
+		return toolError(err), nil
+	}
+	if len(result.Post.Children) == 0 {
+		return toolError(fmt.Errorf("%w: post %s", errNotFound, postID)), nil
+	}
+	collapsed, err := expandThread(ctx, &result, sort)
+	if err != nil {
+		return toolError(err), nil
+	}
+
+	// NSFW bodies and spoilers are hidden as in any other view of the thread
+	post := &result.Post.Children[0].Data
+	redactNSFW(post, opts)
+	redactSpoilers(post, opts)
+	redactCommentSpoilers(&result.Comments, opts)
+
+	export := &threadExport{Post: *newPostOutput(post, true), Collapsed: collapsed}
+	_, export.Exported = createdTimes(float64(time.Now().Unix()))
+	comments := &commentsOutput{View: "threaded"}
+	appendComments(comments, &result.Comments, "", 1, exportCommentLimit)
+	export.Comments = comments.Comments
+	doc, err := renderExport(export, format)
+	if err != nil {
+		return toolError(fmt.Errorf("failed to render export: %w", err)), nil
+	}
+
+	out := &exportOutput{PostID: postID, Format: format, Comments: len(export.Comments), Collapsed: collapsed, Bytes: len(doc)}
+	summary := fmt.Sprintf("Exported post %s (%q) with %d comments as %s", postID, export.Post.Title, out.Comments, format)
+	if collapsed > 0 {
+		summary += fmt.Sprintf("; %d collapsed comments couldn't be fetched", collapsed)
+	}
+	path, embedded, err := saveExport(filename, kind.mimeType, doc)
+	if err != nil {
+		return toolError(err), nil
+	}
+	if embedded == nil {
+		out.File = path
+		return newStructuredResult(out, fmt.Sprintf("%s to %s (%d bytes).\n", summary, path, out.Bytes)), nil
+	}
+	out.URI = "reddit://export/" + filename
+	toolResult := newStructuredResult(out, fmt.Sprintf("%s; the export is attached as %s (%d bytes).\n", summary, out.URI, out.Bytes))
+	toolResult.Content = append(toolResult.Content, embedded)
+	return toolResult, nil
+}
+
+func renderExport(export *threadExport, format string) (string, error) {
+	switch format {
+	case "json":
+		b, err := json.MarshalIndent(export, "", "  ")
+		return string(b) + "\n", err
+	case "html":
+		var sb strings.Builder
+		err := exportHTML.Execute(&sb, export)
+		return sb.String(), err
+	}
+	var sb strings.Builder
+	markdownPost(&sb, &export.Post)
+	if export.Post.Permalink != "" {
+		fmt.Fprintf(&sb, "[View on Reddit](%s)\n\n", export.Post.Permalink)
+	}
+	markdownComments(&sb, &commentsOutput{View: "threaded", Comments: export.Comments})
+	if export.Collapsed > 0 {
+		fmt.Fprintf(&sb, "*%d collapsed comments couldn't be fetched.*\n\n", export.Collapsed)
+	}
+	fmt.Fprintf(&sb, "---\n\n*Exported %s*\n", export.Exported)
+	return sb.String(), nil
+}
+
+// A standalone page: styles inline, replies indented by depth, bodies as
+// written (Reddit markdown shown as plain text)
+var exportHTML = template.Must(template.New("thread").Funcs(template.FuncMap{
+	"user":   userLabel,
+	"indent": func(depth int) int { return (depth - 1) * 24 },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Post.Title}}</title>
+<style>
+body { font: 15px/1.5 system-ui, sans-serif; max-width: 50rem; margin: 2rem auto; padding: 0 1rem; color: #1a1a1b; }
+.meta { color: #787c7e; font-size: 13px; }
+.body { white-space: pre-wrap; overflow-wrap: anywhere; }
+.comment { border-left: 2px solid #edeff1; padding: 0 0 0 .75rem; margin: 1rem 0; }
+.gone { color: #787c7e; font-style: italic; }
+</style>
+</head>
+<body>
+<article>
+<h1>{{if .Post.URL}}<a href="{{.Post.URL}}">{{.Post.Title}}</a>{{else}}{{.Post.Title}}{{end}}</h1>
+<p class="meta">{{if .Post.Subreddit}}r/{{.Post.Subreddit}} · {{end}}{{user .Post.Author}} · {{.Post.Score}} points · {{.Post.NumComments}} comments · {{.Post.Created}}{{if .Post.Permalink}} · <a href="{{.Post.Permalink}}">view on Reddit</a>{{end}}</p>
+{{if .Post.Removed}}<p class="gone">This post was {{.Post.Removed}}.</p>{{else if .Post.Selftext}}<div class="body">{{.Post.Selftext}}</div>{{end}}
+</article>
+<h2>{{len .Comments}} comments</h2>
+{{range .Comments}}<div class="comment" id="{{.ID}}" style="margin-left: {{indent .Depth}}px">
+<p class="meta"><strong>{{user .Author}}</strong>{{if .IsOP}} (OP){{end}}{{if .Flair}} · {{.Flair}}{{end}} · {{if .ScoreHidden}}score hidden{{else}}{{.Score}} points{{end}} · {{if .Permalink}}<a href="{{.Permalink}}">{{.Created}}</a>{{else}}{{.Created}}{{end}}</p>
+{{if .Removed}}<p class="gone">[{{.Removed}}]</p>{{else}}<div class="body">{{.Body}}</div>{{end}}
+</div>
+{{end}}{{if .Collapsed}}<p class="gone">{{.Collapsed}} collapsed comments couldn't be fetched.</p>
+{{end}}<footer class="meta">Exported {{.Exported}}</footer>
+</body>
+</html>
+`))
+
+const (
+	// Posts a subreddit export fetches by default, and at most; Reddit stops
+	// serving a listing after about 1000 posts
+	defaultExportPosts = 500
+	maxExportPosts     = 1000
+	// Posts per listing page, Reddit's maximum
+	exportPageSize = 100
+	// Pause between listing pages, and after Reddit says to slow down, so a
+	// long export doesn't use up the rate limit other calls share
+	exportPagePause      = time.Second
+	exportRateLimitPause = 30 * time.Second
+	exportRateLimitTries = 3
+)
+
+// Output of reddit_export_subreddit
+type subredditExportOutput struct {
+	Subreddit string `json:"subreddit"`
+	Sort      string `json:"sort"`
+	Format    string `json:"format" jsonschema:"jsonl or csv"`
+	Posts     int    `json:"posts" jsonschema:"Posts exported"`
+	Pages     int    `json:"pages" jsonschema:"Listing pages fetched"`
+	Complete  bool   `json:"complete" jsonschema:"Reddit had no more posts in the listing"`
+	Cancelled bool   `json:"cancelled,omitempty" jsonschema:"The call was cancelled part way; the export holds the pages fetched until then"`
+	Bytes     int    `json:"bytes" jsonschema:"Size of the export"`
+	File      string `json:"file,omitempty" jsonschema:"Path written, when the server has an export directory; otherwise the export is embedded in the result"`
+	URI       string `json:"uri,omitempty" jsonschema:"URI of the embedded export"`
+}
+
+// Page through a subreddit listing and export its posts as JSONL or CSV
+func handleRedditExportSubreddit(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	subreddit, _ := args["subreddit"].(string)
+	subreddit = subredditKey(subreddit)
+	if subreddit == "" {
+		return toolError(invalidInput("subreddit is required")), nil
+	}
+	opts, err := newFormatOptions(args)
+	if err != nil {
+		return toolError(err), nil
+	}
+	format, _ := args["format"].(string)
+	if format == "" {
+		format = "jsonl"
+	}
+	if format != "jsonl" && format != "csv" {
+		return toolError(invalidInput("format must be jsonl or csv")), nil
+	}
+	kind := exportFormats[format]
+	sort, _ := args["sort"].(string)
+	if sort == "" {
+		sort = "new"
+	}
+	window, _ := args["time"].(string)
+	if window != "" && sort != "top" && sort != "controversial" {
+		return toolError(invalidInput("time only applies to sort top or controversial")), nil
+	}
+	maxPosts := defaultExportPosts
+	if n, ok := args["max_posts"].(float64); ok {
+		maxPosts = min(max(int(n), 1), maxExportPosts)
+	}
+	filename, err := exportFilename(args, fmt.Sprintf("%s-%s.%s", subreddit, sort, kind.ext))
+	if err != nil {
+		return toolError(err), nil
+	}
+
+	endpoint, params := subredditListingRequest(subreddit, sort, exportPageSize)
+	if window != "" {
+		params.Set("t", window)
+	}
+	// A cancelled export still saves the pages already fetched
+	all, pages, err := pageListing(ctx, endpoint, params, maxPosts, "posts", nil)
+	cancelled := err != nil && ctx.Err() != nil && len(all.Children) > 0
+	if err != nil && !cancelled {
+		return toolError(err), nil
+	}
+	leftOut := filterPosts(&all, opts)
+
+	// Datasets carry each post's body, hidden as elsewhere when NSFW or a spoiler
+	listingOut := &listingOutput{Posts: make([]postOutput, 0, len(all.Children))}
+	for i := range all.Children {
+		post := &all.Children[i].Data
+		redactNSFW(post, opts)
+		redactSpoilers(post, opts)
+		listingOut.Posts = append(listingOut.Posts, *newPostOutput(post, true))
+	}
+	var doc string
+	if format == "csv" {
+		if doc, err = formatTable(listingOut, ',', true); err != nil {
+			return toolError(fmt.Errorf("failed to render export: %w", err)), nil
+		}
+	} else {
+		doc, _ = formatJSONLines(listingOut)
+	}
+
+	out := &subredditExportOutput{Subreddit: subreddit, Sort: sort, Format: format, Posts: len(listingOut.Posts), Pages: pages,
+		Complete: all.After == "" && !cancelled, Cancelled: cancelled, Bytes: len(doc)}
+	pageCount := fmt.Sprintf("%d pages", pages)
+	if pages == 1 {
+		pageCount = "1 page"
+	}
+	summary := fmt.Sprintf("Exported %d posts from r/%s (%s, %s) as %s", out.Posts, subreddit, sort, pageCount, format)
+	switch {
+	case cancelled:
+		leftOut = cancelledNote(fmt.Sprintf("the export holds the %s fetched until then", pageCount)) + leftOut
+	case !out.Complete && maxPosts < maxExportPosts:
+		leftOut = "The listing has more posts; raise max_posts to export them.\n" + leftOut
+	}
+	path, embedded, err := saveExport(filename, kind.mimeType, doc)
+	if err != nil {
+		return toolError(err), nil
+	}
+	if embedded == nil {
+		out.File = path
+		return newStructuredResult(out, fmt.Sprintf("%s to %s (%d bytes).\n%s", summary, path, out.Bytes, leftOut)), nil
+	}
+	out.URI = "reddit://export/" + filename
+	toolResult := newStructuredResult(out, fmt.Sprintf("%s; the export is attached as %s (%d bytes).\n%s", summary, out.URI, out.Bytes, leftOut))
+	toolResult.Content = append(toolResult.Content, embedded)
+	return toolResult, nil
+}
+
+// Read up to maxItems of a listing a page at a time, pausing between pages,
+// until a page satisfies stop (when given); After is left empty when Reddit
+// had nothing more to list
+func pageListing(ctx context.Context, endpoint string, params url.Values, maxItems int, noun string, stop func(page *listing) bool) (listing, int, error) {
+	var all listing
+	seen := map[string]bool{}
+	pages := 0
+	for len(all.Children) < maxItems {
+		if pages > 0 {
+			if err := pause(ctx, exportPagePause); err != nil {
+				return all, pages, fmt.Errorf("request cancelled: %w", err)
+			}
+		}
+		var page listing
+		if err := fetchExportPage(ctx, endpoint, params, &page); err != nil {
+			return all, pages, err
+		}
+		pages++
+		// Listings shift as items are added, repeating some across pages
+		added := 0
+		for _, child := range page.Children {
+			if !seen[child.Data.Name] && len(all.Children) < maxItems {
+				seen[child.Data.Name] = true
+				all.Children = append(all.Children, child)
+				added++
+			}
+		}
+		reportProgress(ctx, float64(len(all.Children)), float64(maxItems), fmt.Sprintf("Fetched %d of up to %d %s", len(all.Children), maxItems, noun))
+		all.After = page.After
+		if page.After == "" || added == 0 {
+			all.After = ""
+			break
+		}
+		if stop != nil && stop(&page) {
+			break
+		}
+		params.Set("after", page.After)
+		params.Set("count", fmt.Sprint(len(all.Children)))
+	}
+	return all, pages, nil
+}
+
+// Fetch one listing page, waiting and retrying when Reddit rate limits it
+func fetchExportPage(ctx context.Context, endpoint string, params url.Values, page *listing) error {
+	for try := 1; ; try++ {
+		err := makeRedditRequest(ctx, endpoint, params, page)
+		if !errors.Is(err, errRateLimited) || try == exportRateLimitTries {
+			return err
+		}
+		slog.InfoContext(ctx, "Rate limited by Reddit; waiting before fetching the page again", "endpoint", endpoint, "wait", exportRateLimitPause)
+		if err := pause(ctx, exportRateLimitPause); err != nil {
+			return fmt.Errorf("request cancelled: %w", err)
+		}
+	}
+}
+
+// Sleep for d, or until ctx is done
+func pause(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
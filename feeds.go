@@ -0,0 +1,146 @@
+package main
+
+import (
+	"cmp"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Characters of a post's body quoted in its feed entry
+const feedBodyChars = 1000
+
+// An outgoing Atom feed of watch matches
+type outFeed struct {
+	XMLName xml.Name   `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string     `xml:"id"`
+	Title   string     `xml:"title"`
+	Updated string     `xml:"updated"`
+	Link    outLink    `xml:"link"`
+	Entries []outEntry `xml:"entry"`
+}
+
+type outLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type outEntry struct {
+	ID        string  `xml:"id"`
+	Title     string  `xml:"title"`
+	Updated   string  `xml:"updated"`
+	Published string  `xml:"published"`
+	Author    string  `xml:"author>name"`
+	Link      outLink `xml:"link"`
+	Category  struct {
+		Term  string `xml:"term,attr"`
+		Label string `xml:"label,attr"`
+	} `xml:"category"`
+	Content struct {
+		Type string `xml:"type,attr"`
+		Text string `xml:",chardata"`
+	} `xml:"content"`
+}
+
+// Path of a watch's feed, or of every watch's with id ""
+func feedPath(cfg *config, id string) string {
+	if id == "" {
+		return basePath(cfg) + "/feeds"
+	}
+	return basePath(cfg) + "/feeds/" + id
+}
+
+// Serve the latest matches of one watch, or of all the watches the client's
+// token sees, as an Atom feed built from what polling collected; it never
+// calls Reddit itself
+func handleWatchFeed(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSuffix(r.PathValue("id"), ".atom")
+	list := watches.list(clientFingerprint(r))
+	title := "Reddit watches"
+	if id != "" {
+		list = slices.DeleteFunc(list, func(each watch) bool { return each.id != id })
+		if len(list) == 0 {
+			http.Error(w, fmt.Sprintf("no watch %q", id), http.StatusNotFound)
+			return
+		}
+		title = fmt.Sprintf("Reddit watch %s: %s", id, list[0].rule)
+	}
+
+	// Matches newest first, once each even when several watches caught a post
+	result := &listing{}
+	for _, each := range list {
+		for _, post := range each.recent {
+			if !slices.ContainsFunc(result.Children, func(child thing) bool { return child.Data.ID == post.ID }) {
+				result.Children = append(result.Children, thing{Kind: "t3", Data: post})
+			}
+		}
+	}
+	filterPosts(result, defaultFormatOptions())
+	slices.SortFunc(result.Children, func(a, b thing) int {
+		return cmp.Compare(b.Data.CreatedUTC, a.Data.CreatedUTC)
+	})
+	result.Children = result.Children[:min(len(result.Children), maxWatchMatches)]
+
+	feed := outFeed{
+		ID:    "urn:reddit-mcp:" + strings.TrimPrefix(feedPath(currentConfig(), id), "/"),
+		Title: title,
+		Link:  outLink{Rel: "self", Href: r.URL.Path},
+	}
+	// A feed without entries is as old as its oldest watch
+	updated := time.Now()
+	for _, each := range list {
+		if each.created.Before(updated) {
+			updated = each.created
+		}
+	}
+	if len(result.Children) > 0 {
+		updated = time.Unix(int64(result.Children[0].Data.CreatedUTC), 0)
+	}
+	feed.Updated = updated.UTC().Format(time.RFC3339)
+	for i := range result.Children {
+		feed.Entries = append(feed.Entries, newFeedEntry(&result.Children[i].Data))
+	}
+
+	body, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		http.Error(w, "failed to build feed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.Header().Set("Last-Modified", updated.UTC().Format(http.TimeFormat))
+	w.Write([]byte(xml.Header))
+	w.Write(body)
+}
+
+func newFeedEntry(post *item) outEntry {
+	_, created := createdTimes(post.CreatedUTC)
+	entry := outEntry{
+		ID:        permalinkURL(post.Permalink),
+		Title:     post.Title,
+		Updated:   created,
+		Published: created,
+		Author:    userLabel(post.Author),
+		Link:      outLink{Href: permalinkURL(post.Permalink)},
+	}
+	entry.Category.Term = post.Subreddit
+	entry.Category.Label = "r/" + post.Subreddit
+
+	// Scores are as of the poll that matched the post
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "r/%s | %s | Score: %d | Comments: %d", post.Subreddit, userLabel(post.Author), post.Score, post.NumComments)
+	if post.URL != "" && !strings.Contains(post.URL, post.Permalink) {
+		fmt.Fprintf(&sb, "\nLink: %s", post.URL)
+	}
+	if body := shownText(post, post.Selftext); body != "" && !post.Over18 {
+		if len(body) > feedBodyChars {
+			body = strings.ToValidUTF8(body[:feedBodyChars], "") + "..."
+		}
+		sb.WriteString("\n\n" + body)
+	}
+	entry.Content.Type = "text"
+	entry.Content.Text = sb.String()
+	return entry
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// The exclude_bots argument of the comments tool
+func excludeBotsArgument() mcp.ToolOption {
+	return mcp.WithBoolean("exclude_bots",
+		mcp.Description("Leave out comments by AutoModerator and the server's configured bot accounts, with their replies"),
+		mcp.DefaultBool(true),
+	)
+}
+
+// The exclude_stickied argument of listing tools
+func excludeStickiedArgument() mcp.ToolOption {
+	return mcp.WithBoolean("exclude_stickied",
+		mcp.Description("Leave out stickied posts and posts distinguished by moderators or admins, such as megathreads and announcements"),
+		mcp.DefaultBool(false),
+	)
+}
+
+// The min_score argument of tools that return posts or comments
+func minScoreArgument() mcp.ToolOption {
+	return mcp.WithNumber("min_score",
+		mcp.Description("Leave out posts and comments scoring below this (replies to a dropped comment go with it)"),
+	)
+}
+
+// The author argument of tools that can narrow results to one user
+func authorArgument() mcp.ToolOption {
+	return mcp.WithString("author",
+		mcp.Description("Only include posts or comments by this username (without u/); comments are then listed flat"),
+	)
+}
+
+// The min_length argument of the comments tool
+func minLengthArgument() mcp.ToolOption {
+	return mcp.WithNumber("min_length",
+		mcp.Description("Leave out comments shorter than this many characters, such as one-word reactions (replies to a dropped comment go with it)"),
+		mcp.Min(0),
+	)
+}
+
+// Drop the posts opts leave out of a listing, returning a note telling the
+// model what was dropped and how to get it back ("" when nothing was)
+func filterPosts(result *listing, opts formatOptions) string {
+	note := ""
+	if n := filterNSFW(result, opts); n > 0 {
+		note += hiddenNote(n, "NSFW post", "pass include_nsfw true")
+	}
+	if opts.excludeStickied {
+		n := dropPosts(result, func(post *item) bool {
+			return post.Stickied || post.Distinguished != ""
+		})
+		if n > 0 {
+			note += hiddenNote(n, "stickied or moderator post", "call without exclude_stickied")
+		}
+	}
+	if opts.author != "" {
+		// Search asks Reddit for the author already; this catches the rest
+		dropPosts(result, notBy(opts.author))
+	}
+	if opts.minScore != nil {
+		if n := dropPosts(result, scoreBelow(*opts.minScore)); n > 0 {
+			note += hiddenNote(n, "low-scoring post", fmt.Sprintf("lower min_score (now %d)", *opts.minScore))
+		}
+	}
+	return note
+}
+
+// Drop the comments opts leave out of a thread, like filterPosts
+func filterComments(replies *listing, opts formatOptions) string {
+	note := ""
+	if opts.excludeBots {
+		bots := currentConfig().BotAccounts
+		n := dropComments(replies, func(comment *item) bool {
+			return isBot(comment.Author, bots)
+		})
+		if n > 0 {
+			note += hiddenNote(n, "bot comment", "pass exclude_bots false")
+		}
+	}
+	if opts.minScore != nil {
+		if n := dropComments(replies, scoreBelow(*opts.minScore)); n > 0 {
+			note += hiddenNote(n, "low-scoring comment", fmt.Sprintf("lower min_score (now %d)", *opts.minScore))
+		}
+	}
+	if opts.minLength > 0 {
+		n := dropComments(replies, func(comment *item) bool {
+			// A removed comment's placeholder says nothing of its length, and
+			// dropping it would take its replies along
+			return comment.Removal == "" && utf8.RuneCountInString(strings.TrimSpace(comment.Body)) < opts.minLength
+		})
+		if n > 0 {
+			note += hiddenNote(n, "short comment", fmt.Sprintf("lower min_length (now %d)", opts.minLength))
+		}
+	}
+	return note
+}
+
+func notBy(author string) func(*item) bool {
+	return func(it *item) bool {
+		return !strings.EqualFold(it.Author, author)
+	}
+}
+
+func scoreBelow(min int) func(*item) bool {
+	return func(it *item) bool {
+		return it.Score < min
+	}
+}
+
+// Drop the posts in result matching drop, returning how many were dropped
+func dropPosts(result *listing, drop func(*item) bool) int {
+	before := len(result.Children)
+	result.Children = slices.DeleteFunc(result.Children, func(child thing) bool {
+		return drop(&child.Data)
+	})
+	return before - len(result.Children)
+}
+
+// Drop the comments in a comment tree matching drop, with the replies to
+// them, returning how many matching comments were dropped
+func dropComments(replies *listing, drop func(*item) bool) int {
+	if replies == nil {
+		return 0
+	}
+	dropped := 0
+	replies.Children = slices.DeleteFunc(replies.Children, func(child thing) bool {
+		if child.Kind == "more" || !drop(&child.Data) {
+			return false
+		}
+		dropped++
+		return true
+	})
+	for i := range replies.Children {
+		dropped += dropComments(replies.Children[i].Data.Replies, drop)
+	}
+	return dropped
+}
+
+func isBot(author string, bots []string) bool {
+	if strings.EqualFold(author, "AutoModerator") {
+		return true
+	}
+	return slices.ContainsFunc(bots, func(bot string) bool {
+		return strings.EqualFold(strings.TrimPrefix(bot, "u/"), author)
+	})
+}
+
+// Note that n things (e.g. "NSFW post") were left out and how to show them
+func hiddenNote(n int, what, remedy string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s was left out; %s to show it.\n", what, remedy)
+	}
+	return fmt.Sprintf("%d %ss were left out; %s to show them.\n", n, what, remedy)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"strings"
+	"time"
+	_ "time/tzdata" // Timezones work in minimal containers without a zoneinfo database
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Per-call choices for rendering tool text, from the call's arguments with
+// the live config as the fallback
+type formatOptions struct {
+	loc   *time.Location
+	depth int    // levels of comment replies
+	view  string // threaded or flat comments
+
+	maxBody int // characters of a post body shown before cutting it short (0 for all)
+
+	stripMarkdown  bool // render bodies as plain text
+	revealSpoilers bool // show spoiler-tagged bodies and inline spoilers
+	preview        bool // show the start of each listed post's body
+
+	includeNSFW     bool // show NSFW posts and their bodies
+	excludeStickied bool // leave stickied and distinguished posts out of listings
+	excludeBots     bool // leave bot comments out of threads
+	minScore        *int // leave out posts and comments scoring lower, when set
+	minLength       int  // leave out comments with fewer characters
+
+	author string // keep only posts and comments by this user
+
+	cites *citations // tags for citing what's rendered; nil when not asked for
+
+	scoreHideMins int // minutes the subreddit hides new comments' scores, when known
+}
+
+// Citation tag for a post ('r') or comment ('c'), or "" without citations
+func (o formatOptions) cite(prefix byte, it *item) string {
+	if o.cites == nil {
+		return ""
+	}
+	return o.cites.cite(prefix, it)
+}
+
+// Write the legend of the citation tags used, if any
+func (o formatOptions) writeLegend(sb *strings.Builder) {
+	if o.cites != nil {
+		o.cites.writeLegend(sb)
+	}
+}
+
+// A post or comment body as it should be shown
+func (o formatOptions) body(text string) string {
+	if o.stripMarkdown {
+		return stripMarkdown(text)
+	}
+	return text
+}
+
+// Options for output with no call to take arguments from, such as resources
+func defaultFormatOptions() formatOptions {
+	opts, _ := newFormatOptions(nil)
+	return opts
+}
+
+func newFormatOptions(args map[string]any) (formatOptions, error) {
+	opts := formatOptions{loc: time.UTC, depth: defaultCommentDepth, maxBody: currentConfig().InlineBodyChars}
+	if maxBody, ok := args["max_body_length"].(float64); ok {
+		opts.maxBody = max(int(maxBody), 0)
+	}
+	if depth, ok := args["depth"].(float64); ok {
+		opts.depth = min(max(int(depth), 1), 10)
+	}
+	opts.stripMarkdown = currentConfig().StripMarkdown
+	if strip, ok := args["strip_markdown"].(bool); ok {
+		opts.stripMarkdown = strip
+	}
+	opts.includeNSFW = currentConfig().IncludeNSFW
+	if include, ok := args["include_nsfw"].(bool); ok {
+		opts.includeNSFW = include
+	}
+	opts.excludeStickied, _ = args["exclude_stickied"].(bool)
+	opts.excludeBots = true
+	if exclude, ok := args["exclude_bots"].(bool); ok {
+		opts.excludeBots = exclude
+	}
+	opts.revealSpoilers, _ = args["reveal_spoilers"].(bool)
+	opts.preview, _ = args["include_preview"].(bool)
+	if minScore, ok := args["min_score"].(float64); ok {
+		n := int(minScore)
+		opts.minScore = &n
+	}
+	if minLength, ok := args["min_length"].(float64); ok {
+		opts.minLength = max(int(minLength), 0)
+	}
+	if cite, _ := args["citations"].(bool); cite {
+		opts.cites = newCitations()
+	}
+	if author, _ := args["author"].(string); author != "" {
+		opts.author = strings.TrimPrefix(author, "u/")
+	}
+	opts.view, _ = args["view"].(string)
+	switch opts.view {
+	case "":
+		opts.view = "threaded"
+		if opts.author != "" {
+			// One user's comments are scattered through a thread
+			opts.view = "flat"
+		}
+	case "threaded", "flat":
+	default:
+		return opts, invalidInput("view must be threaded or flat")
+	}
+	if opts.author != "" && opts.view != "flat" {
+		return opts, invalidInput("comments filtered by author are listed flat; drop view or pass view flat")
+	}
+	tz := currentConfig().Timezone
+	if arg, _ := args["timezone"].(string); arg != "" {
+		tz = arg
+	}
+	if tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return opts, invalidInput("unknown timezone %q (use an IANA name such as Europe/Berlin)", tz)
+		}
+		opts.loc = loc
+	}
+	return opts, nil
+}
+
+// The shared timezone argument of tools that show times
+func timezoneArgument() mcp.ToolOption {
+	return mcp.WithString("timezone",
+		mcp.Description("IANA timezone for displayed times, e.g. America/New_York (defaults to the server's, normally UTC)"),
+	)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// How long a readiness result is reused before Reddit is probed again
+const readinessTTL = 30 * time.Second
+
+// Liveness and readiness endpoints for orchestrators
+type healthChecker struct {
+	mu      sync.Mutex
+	checked time.Time
+	lastErr error
+}
+
+// Report that the process is up and serving
+func (h *healthChecker) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Report whether Reddit is reachable and the configured account can authenticate
+func (h *healthChecker) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if err := h.ready(r.Context()); err != nil {
+		writeHealth(w, http.StatusServiceUnavailable, map[string]string{"status": "unavailable", "error": err.Error()})
+		return
+	}
+	writeHealth(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+// Run the readiness checks, reusing a recent result to avoid probing Reddit on every poll
+func (h *healthChecker) ready(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.checked.IsZero() && time.Since(h.checked) < readinessTTL {
+		return h.lastErr
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	h.lastErr = checkRedditReachable(ctx)
+	if account := currentConfig().Reddit; h.lastErr == nil && account.configured() {
+		if _, err := redditTokens.Token(ctx, account); err != nil {
+			h.lastErr = fmt.Errorf("reddit auth invalid: %w", err)
+		}
+	}
+	h.checked = time.Now()
+	return h.lastErr
+}
+
+// Make a cheap request to confirm the Reddit API answers
+func checkRedditReachable(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, redditBaseURL+"/api/info.json?id=t5_2qh1i", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", userAgent())
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("reddit unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("reddit returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func writeHealth(w http.ResponseWriter, status int, body map[string]string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
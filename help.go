@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Worked examples for one tool, by base name; %s in the text stands for the
+// tool's advertised name
+type toolExamples struct {
+	tool     string
+	summary  string
+	examples []string
+}
+
+// In the order an agent would usually reach for them
+var helpExamples = []toolExamples{
+	{"search", "Find posts by keyword, across Reddit or within one subreddit.", []string{
+		`%s {"query": "mechanical keyboard", "limit": 5}`,
+		`%s {"query": "borrow checker", "subreddit": "rust", "sort": "top"}`,
+		`%s {"cursor": "<next_cursor from the previous result>"} continues a listing with its original arguments`,
+		`%s {"query": "heartbleed", "subreddit": "sysadmin", "backend": "pullpush", "after_date": "2014-04-01", "before_date": "2014-05-01"}`,
+	}},
+	{"subreddit_posts", "List a subreddit's hot, new, top or rising posts, with a header describing the subreddit.", []string{
+		`%s {"subreddit": "golang"}`,
+		`%s {"subreddit": "AskHistorians", "sort": "top", "limit": 5}`,
+	}},
+	{"post", "Read one post in full, including its body and images.", []string{
+		`%s {"post_id": "1abc23x"}`,
+		`%s {"post_id": "t3_1abc23x"} (the t3_ prefix is optional)`,
+	}},
+	{"comments", "Read the discussion under a post.", []string{
+		`%s {"post_id": "1abc23x"}`,
+		`%s {"post_id": "1abc23x", "sort": "new", "limit": 50}`,
+		`%s {"post_id": "1abc23x", "recover_removed": true} (archived text of removed comments)`,
+	}},
+	{"next_page", "Continue the most recent search or subreddit listing of this session.", []string{
+		`%s {}`,
+	}},
+	{"capabilities", "Check which features this server has enabled before planning.", []string{
+		`%s {}`,
+	}},
+	{"batch", "Run several lookups at once when the plan is known up front; each result comes back under its key.", []string{
+		`%s {"requests": [{"key": "rust", "tool": "search", "arguments": {"query": "async runtime", "subreddit": "rust"}}, {"key": "thread", "tool": "comments", "arguments": {"post_id": "1abc23x"}}]}`,
+	}},
+	{"watch_add", "Collect new posts in a subreddit that mention a keyword, for monitoring brands or topics.", []string{
+		`%s {"subreddit": "golang", "keyword": "generics"}`,
+		`%s {"subreddit": "programming", "pattern": "(?i)\\bacme\\b", "min_score": 10}`,
+	}},
+	{"watch_matches", "Fetch what the watches have matched since the last fetch.", []string{
+		`%s {}`,
+		`%s {"id": "w1"}`,
+	}},
+	{"watch_list", "Review the watches and how many matches each has waiting.", []string{
+		`%s {}`,
+	}},
+	{"watch_remove", "Stop a watch.", []string{
+		`%s {"id": "w1"}`,
+	}},
+	{"job_add", "Run a lookup on a schedule, such as a nightly snapshot or a weekly digest, and keep its output.", []string{
+		`%s {"name": "golang-nightly", "schedule": "0 3 * * *", "tool": "subreddit_posts", "arguments": {"subreddit": "golang", "sort": "top", "limit": 10}}`,
+		`%s {"name": "rust-weekly", "schedule": "@weekly", "tool": "search", "arguments": {"query": "async", "subreddit": "rust", "sort": "new"}}`,
+	}},
+	{"job_results", "Read what a scheduled job returned, latest run first.", []string{
+		`%s {"name": "golang-nightly"}`,
+		`%s {"name": "golang-nightly", "run": 2} (the run before)`,
+	}},
+	{"job_list", "Review scheduled jobs and when they run next.", []string{
+		`%s {}`,
+	}},
+	{"job_remove", "Stop a scheduled job.", []string{
+		`%s {"name": "golang-nightly"}`,
+	}},
+	{"archive_search", "Search what the server has fetched before, including posts since edited or deleted (needs --archive).", []string{
+		`%s {"query": "generics", "subreddit": "golang", "after_date": "2024-01-01"}`,
+		`%s {"query": "\"error handling\" OR title:generics", "sort": "new"}`,
+		`%s {"author": "spez", "kind": "comment", "history": true} (every archived version)`,
+	}},
+	{"export_thread", "Save a whole thread, collapsed comments included, for offline reading.", []string{
+		`%s {"post_id": "1abc234"}`,
+		`%s {"post_id": "1abc234", "format": "html", "filename": "release-thread.html"}`,
+	}},
+	{"export_subreddit", "Collect a subreddit's posts into a JSONL or CSV dataset.", []string{
+		`%s {"subreddit": "golang", "max_posts": 1000}`,
+		`%s {"subreddit": "rust", "sort": "top", "time": "year", "format": "csv"}`,
+	}},
+	{"digest", "Brief on what a few subreddits talked about today or this week.", []string{
+		`%s {"subreddits": ["golang", "rust"]}`,
+		`%s {"subreddits": ["machinelearning"], "window": "week", "posts_per_subreddit": 10}`,
+	}},
+	{"multi_search", "Search a handful of subreddits at once with one merged ranking.", []string{
+		`%s {"query": "error handling", "subreddits": ["golang", "rust", "zig"]}`,
+		`%s {"query": "benchmark", "subreddits": ["golang", "rust"], "sort": "top", "limit": 25}`,
+	}},
+	{"user_analysis", "See where and when a user posts and what they write about.", []string{
+		`%s {"username": "spez"}`,
+		`%s {"username": "spez", "limit": 1000, "timezone": "America/New_York"}`,
+	}},
+	{"subreddit_stats", "Check how active a community is before posting or recommending it.", []string{
+		`%s {"subreddit": "golang"}`,
+		`%s {"subreddit": "selfhosted", "limit": 500}`,
+	}},
+	{"keyword_trend", "See whether interest in a topic is growing in a community.", []string{
+		`%s {"keyword": "htmx", "subreddits": ["webdev"]}`,
+		`%s {"keyword": "\"rust analyzer\"", "subreddits": ["rust", "neovim"], "days": 180, "bucket": "week"}`,
+	}},
+	{"track_post", "Follow how a new post does over its first day.", []string{
+		`%s {"post_id": "1abc234"}`,
+		`%s {"post_id": "1abc234", "hours": 72}`,
+	}},
+	{"track_report", "See how tracked posts have gained points and comments.", []string{
+		`%s {}`,
+		`%s {"post_id": "1abc234", "timezone": "Europe/London"}`,
+	}},
+	{"track_stop", "Stop tracking a post.", []string{
+		`%s {"post_id": "1abc234"}`,
+	}},
+	{"comment_stream", "Follow a live thread, such as an AMA, getting only the comments posted since the last call.", []string{
+		`%s {"post_id": "1abc23x"} (call again for what's new)`,
+		`%s {"post_id": "1abc23x", "author": "ama_guest"} (only the guest's answers)`,
+	}},
+	{"post_stream", "Hand a bot each new post in a subreddit exactly once.", []string{
+		`%s {"subreddit": "golang", "consumer": "answer-bot"}`,
+		`%s {"subreddit": "golang", "consumer": "answer-bot", "peek": true} (look without using them up)`,
+	}},
+	{"help", "Show these examples, optionally for a single tool.", []string{
+		`%s {"tool": "comments"} (tools can be named with or without their prefix)`,
+	}},
+}
+
+// Return usage examples for every offered tool, or just the one asked for
+func handleRedditHelp(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	cfg := currentConfig()
+	want, _ := request.GetArguments()["tool"].(string)
+
+	var sb strings.Builder
+	found := false
+	for _, ex := range helpExamples {
+		name := toolName(cfg, ex.tool)
+		if !toolEnabled(cfg, ex.tool) || (want != "" && want != name && want != ex.tool) {
+			continue
+		}
+		found = true
+		fmt.Fprintf(&sb, "%s: %s\n", name, ex.summary)
+		for _, example := range ex.examples {
+			fmt.Fprintf(&sb, "  "+example+"\n", name)
+		}
+		sb.WriteString("\n")
+	}
+	if !found {
+		return toolError(invalidInput("unknown tool %q", want)), nil
+	}
+
+	if want == "" {
+		sb.WriteString("Typical research chain:\n")
+		fmt.Fprintf(&sb, "  1. %s {\"query\": \"standing desk\", \"subreddit\": \"BuyItForLife\"} and pick a result's \"Post ID:\"\n", toolName(cfg, "search"))
+		fmt.Fprintf(&sb, "  2. %s {\"post_id\": \"<that ID>\"} for the full post\n", toolName(cfg, "post"))
+		fmt.Fprintf(&sb, "  3. %s {\"post_id\": \"<that ID>\", \"sort\": \"top\"} for the discussion\n\n", toolName(cfg, "comments"))
+		sb.WriteString("Accepted formats:\n")
+		sb.WriteString("  post_id: the bare ID (1abc23x) or the fullname with its t3_ prefix (t3_1abc23x)\n")
+		sb.WriteString("  subreddit: the name without r/ (golang, not r/golang)\n")
+		sb.WriteString("  limit: a number; keep it small and raise it only when needed\n")
+	}
+
+	return mcp.NewToolResultText(sb.String()), nil
+}
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// Jobs added through tools, on top of those in the config file
+	maxJobs = 20
+	// Results kept per job, newest first
+	maxJobRuns = 10
+)
+
+// URI template of a job's results
+const jobTemplate = "reddit://job/{name}{?run}"
+
+// Characters allowed in job names, which appear in resource URIs
+var jobNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// A recurring job: a search, subreddit_posts, post or comments call run on a
+// cron schedule
+type jobSpec struct {
+	Name      string         `json:"name"`
+	Schedule  string         `json:"schedule"`
+	Tool      string         `json:"tool"`
+	Arguments map[string]any `json:"arguments,omitempty"`
+}
+
+// Check a job and resolve its tool to a base name and handler
+func (j jobSpec) compile(cfg *config) (*cronSchedule, string, server.ToolHandlerFunc, error) {
+	if !jobNamePattern.MatchString(j.Name) {
+		return nil, "", nil, fmt.Errorf("job name %q must be 1-64 letters, digits, _ or -", j.Name)
+	}
+	schedule, err := parseCron(j.Schedule)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	for base, handler := range batchTools {
+		if j.Tool == base || j.Tool == toolName(cfg, base) {
+			return schedule, base, handler, nil
+		}
+	}
+	return nil, "", nil, fmt.Errorf("job %s: %q can't be scheduled; use one of %s", j.Name, j.Tool, batchableTools(cfg))
+}
+
+// One run of a job and what the tool returned
+type jobRun struct {
+	at     time.Time
+	result batchResult
+}
+
+type job struct {
+	spec       jobSpec
+	schedule   *cronSchedule
+	base       string
+	handler    server.ToolHandlerFunc
+	fromConfig bool
+
+	next    time.Time
+	running bool
+	runs    []jobRun // newest first
+}
+
+type jobRegistry struct {
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+var jobs = &jobRegistry{jobs: make(map[string]*job)}
+
+// Times are scheduled in the server's timezone
+func jobLocation() *time.Location {
+	if loc, err := time.LoadLocation(currentConfig().Timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// Add a job; returns an error when the name is taken or the job is invalid
+func (r *jobRegistry) add(spec jobSpec, fromConfig bool) (*job, error) {
+	schedule, base, handler, err := spec.compile(currentConfig())
+	if err != nil {
+		return nil, invalidInput("%v", err)
+	}
+	if spec.Arguments == nil {
+		spec.Arguments = map[string]any{}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.jobs[spec.Name]; ok {
+		return nil, invalidInput("a job named %s already exists", spec.Name)
+	}
+	if !fromConfig {
+		added := 0
+		for _, j := range r.jobs {
+			if !j.fromConfig {
+				added++
+			}
+		}
+		if added >= maxJobs {
+			return nil, invalidInput("at most %d jobs can be added; remove one first", maxJobs)
+		}
+	}
+	j := &job{spec: spec, schedule: schedule, base: base, handler: handler, fromConfig: fromConfig}
+	j.next = schedule.next(time.Now().In(jobLocation()))
+	r.jobs[spec.Name] = j
+	return j, nil
+}
+
+func (r *jobRegistry) remove(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	j, ok := r.jobs[name]
+	switch {
+	case !ok:
+		return invalidInput("no job %q; see job_list for the names", name)
+	case j.fromConfig:
+		return invalidInput("job %s is defined in the config file; remove it there and reload", name)
+	}
+	delete(r.jobs, name)
+	return nil
+}
+
+// Jobs ordered by name, as copies safe to read without the lock
+func (r *jobRegistry) list() []job {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := make([]job, 0, len(r.jobs))
+	for _, j := range r.jobs {
+		c := *j
+		c.runs = slices.Clone(j.runs)
+		list = append(list, c)
+	}
+	slices.SortFunc(list, func(a, b job) int {
+		return strings.Compare(a.spec.Name, b.spec.Name)
+	})
+	return list
+}
+
+func (r *jobRegistry) get(name string) (job, bool) {
+	for _, j := range r.list() {
+		if j.spec.Name == name {
+			return j, true
+		}
+	}
+	return job{}, false
+}
+
+// Replace the config file's jobs, keeping the results of jobs that didn't change
+func (r *jobRegistry) syncConfig(specs []jobSpec) {
+	specs = slices.Clone(specs)
+	for i := range specs {
+		if specs[i].Arguments == nil {
+			specs[i].Arguments = map[string]any{}
+		}
+	}
+
+	r.mu.Lock()
+	keep := make(map[string]bool)
+	for name, j := range r.jobs {
+		if !j.fromConfig {
+			continue
+		}
+		i := slices.IndexFunc(specs, func(spec jobSpec) bool { return spec.Name == name })
+		if i >= 0 && specs[i].Schedule == j.spec.Schedule && specs[i].Tool == j.spec.Tool && reflect.DeepEqual(specs[i].Arguments, j.spec.Arguments) {
+			keep[name] = true
+		} else {
+			delete(r.jobs, name)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, spec := range specs {
+		if keep[spec.Name] {
+			continue
+		}
+		if _, err := r.add(spec, true); err != nil {
+			slog.Warn("Invalid job in config", "job", spec.Name, "error", err)
+		}
+	}
+}
+
+// Load the config file's jobs, follow them across reloads and offer their results as resources
+func startJobs(s *server.MCPServer) {
+	jobs.syncConfig(currentConfig().Jobs)
+	onReload(func(old, cfg *config) {
+		jobs.syncConfig(cfg.Jobs)
+	})
+	s.AddResourceTemplate(
+		mcp.NewResourceTemplate(jobTemplate, "Scheduled job results",
+			mcp.WithTemplateDescription("Output of a scheduled job's latest run, or of an earlier one with run=2 (the one before) up to 10"),
+			mcp.WithTemplateMIMEType("text/plain"),
+		),
+		func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			args := request.Params.Arguments
+			j, ok := jobs.get(resourceArg(args, "name"))
+			if !ok {
+				return nil, invalidInput("no job %q; see job_list for the names", resourceArg(args, "name"))
+			}
+			run, err := j.run(resourceArg(args, "run"))
+			if err != nil {
+				return nil, err
+			}
+			return textResource(request.Params.URI, formatJobRun(&j, run)), nil
+		},
+	)
+}
+
+// The run numbered n (1 for the latest, as text), or an error when there's none
+func (j *job) run(n string) (*jobRun, error) {
+	i := 1
+	if n != "" {
+		if _, err := fmt.Sscan(n, &i); err != nil || i < 1 {
+			return nil, invalidInput("run must be a number from 1 to %d", maxJobRuns)
+		}
+	}
+	if len(j.runs) == 0 {
+		return nil, invalidInput("job %s hasn't run yet; next run %s", j.spec.Name, formatJobTime(j.next))
+	}
+	if i > len(j.runs) {
+		return nil, invalidInput("job %s has %d stored runs", j.spec.Name, len(j.runs))
+	}
+	return &j.runs[i-1], nil
+}
+
+// Run jobs as they fall due, checking twice a minute
+func runJobs(ctx context.Context) {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, j := range jobs.due(time.Now()) {
+			go jobs.execute(withCacheRefresh(ctx), j)
+		}
+	}
+}
+
+// Jobs whose time has come, marked running so a slow run isn't started twice
+func (r *jobRegistry) due(now time.Time) []*job {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var due []*job
+	for _, j := range r.jobs {
+		if j.running || j.next.IsZero() || now.Before(j.next) {
+			continue
+		}
+		j.running = true
+		j.next = j.schedule.next(now.In(jobLocation()))
+		due = append(due, j)
+	}
+	return due
+}
+
+// Run a job's call and store its result
+func (r *jobRegistry) execute(ctx context.Context, j *job) {
+	at := time.Now()
+	result := runBatchRequest(ctx, batchRequest{key: j.spec.Name, base: j.base, handler: j.handler, args: maps.Clone(j.spec.Arguments)})
+	if result.IsError {
+		slog.Warn("Scheduled job failed", "job", j.spec.Name, "error", result.Text)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	j.running = false
+	j.runs = slices.Insert(j.runs, 0, jobRun{at: at, result: result})
+	if len(j.runs) > maxJobRuns {
+		j.runs = j.runs[:maxJobRuns]
+	}
+}
+
+// A time in the server's timezone, or "never" for a schedule that doesn't fire
+func formatJobTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.In(jobLocation()).Format(time.RFC3339)
+}
+
+// A run's output under a line saying what and when it was
+func formatJobRun(j *job, run *jobRun) string {
+	status := ""
+	if run.result.IsError {
+		status = " (failed)"
+	}
+	return fmt.Sprintf("Job %s: %s at %s%s\n\n%s\n", j.spec.Name, run.result.Tool, formatJobTime(run.at), status, strings.TrimRight(run.result.Text, "\n"))
+}
+
+type jobOutput struct {
+	Name       string         `json:"name"`
+	Schedule   string         `json:"schedule" jsonschema:"Cron schedule in the server's timezone"`
+	Tool       string         `json:"tool"`
+	Arguments  map[string]any `json:"arguments"`
+	FromConfig bool           `json:"from_config,omitempty" jsonschema:"Defined in the config file; change or remove it there"`
+	NextRun    string         `json:"next_run,omitempty" jsonschema:"RFC 3339 time of the next run; absent if the schedule never fires"`
+	LastRun    string         `json:"last_run,omitempty" jsonschema:"RFC 3339 time of the latest stored run"`
+	Runs       int            `json:"runs" jsonschema:"Stored runs, readable with job_results"`
+	Resource   string         `json:"resource" jsonschema:"Resource holding the latest run's output"`
+}
+
+// Output of reddit_job_list
+type jobListOutput struct {
+	Jobs []jobOutput `json:"jobs"`
+}
+
+// Output of reddit_job_results
+type jobResultOutput struct {
+	Job    string      `json:"job"`
+	RanAt  string      `json:"ran_at" jsonschema:"RFC 3339 time the run started"`
+	Result batchResult `json:"result"`
+}
+
+func newJobOutput(j *job) jobOutput {
+	out := jobOutput{
+		Name:       j.spec.Name,
+		Schedule:   j.spec.Schedule,
+		Tool:       toolName(currentConfig(), j.base),
+		Arguments:  j.spec.Arguments,
+		FromConfig: j.fromConfig,
+		Runs:       len(j.runs),
+		Resource:   "reddit://job/" + j.spec.Name,
+	}
+	if !j.next.IsZero() {
+		out.NextRun = formatJobTime(j.next)
+	}
+	if len(j.runs) > 0 {
+		out.LastRun = formatJobTime(j.runs[0].at)
+	}
+	return out
+}
+
+// Schedule a recurring call
+func handleRedditJobAdd(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	spec := jobSpec{}
+	spec.Name, _ = args["name"].(string)
+	spec.Schedule, _ = args["schedule"].(string)
+	spec.Tool, _ = args["tool"].(string)
+	spec.Arguments, _ = args["arguments"].(map[string]any)
+
+	j, err := jobs.add(spec, false)
+	if err != nil {
+		return toolError(err), nil
+	}
+	out := newJobOutput(j)
+	text := fmt.Sprintf("Scheduled job %s: %s %s.\nNext run: %s. Read results with %s {\"name\": %q} or from %s.\n",
+		out.Name, out.Tool, out.Schedule, formatJobTime(j.next), toolName(currentConfig(), "job_results"), out.Name, out.Resource)
+	return newStructuredResult(out, text), nil
+}
+
+// List scheduled jobs with their next and latest runs
+func handleRedditJobList(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	out := &jobListOutput{Jobs: []jobOutput{}}
+	var sb strings.Builder
+	list := jobs.list()
+	if len(list) == 0 {
+		fmt.Fprintf(&sb, "No scheduled jobs. Add one with %s.\n", toolName(currentConfig(), "job_add"))
+	}
+	for i := range list {
+		j := &list[i]
+		o := newJobOutput(j)
+		out.Jobs = append(out.Jobs, o)
+		fmt.Fprintf(&sb, "%s: %s %s\n", o.Name, o.Tool, o.Schedule)
+		fmt.Fprintf(&sb, "   Next run: %s", formatJobTime(j.next))
+		if o.LastRun != "" {
+			fmt.Fprintf(&sb, " | Last run: %s | Stored runs: %d", o.LastRun, o.Runs)
+		}
+		sb.WriteString("\n")
+		if j.fromConfig {
+			sb.WriteString("   From the config file\n")
+		}
+		sb.WriteString("\n")
+	}
+	return newStructuredResult(out, sb.String()), nil
+}
+
+// Stop a scheduled job, discarding its results
+func handleRedditJobRemove(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name, _ := request.GetArguments()["name"].(string)
+	if name == "" {
+		return toolError(invalidInput("name is required")), nil
+	}
+	if err := jobs.remove(name); err != nil {
+		return toolError(err), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Removed job %s.\n", name)), nil
+}
+
+// Return the output of a job's latest run, or an earlier one
+func handleRedditJobResults(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	name, _ := args["name"].(string)
+	j, ok := jobs.get(name)
+	if !ok {
+		return toolError(invalidInput("no job %q; see job_list for the names", name)), nil
+	}
+	n := ""
+	if run, ok := args["run"].(float64); ok {
+		n = fmt.Sprint(int(run))
+	}
+	if len(j.runs) == 0 {
+		return mcp.NewToolResultText(fmt.Sprintf("Job %s hasn't run yet; next run %s.\n", name, formatJobTime(j.next))), nil
+	}
+	run, err := j.run(n)
+	if err != nil {
+		return toolError(err), nil
+	}
+	out := &jobResultOutput{Job: name, RanAt: formatJobTime(run.at), Result: run.result}
+	return newStructuredResult(out, formatJobRun(&j, run)), nil
+}
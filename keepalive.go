@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// SSE streams whose sessions haven't sent a message for -session-idle-timeout
+// are closed, so abandoned sessions behind proxies that never report the
+// disconnect don't linger. (The http transport has an equivalent built in.)
+type sseIdleTracker struct {
+	mu       sync.Mutex
+	sessions map[string]*sseStream
+}
+
+// An open SSE stream: cancelling it ends the GET request serving the session
+type sseStream struct {
+	cancel   context.CancelFunc
+	lastSeen time.Time
+}
+
+var sseIdleSessions = &sseIdleTracker{sessions: make(map[string]*sseStream)}
+
+type sseStreamKey struct{}
+
+// Learn the session ID of each stream as mcp-go registers it; the stream's
+// request context carries the *sseStream set up by middleware
+func (t *sseIdleTracker) addHooks(hooks *server.Hooks) {
+	hooks.AddOnRegisterSession(func(ctx context.Context, session server.ClientSession) {
+		if stream, ok := ctx.Value(sseStreamKey{}).(*sseStream); ok {
+			t.mu.Lock()
+			t.sessions[session.SessionID()] = stream
+			t.mu.Unlock()
+		}
+	})
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		t.mu.Lock()
+		delete(t.sessions, session.SessionID())
+		t.mu.Unlock()
+	})
+}
+
+// Make SSE streams cancellable and count message POSTs as session activity
+func (t *sseIdleTracker) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			ctx, cancel := context.WithCancel(r.Context())
+			defer cancel()
+			stream := &sseStream{cancel: cancel, lastSeen: time.Now()}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, sseStreamKey{}, stream)))
+			return
+		}
+
+		if id := r.URL.Query().Get("sessionId"); id != "" {
+			t.mu.Lock()
+			if stream, ok := t.sessions[id]; ok {
+				stream.lastSeen = time.Now()
+			}
+			t.mu.Unlock()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Close streams idle for longer than timeout, checking a few times per timeout
+func (t *sseIdleTracker) sweep(timeout time.Duration) {
+	ticker := time.NewTicker(max(timeout/4, time.Second))
+	defer ticker.Stop()
+	for range ticker.C {
+		t.mu.Lock()
+		for id, stream := range t.sessions {
+			if time.Since(stream.lastSeen) > timeout {
+				slog.Info("Closing idle SSE session", "session", id, "idle", time.Since(stream.lastSeen).Round(time.Second))
+				stream.cancel()
+				delete(t.sessions, id)
+			}
+		}
+		t.mu.Unlock()
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Returned when Reddit sends more than the configured maximum response size
+var errResponseTooLarge = errors.New("response too large")
+
+// Like io.LimitReader, but fails loudly instead of reporting a truncated body as EOF
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func newLimitedReader(r io.Reader, limit int64) io.Reader {
+	if limit <= 0 {
+		return r
+	}
+	return &limitedReader{r: r, remaining: limit}
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		// Only an error if there really is more data
+		var probe [1]byte
+		if n, _ := l.r.Read(probe[:]); n > 0 {
+			return 0, errResponseTooLarge
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// Build a text tool result, cutting it at the configured output size with a notice
+func newTextResult(text string) *mcp.CallToolResult {
+	return mcp.NewToolResultText(truncateOutput(text, currentConfig().MaxOutputChars))
+}
+
+// Build a result carrying structured content plus its text rendering; the text
+// is cut here, the structured content by structuredLimitMiddleware once any
+// output_format rendering has been made from it
+func newStructuredResult(structured any, text string) *mcp.CallToolResult {
+	return mcp.NewToolResultStructured(structured, truncateOutput(text, currentConfig().MaxOutputChars))
+}
+
+// Cut text to at most max bytes on a line (or at least rune) boundary
+func truncateOutput(text string, max int) string {
+	if max <= 0 || len(text) <= max {
+		return text
+	}
+
+	cut := len(truncateAt(text, max))
+	return text[:cut] + fmt.Sprintf("\n[Output truncated: showing %d of %d characters. Request fewer results or a specific item to see more.]\n", cut, len(text))
+}
+
+// Longest prefix of text within max bytes that ends on a line (or at least rune) boundary
+func truncateAt(text string, max int) string {
+	if len(text) <= max {
+		return text
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	if nl := strings.LastIndexByte(text[:cut], '\n'); nl > max/2 {
+		cut = nl + 1
+	}
+	return text[:cut]
+}
+
+// Longest strings kept while shrinking oversized structured content, tried in
+// turn before whole list items are dropped
+var structuredStringLimits = []int{2000, 500}
+
+// Tool middleware keeping structured content within the configured output
+// size: long strings such as bodies are shortened, then the last items of the
+// longest lists dropped, and "truncated" is set on what's returned
+func structuredLimitMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := next(ctx, request)
+		if err == nil && result != nil && result.StructuredContent != nil {
+			result.StructuredContent = capStructured(result.StructuredContent, currentConfig().MaxOutputChars)
+		}
+		return result, err
+	}
+}
+
+// Structured content whose JSON encoding fits in max bytes; content that
+// doesn't encode to an object is returned as it is
+func capStructured(structured any, max int) any {
+	if max <= 0 {
+		return structured
+	}
+	b, err := json.Marshal(structured)
+	if err != nil || len(b) <= max {
+		return structured
+	}
+	var out map[string]any
+	if err := json.Unmarshal(b, &out); err != nil {
+		return structured
+	}
+	out["truncated"] = true
+	size := func() int {
+		b, _ := json.Marshal(out)
+		return len(b)
+	}
+	for _, limit := range structuredStringLimits {
+		if shortenStrings(out, limit); size() <= max {
+			return out
+		}
+	}
+	// Cut the longest list to fit, measuring each item once, and move on to
+	// the next longest while one cut isn't enough
+	for total := size(); total > max; {
+		list, set := longestList(out)
+		if len(list) == 0 {
+			break
+		}
+		keep := len(list)
+		for keep > 0 && total > max {
+			keep--
+			b, _ := json.Marshal(list[keep])
+			total -= len(b)
+			if keep > 0 {
+				total-- // the comma before it
+			}
+		}
+		set(list[:keep])
+	}
+	return out
+}
+
+// Shorten every string in v longer than limit bytes, in place
+func shortenStrings(v any, limit int) {
+	shorten := func(s string) string {
+		if len(s) <= limit {
+			return s
+		}
+		return truncateAt(s, limit) + "..."
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		for key, each := range v {
+			if text, ok := each.(string); ok {
+				v[key] = shorten(text)
+			} else {
+				shortenStrings(each, limit)
+			}
+		}
+	case []any:
+		for i, each := range v {
+			if text, ok := each.(string); ok {
+				v[i] = shorten(text)
+			} else {
+				shortenStrings(each, limit)
+			}
+		}
+	}
+}
+
+// The longest list in the object, with a function replacing it there
+func longestList(obj map[string]any) ([]any, func([]any)) {
+	var longest []any
+	var set func([]any)
+	var walk func(v any, replace func([]any))
+	walk = func(v any, replace func([]any)) {
+		switch v := v.(type) {
+		case map[string]any:
+			for key, each := range v {
+				walk(each, func(list []any) { v[key] = list })
+			}
+		case []any:
+			if len(v) > len(longest) {
+				longest, set = v, replace
+			}
+			for i, each := range v {
+				walk(each, func(list []any) { v[i] = list })
+			}
+		}
+	}
+	walk(obj, nil)
+	return longest, set
+}
+
+// Let a tool's output schema carry the truncated flag capStructured sets;
+// the generated schemas allow no properties beyond the output type's own
+func allowTruncated(tool *mcp.Tool) {
+	if tool.OutputSchema.Properties == nil {
+		return
+	}
+	tool.OutputSchema.Properties["truncated"] = map[string]any{
+		"type":        "boolean",
+		"description": "The output was too large and was shortened: long text cut and the last list items left out",
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCapStructured(t *testing.T) {
+	posts := func(n, body int) *listingOutput {
+		out := &listingOutput{}
+		for i := range n {
+			out.Posts = append(out.Posts, postOutput{ID: string(rune('a' + i)), Title: "title", Selftext: strings.Repeat("x", body)})
+		}
+		return out
+	}
+	tests := []struct {
+		name      string
+		in        any
+		max       int
+		truncated bool
+		posts     int // posts left, when truncated
+	}{
+		{"fits", posts(3, 10), 10000, false, 0},
+		{"no limit", posts(3, 5000), 0, false, 0},
+		{"bodies shortened", posts(3, 5000), 5000, true, 3},
+		{"posts dropped", posts(20, 5000), 3000, true, 4},
+		{"not an object", []string{strings.Repeat("x", 100)}, 10, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := capStructured(tt.in, tt.max)
+			out, ok := got.(map[string]any)
+			if !tt.truncated {
+				if ok {
+					t.Fatalf("capStructured() = %v, want the content unchanged", got)
+				}
+				return
+			}
+			if !ok || out["truncated"] != true {
+				t.Fatalf("capStructured() = %v, want truncated content", got)
+			}
+			b, _ := json.Marshal(out)
+			if len(b) > tt.max {
+				t.Errorf("capped content is %d bytes, want at most %d", len(b), tt.max)
+			}
+			if n := len(out["posts"].([]any)); n != tt.posts {
+				t.Errorf("capped content has %d posts, want %d", n, tt.posts)
+			}
+		})
+	}
+}
+
+// A listing far over the limit, the case the cap exists for
+func BenchmarkCapStructured(b *testing.B) {
+	out := &listingOutput{}
+	for range 5000 {
+		out.Posts = append(out.Posts, postOutput{ID: "abc123", Title: "title", Selftext: strings.Repeat("x", 200)})
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		capStructured(out, 20000)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Link previews read only the start of the page, where <title> and the meta
+// tags live, and give up quickly on slow sites
+const (
+	linkPreviewBytes   = 256 << 10
+	linkPreviewTimeout = 5 * time.Second
+)
+
+// The link_preview argument of the post tool
+func linkPreviewArgument() mcp.ToolOption {
+	return mcp.WithBoolean("link_preview",
+		mcp.Description("For link posts, fetch the linked page's title and description, since the post title often doesn't say what the article is"),
+		mcp.DefaultBool(false),
+	)
+}
+
+// Title and description of the page a link post points at
+type linkPreview struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Links leave for arbitrary sites, so they may only reach public addresses;
+// a post can't point the server at its own network
+var linkPreviewClient = &http.Client{
+	Timeout: linkPreviewTimeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: linkPreviewTimeout,
+			Control: publicAddressOnly,
+		}).DialContext,
+		TLSHandshakeTimeout:   linkPreviewTimeout,
+		ResponseHeaderTimeout: linkPreviewTimeout,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return errors.New("stopped after 5 redirects")
+		}
+		return nil
+	},
+}
+
+// Dialer control refusing connections to loopback, private and other
+// non-public addresses; checked after DNS resolution, so names can't dodge it
+func publicAddressOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return fmt.Errorf("address %s is not public", host)
+	}
+	return nil
+}
+
+var (
+	titleTag = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	metaTag  = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	metaAttr = regexp.MustCompile(`(?is)\b(name|property|content)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+)
+
+// Fetch the title and description of the page at rawURL, or nil when it
+// isn't a reachable HTML page
+func fetchLinkPreview(ctx context.Context, rawURL string) *linkPreview {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil
+	}
+	req.Header.Set("User-Agent", userAgent())
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := linkPreviewClient.Do(req)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to fetch link preview", "url", rawURL, "error", err)
+		return nil
+	}
+	defer resp.Body.Close()
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if resp.StatusCode != http.StatusOK || (mediaType != "text/html" && mediaType != "application/xhtml+xml") {
+		return nil
+	}
+	page, err := io.ReadAll(io.LimitReader(resp.Body, linkPreviewBytes))
+	if err != nil && len(page) == 0 {
+		return nil
+	}
+	preview := parseLinkPreview(string(page))
+	if preview.Title == "" && preview.Description == "" {
+		return nil
+	}
+	return preview
+}
+
+// Pull the title and description out of an HTML page's head, preferring
+// Open Graph tags, which sites write for exactly this
+func parseLinkPreview(page string) *linkPreview {
+	meta := map[string]string{}
+	for _, tag := range metaTag.FindAllString(page, -1) {
+		var key, content string
+		for _, attr := range metaAttr.FindAllStringSubmatch(tag, -1) {
+			value := attr[2] + attr[3]
+			if strings.EqualFold(attr[1], "content") {
+				content = value
+			} else {
+				key = strings.ToLower(value)
+			}
+		}
+		if key != "" && content != "" {
+			if _, seen := meta[key]; !seen {
+				meta[key] = content
+			}
+		}
+	}
+
+	preview := &linkPreview{Title: meta["og:title"], Description: meta["og:description"]}
+	if preview.Title == "" {
+		if m := titleTag.FindStringSubmatch(page); m != nil {
+			preview.Title = m[1]
+		}
+	}
+	if preview.Description == "" {
+		preview.Description = meta["description"]
+	}
+	preview.Title = cleanPreviewText(preview.Title, 300)
+	preview.Description = cleanPreviewText(preview.Description, 1000)
+	return preview
+}
+
+// Unescape and collapse whitespace in text taken from HTML, capped at max bytes
+func cleanPreviewText(text string, max int) string {
+	text = strings.Join(strings.Fields(html.UnescapeString(text)), " ")
+	return truncateAt(text, max)
+}
+
+// Text shown for a link preview in post details
+func formatLinkPreview(preview *linkPreview) string {
+	var sb strings.Builder
+	sb.WriteString("Link preview:\n")
+	if preview.Title != "" {
+		fmt.Fprintf(&sb, "  Title: %s\n", preview.Title)
+	}
+	if preview.Description != "" {
+		fmt.Fprintf(&sb, "  Description: %s\n", preview.Description)
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
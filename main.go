@@ -1,31 +1,157 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"maps"
 	"net/http"
 	"net/url"
+	"os"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
+// Reddit's public JSON API
+const redditBaseURL = "https://www.reddit.com"
+
+// Default number of posts for subreddit listings, shared with cache pre-warming
+const defaultListingLimit = 10
+
+// Levels of comment replies shown when the call doesn't say
+const defaultCommentDepth = 3
+
+// Guidance sent to clients on initialize so models use the tools in sequence;
+// the verbs are the tool names in the order serverInstructions passes them
+const instructionsTemplate = `Read-only access to Reddit.
+
+Start with %[1]s (optionally within a subreddit) or %[2]s to find posts. Each result has a "Post ID:" line; pass that ID as post_id to %[3]s for the full post and to %[4]s for the discussion. IDs may be given with or without the "t3_" prefix, and subreddit names go without "r/".
+
+Prefer small limits and raise them only when needed; long output is truncated. For more results of the last listing, call %[5]s. The same data is available as resources (reddit://r/{subreddit}/{sort}, reddit://post/{id}, reddit://post/{id}/comments), and prompts such as summarize_thread bundle common workflows. Call %[6]s for worked examples of every tool.`
+
+// Instructions naming the tools as cfg advertises them
+func serverInstructions(cfg *config) string {
+	return fmt.Sprintf(instructionsTemplate,
+		toolName(cfg, "search"), toolName(cfg, "subreddit_posts"), toolName(cfg, "post"),
+		toolName(cfg, "comments"), toolName(cfg, "next_page"), toolName(cfg, "help"))
+}
+
 func main() {
-	// Create MCP server
+	// Parse command-line flags and the config file
+	cfg, err := parseConfig(os.Args[1:], os.Stderr)
+	if err != nil {
+		os.Exit(2)
+	}
+	if cfg.ShowVersion {
+		fmt.Println(versionString())
+		return
+	}
+	liveConfig.Store(cfg)
+	setupLogging(cfg)
+	setUpstreamConcurrency(cfg.MaxConcurrency)
+
+	// Keep what is fetched in the local archive
+	if cfg.Archive != "" {
+		if err := openArchive(cfg.Archive); err != nil {
+			fmt.Fprintf(os.Stderr, "Archive error: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	// Remember what post streams handed out across restarts
+	if cfg.StreamState != "" {
+		if err := openPostStreams(cfg.StreamState); err != nil {
+			fmt.Fprintf(os.Stderr, "Stream state error: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	// Reload the config file on SIGHUP without restarting
+	go watchReload(os.Args[1:])
+
+	// Fetch pinned subreddits up front so the first calls hit the cache
+	go warmPinnedSubreddits(context.Background())
+
+	s := newServer()
+
+	// Watch subscribed subreddit feeds for new posts
+	go pollSubscriptions(context.Background(), s)
+
+	// Check watched subreddits for posts matching watch rules
+	go pollWatches(context.Background(), s)
+
+	// Snapshot tracked posts
+	go pollTrackedPosts(context.Background())
+
+	// Run scheduled jobs as they fall due
+	go runJobs(context.Background())
+
+	// Start the server on the selected transport
+	t, err := newTransport(s, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Transport error: %v\n", err)
+		os.Exit(2)
+	}
+	if err := t.Serve(); err != nil {
+		slog.Error("Server error", "error", err)
+		os.Exit(1)
+	}
+}
+
+// Create the MCP server with all tools and resources registered
+func newServer() *server.MCPServer {
+	hooks := &server.Hooks{}
+	addSubscriptionHooks(hooks)
+	addWatchHooks(hooks)
+	lastListings.addHooks(hooks)
+	streams.addHooks(hooks)
+	sessionRedditTokens.addHooks(hooks)
+	sseIdleSessions.addHooks(hooks)
+
 	s := server.NewMCPServer(
 		"Reddit API Tool 🔍",
-		"1.0.0",
+		version,
+		server.WithDescription(buildDescription()),
+		server.WithInstructions(serverInstructions(currentConfig())),
 		server.WithLogging(),
 		server.WithRecovery(),
+		server.WithToolHandlerMiddleware(toolMiddleware),
+		server.WithResourceCapabilities(true, true),
+		server.WithToolCapabilities(true),
+		server.WithPromptCapabilities(false),
+		server.WithCompletions(),
+		server.WithPromptCompletionProvider(subredditCompleter{}),
+		server.WithResourceCompletionProvider(subredditCompleter{}),
+		server.WithHooks(hooks),
 	)
 
+	// Forward logs to clients as notifications/message
+	clientLogs.attach(s, hooks)
+
 	// 1. Search Reddit Tool
-	searchTool := mcp.NewTool("reddit_search",
+	searchTool := mcp.NewTool("search",
 		mcp.WithDescription("Search Reddit for posts matching a query"),
+		readOnlyTool("Search Reddit"),
+		mcp.WithOutputSchema[listingOutput](),
+		outputFormatArgument(),
+		timezoneArgument(),
+		citationsArgument(),
+		includeNSFWArgument(),
+		excludeStickiedArgument(),
+		minScoreArgument(),
+		mcp.WithBoolean("include_preview",
+			mcp.Description("Add the first 200 characters of each post's body, or the site a link post points at, to triage results without fetching each post"),
+			mcp.DefaultBool(false),
+		),
+		authorArgument(),
 		mcp.WithString("query",
 			mcp.Required(),
 			mcp.Description("Search query terms"),
@@ -44,20 +170,68 @@ func main() {
 			mcp.Min(1),
 			mcp.Max(25),
 		),
+		mcp.WithString("after_date",
+			mcp.Description("Only posts created on or after this date (YYYY-MM-DD in the timezone argument, or RFC 3339)"),
+		),
+		mcp.WithString("before_date",
+			mcp.Description("Only posts created before this date (YYYY-MM-DD or RFC 3339); after_date 2024-03-01 with before_date 2024-04-01 means March 2024"),
+		),
+		mcp.WithString("cursor",
+			mcp.Description("next_cursor from a previous result; continues that listing with its original arguments"),
+		),
+		mcp.WithString("backend",
+			mcp.Description("Where to search: reddit, or pullpush for the pullpush.io archive, which reaches years back and filters dates itself but has no relevance or hot ranking (relevance then means newest first) and shows scores as archived"),
+			mcp.Enum("reddit", "pullpush"),
+			mcp.DefaultString("reddit"),
+		),
 	)
 
 	// 2. Get Post Details Tool
-	postTool := mcp.NewTool("reddit_post",
+	postTool := mcp.NewTool("post",
 		mcp.WithDescription("Get details for a specific Reddit post"),
+		readOnlyTool("Get Reddit post"),
+		mcp.WithOutputSchema[postOutput](),
+		outputFormatArgument(),
+		timezoneArgument(),
+		citationsArgument(),
+		linkPreviewArgument(),
+		revealSpoilersArgument(),
+		includeNSFWArgument(),
+		recoverRemovedArgument(),
+		mcp.WithBoolean("strip_markdown",
+			mcp.Description("Render Reddit markdown as plain text (links as \"text (url)\", no emphasis or spoiler markers); defaults to the server's setting"),
+		),
 		mcp.WithString("post_id",
 			mcp.Required(),
 			mcp.Description("Reddit post ID (with or without prefix)"),
 		),
+		mcp.WithNumber("max_body_length",
+			mcp.Description("Characters of the body to include before cutting it short (0 for the full body; defaults to the server's limit)"),
+			mcp.Min(0),
+		),
 	)
 
 	// 3. Get Comments Tool
-	commentsTool := mcp.NewTool("reddit_comments",
+	commentsTool := mcp.NewTool("comments",
 		mcp.WithDescription("Get comments for a specific Reddit post"),
+		readOnlyTool("Get Reddit comments"),
+		mcp.WithOutputSchema[commentsOutput](),
+		outputFormatArgument(),
+		timezoneArgument(),
+		citationsArgument(),
+		revealSpoilersArgument(),
+		excludeBotsArgument(),
+		minScoreArgument(),
+		minLengthArgument(),
+		authorArgument(),
+		commentBudgetArgument(),
+		recoverRemovedArgument(),
+		mcp.WithString("comment_id",
+			mcp.Description("Start from this comment (it and its replies) instead of the whole thread, e.g. to continue a reply chain cut off by depth"),
+		),
+		mcp.WithBoolean("strip_markdown",
+			mcp.Description("Render Reddit markdown as plain text (links as \"text (url)\", no emphasis or spoiler markers); defaults to the server's setting"),
+		),
 		mcp.WithString("post_id",
 			mcp.Required(),
 			mcp.Description("Reddit post ID (with or without prefix)"),
@@ -68,28 +242,680 @@ func main() {
 			mcp.Min(1),
 			mcp.Max(100),
 		),
+		mcp.WithNumber("depth",
+			mcp.Description("Levels of replies to include, 1 for top-level comments only (1-10)"),
+			mcp.DefaultNumber(defaultCommentDepth),
+			mcp.Min(1),
+			mcp.Max(10),
+		),
+		mcp.WithString("view",
+			mcp.Description("threaded shows replies beneath their parents; flat lists every comment by score, for summarizing"),
+			mcp.Enum("threaded", "flat"),
+			mcp.DefaultString("threaded"),
+		),
+		mcp.WithString("sort",
+			mcp.Description("Sort method for comments"),
+			mcp.Enum("top", "new", "controversial", "old", "qa"),
+			mcp.DefaultString("top"),
+		),
+	)
+
+	// 4. Subreddit Posts Tool
+	subredditPostsTool := mcp.NewTool("subreddit_posts",
+		mcp.WithDescription("List posts from a subreddit's front page (hot, new, top or rising)"),
+		readOnlyTool("List subreddit posts"),
+		mcp.WithOutputSchema[listingOutput](),
+		outputFormatArgument(),
+		timezoneArgument(),
+		citationsArgument(),
+		includeNSFWArgument(),
+		excludeStickiedArgument(),
+		minScoreArgument(),
+		mcp.WithBoolean("include_preview",
+			mcp.Description("Add the first 200 characters of each post's body, or the site a link post points at, to triage results without fetching each post"),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithString("subreddit",
+			mcp.Required(),
+			mcp.Description("Subreddit name (without the 'r/' prefix)"),
+		),
+		mcp.WithString("sort",
+			mcp.Description("Which listing to fetch"),
+			mcp.Enum("hot", "new", "top", "rising"),
+			mcp.DefaultString("hot"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of posts to return (1-25)"),
+			mcp.DefaultNumber(defaultListingLimit),
+			mcp.Min(1),
+			mcp.Max(25),
+		),
+		mcp.WithString("cursor",
+			mcp.Description("next_cursor from a previous result; continues that listing with its original arguments"),
+		),
+	)
+
+	// 5. Next Page Tool
+	nextPageTool := mcp.NewTool("next_page",
+		mcp.WithDescription("Get the next page of the most recent search or subreddit listing in this session"),
+		readOnlyTool("Next page of results"),
+		mcp.WithOutputSchema[listingOutput](),
+		outputFormatArgument(),
+	)
+
+	// 6. Capabilities Tool
+	capabilitiesTool := mcp.NewTool("capabilities",
+		mcp.WithDescription("Report which features this server has enabled (Reddit auth mode, write tools, cache, transport, archive, output limits)"),
+		readOnlyTool("Server capabilities"),
+		mcp.WithOutputSchema[capabilitiesOutput](),
+		outputFormatArgument(),
+	)
+
+	// 7. Help Tool
+	helpTool := mcp.NewTool("help",
+		mcp.WithDescription("Show worked examples for every tool: arguments, accepted ID formats and how to chain search, post and comments"),
+		readOnlyTool("Usage examples"),
+		outputFormatArgument(),
+		mcp.WithString("tool",
+			mcp.Description("Only show examples for this tool"),
+		),
+	)
+
+	// 8. Batch Tool
+	batchTool := mcp.NewTool("batch",
+		mcp.WithDescription("Run several search, subreddit_posts, post and comments calls concurrently in one call and return each result under its key"),
+		readOnlyTool("Batch Reddit queries"),
+		mcp.WithOutputSchema[batchOutput](),
+		outputFormatArgument(),
+		mcp.WithArray("requests",
+			mcp.Required(),
+			mcp.Description(fmt.Sprintf("Calls to run, e.g. [{\"key\": \"a\", \"tool\": %q, \"arguments\": {\"query\": \"go\"}}]", toolName(currentConfig(), "search"))),
+			mcp.MinItems(1),
+			mcp.MaxItems(maxBatchRequests),
+			mcp.Items(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"key":       map[string]any{"type": "string", "description": "Name for this call's result (defaults to its index)"},
+					"tool":      map[string]any{"type": "string", "description": "Tool to call"},
+					"arguments": map[string]any{"type": "object", "description": "The tool's arguments"},
+				},
+				"required": []string{"tool"},
+			}),
+		),
+	)
+
+	// 9. Watch Tools
+	watchAddTool := mcp.NewTool("watch_add",
+		mcp.WithDescription("Watch a subreddit for new posts whose title or body matches a keyword or regular expression; matches are collected in the background"),
+		localStateTool("Add a Reddit watch", false, false),
+		mcp.WithOutputSchema[watchOutput](),
+		outputFormatArgument(),
+		mcp.WithString("subreddit",
+			mcp.Required(),
+			mcp.Description("Subreddit to watch, without the r/ prefix"),
+		),
+		mcp.WithString("keyword",
+			mcp.Description("Text to look for, case-insensitively (give this or pattern)"),
+		),
+		mcp.WithString("pattern",
+			mcp.Description("Go regular expression to match instead of a keyword, e.g. (?i)\\b(acme|acme corp)\\b"),
+		),
+		mcp.WithNumber("min_score",
+			mcp.Description("Only match posts scoring at least this much when polled"),
+		),
+		mcp.WithString("webhook",
+			mcp.Description("http or https URL to POST each match to, such as a Slack or Discord incoming webhook; it must resolve to a public address"),
+		),
+		mcp.WithString("webhook_template",
+			mcp.Description("Go text/template for the webhook body, given .Watch, .Rule and .Post (the post's structured fields, e.g. .Post.Title, .Post.Permalink); json quotes a value, e.g. {\"content\": {{json .Post.Title}}}. Defaults to the whole match as JSON"),
+		),
+		mcp.WithString("webhook_secret",
+			mcp.Description("Key for an HMAC-SHA256 signature of each body, sent as X-Signature-256: sha256=<hex>"),
+		),
+	)
+	watchListTool := mcp.NewTool("watch_list",
+		mcp.WithDescription("List watches with their pending match counts"),
+		localStateTool("List Reddit watches", true, false),
+		mcp.WithOutputSchema[watchListOutput](),
+		outputFormatArgument(),
+	)
+	watchRemoveTool := mcp.NewTool("watch_remove",
+		mcp.WithDescription("Stop a watch added with watch_add, discarding its pending matches"),
+		localStateTool("Remove a Reddit watch", false, true),
+		outputFormatArgument(),
+		mcp.WithString("id",
+			mcp.Required(),
+			mcp.Description("ID of the watch, as given by watch_add or watch_list"),
+		),
+	)
+	watchMatchesTool := mcp.NewTool("watch_matches",
+		mcp.WithDescription("Fetch the posts watches have matched since the last fetch; each match is returned once"),
+		localStateTool("Fetch Reddit watch matches", false, false),
+		mcp.WithOutputSchema[watchMatchesOutput](),
+		outputFormatArgument(),
+		timezoneArgument(),
+		citationsArgument(),
+		mcp.WithString("id",
+			mcp.Description("Watch to fetch matches of (defaults to every watch)"),
+		),
+	)
+
+	// 10. Scheduled Job Tools
+	jobAddTool := mcp.NewTool("job_add",
+		mcp.WithDescription("Schedule a recurring search, subreddit_posts, post or comments call, such as a nightly snapshot of a subreddit's top posts; each run's output is stored for job_results"),
+		localStateTool("Schedule a Reddit job", false, false),
+		mcp.WithOutputSchema[jobOutput](),
+		outputFormatArgument(),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("Name for the job: letters, digits, _ or -"),
+		),
+		mcp.WithString("schedule",
+			mcp.Required(),
+			mcp.Description("Cron schedule in the server's timezone: minute hour day-of-month month day-of-week, e.g. \"0 6 * * *\" for 06:00 daily or \"0 9 * * 1\" for Mondays at 09:00; @hourly, @daily, @weekly and @monthly also work"),
+		),
+		mcp.WithString("tool",
+			mcp.Required(),
+			mcp.Description("Tool to run: search, subreddit_posts, post or comments"),
+		),
+		mcp.WithObject("arguments",
+			mcp.Description("The tool's arguments, e.g. {\"subreddit\": \"golang\", \"sort\": \"top\", \"limit\": 10}"),
+		),
+	)
+	jobListTool := mcp.NewTool("job_list",
+		mcp.WithDescription("List scheduled jobs with their next and latest runs"),
+		localStateTool("List Reddit jobs", true, false),
+		mcp.WithOutputSchema[jobListOutput](),
+		outputFormatArgument(),
+	)
+	jobRemoveTool := mcp.NewTool("job_remove",
+		mcp.WithDescription("Stop a job added with job_add, discarding its stored results"),
+		localStateTool("Remove a Reddit job", false, true),
+		outputFormatArgument(),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("Name of the job"),
+		),
+	)
+	jobResultsTool := mcp.NewTool("job_results",
+		mcp.WithDescription("Read the output of a scheduled job's latest run, or of one of the runs before it"),
+		localStateTool("Read Reddit job results", true, false),
+		mcp.WithOutputSchema[jobResultOutput](),
+		outputFormatArgument(),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("Name of the job"),
+		),
+		mcp.WithNumber("run",
+			mcp.Description("Which run: 1 for the latest, 2 for the one before and so on"),
+			mcp.Min(1),
+			mcp.Max(maxJobRuns),
+		),
+	)
+
+	// 11. Archive Tools
+	archiveSearchTool := mcp.NewTool("archive_search",
+		mcp.WithDescription("Search the posts and comments this server has fetched before, offline and ranked by relevance, from its local archive, which also keeps versions later edited or deleted; needs the server started with --archive"),
+		localStateTool("Search the Reddit archive", true, false),
+		mcp.WithOutputSchema[archiveOutput](),
+		outputFormatArgument(),
+		timezoneArgument(),
+		mcp.WithString("query",
+			mcp.Description("Words to find in titles and bodies, matching other forms of each word (release finds released). Supports \"exact phrases\", OR, NOT, prefix* and column filters such as title:generics or author:spez"),
+		),
+		mcp.WithString("subreddit",
+			mcp.Description("Only items from this subreddit"),
+		),
+		mcp.WithString("author",
+			mcp.Description("Only items by this user"),
+		),
+		mcp.WithString("kind",
+			mcp.Description("Only posts or only comments"),
+			mcp.Enum("post", "comment"),
+		),
+		mcp.WithString("after_date",
+			mcp.Description("Only items created on or after this date (YYYY-MM-DD in the timezone argument, or RFC 3339)"),
+		),
+		mcp.WithString("before_date",
+			mcp.Description("Only items created before this date (YYYY-MM-DD or RFC 3339)"),
+		),
+		mcp.WithString("sort",
+			mcp.Description("relevance (best match first; the default with a query) or new (newest first; the default otherwise)"),
+			mcp.Enum("relevance", "new"),
+		),
+		mcp.WithBoolean("history",
+			mcp.Description("Return every archived version of matching items rather than only the latest, to see what was edited or deleted"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Most items to return (default 25)"),
+			mcp.Min(1),
+			mcp.Max(maxArchiveResults),
+		),
+	)
+
+	// 12. Export Tools
+	exportThreadTool := mcp.NewTool("export_thread",
+		mcp.WithDescription("Export a post with its whole comment tree, collapsed comments expanded, as a Markdown, standalone HTML or JSON document for archiving and offline reading. The document is written to the server's export directory when it has one, and otherwise attached to the result as a resource"),
+		// Writes a new file to the operator's export directory, never replacing one
+		localWriteTool("Export a Reddit thread"),
+		mcp.WithOutputSchema[exportOutput](),
+		outputFormatArgument(),
+		includeNSFWArgument(),
+		revealSpoilersArgument(),
+		mcp.WithString("post_id",
+			mcp.Required(),
+			mcp.Description("Reddit post ID (with or without prefix)"),
+		),
+		mcp.WithString("format",
+			mcp.Description("markdown, html (a standalone page) or json"),
+			mcp.Enum("markdown", "html", "json"),
+			mcp.DefaultString("markdown"),
+		),
 		mcp.WithString("sort",
 			mcp.Description("Sort method for comments"),
 			mcp.Enum("top", "new", "controversial", "old", "qa"),
 			mcp.DefaultString("top"),
 		),
+		mcp.WithString("filename",
+			mcp.Description("Name of the file to write in the export directory (default <post_id>.md, .html or .json); existing files are never replaced"),
+		),
+	)
+
+	exportSubredditTool := mcp.NewTool("export_subreddit",
+		mcp.WithDescription("Page through up to 1000 posts of a subreddit listing and export them as JSONL or CSV, for building datasets. Pages are fetched slowly to stay within Reddit's rate limits, with progress notifications; the file goes to the server's export directory when it has one, and is otherwise attached to the result as a resource"),
+		// Writes a new file to the operator's export directory, like export_thread
+		localWriteTool("Export subreddit posts"),
+		mcp.WithOutputSchema[subredditExportOutput](),
+		outputFormatArgument(),
+		includeNSFWArgument(),
+		revealSpoilersArgument(),
+		excludeStickiedArgument(),
+		minScoreArgument(),
+		mcp.WithString("subreddit",
+			mcp.Required(),
+			mcp.Description("Subreddit name (without the 'r/' prefix)"),
+		),
+		mcp.WithString("sort",
+			mcp.Description("Which listing to page through"),
+			mcp.Enum("hot", "new", "top", "rising", "controversial"),
+			mcp.DefaultString("new"),
+		),
+		mcp.WithString("time",
+			mcp.Description("For sort top or controversial, the period to rank over"),
+			mcp.Enum("hour", "day", "week", "month", "year", "all"),
+		),
+		mcp.WithNumber("max_posts",
+			mcp.Description("Most posts to export"),
+			mcp.DefaultNumber(defaultExportPosts),
+			mcp.Min(1),
+			mcp.Max(maxExportPosts),
+		),
+		mcp.WithString("format",
+			mcp.Description("jsonl (one JSON object per post) or csv (a table with a header row)"),
+			mcp.Enum("jsonl", "csv"),
+			mcp.DefaultString("jsonl"),
+		),
+		mcp.WithString("filename",
+			mcp.Description("Name of the file to write in the export directory (default <subreddit>-<sort>.jsonl or .csv); existing files are never replaced"),
+		),
+	)
+
+	// 13. Digest Tool
+	digestTool := mcp.NewTool("digest",
+		mcp.WithDescription("Brief on what happened in one or more subreddits over the last day, week or month: their top posts, the top comment on the leading posts, and topics showing up in more titles than usual. Digests are reused for a while (15 minutes for a day), so asking again is cheap"),
+		readOnlyTool("Subreddit digest"),
+		mcp.WithOutputSchema[digestOutput](),
+		outputFormatArgument(),
+		timezoneArgument(),
+		includeNSFWArgument(),
+		excludeStickiedArgument(),
+		minScoreArgument(),
+		mcp.WithArray("subreddits",
+			mcp.Required(),
+			mcp.Description("Subreddit names (without the 'r/' prefix)"),
+			mcp.WithStringItems(),
+			mcp.MinItems(1),
+			mcp.MaxItems(maxDigestSubreddits),
+		),
+		mcp.WithString("window",
+			mcp.Description("Period covered"),
+			mcp.Enum("day", "week", "month"),
+			mcp.DefaultString("day"),
+		),
+		mcp.WithNumber("posts_per_subreddit",
+			mcp.Description("Top posts listed for each subreddit"),
+			mcp.DefaultNumber(defaultDigestPosts),
+			mcp.Min(1),
+			mcp.Max(maxDigestPosts),
+		),
+	)
+
+	// 14. Multi-subreddit Search Tool
+	multiSearchTool := mcp.NewTool("multi_search",
+		mcp.WithDescription("Search several subreddits at once for posts matching a query, merging their results into one ranking with duplicates collapsed"),
+		readOnlyTool("Search subreddits"),
+		mcp.WithOutputSchema[listingOutput](),
+		outputFormatArgument(),
+		timezoneArgument(),
+		citationsArgument(),
+		includeNSFWArgument(),
+		excludeStickiedArgument(),
+		minScoreArgument(),
+		authorArgument(),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("Search query terms"),
+		),
+		mcp.WithArray("subreddits",
+			mcp.Required(),
+			mcp.Description("Subreddit names to search (without the 'r/' prefix)"),
+			mcp.WithStringItems(),
+			mcp.MinItems(1),
+			mcp.MaxItems(maxMultiSearchSubreddits),
+		),
+		mcp.WithString("sort",
+			mcp.Description("Sort method for results; relevance and hot interleave each subreddit's ranking"),
+			mcp.Enum("relevance", "hot", "new", "top"),
+			mcp.DefaultString("relevance"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of merged results to return"),
+			mcp.DefaultNumber(10),
+			mcp.Min(1),
+			mcp.Max(maxMultiSearchResults),
+		),
+		mcp.WithString("after_date",
+			mcp.Description("Only posts created on or after this date (YYYY-MM-DD in the timezone argument, or RFC 3339)"),
+		),
+		mcp.WithString("before_date",
+			mcp.Description("Only posts created before this date (YYYY-MM-DD or RFC 3339)"),
+		),
+	)
+
+	// 15. User Analysis Tool
+	userAnalysisTool := mcp.NewTool("user_analysis",
+		mcp.WithDescription("Sum up a user's recent posts and comments: the subreddits they're most active in, when they post by hour and weekday, their average scores, and the words they use most"),
+		readOnlyTool("Analyze user activity"),
+		mcp.WithOutputSchema[userAnalysisOutput](),
+		outputFormatArgument(),
+		timezoneArgument(),
+		mcp.WithString("username",
+			mcp.Required(),
+			mcp.Description("Reddit username (without u/)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Latest posts and comments sampled; Reddit lists at most about 1000"),
+			mcp.DefaultNumber(defaultUserSample),
+			mcp.Min(1),
+			mcp.Max(maxUserSample),
+		),
+	)
+
+	// 16. Subreddit Stats Tool
+	subredditStatsTool := mcp.NewTool("subreddit_stats",
+		mcp.WithDescription("Gauge how active and healthy a subreddit is from its latest posts: posts a day, score and comment count distributions, top contributors and the flairs in use"),
+		readOnlyTool("Subreddit stats"),
+		mcp.WithOutputSchema[subredditStatsOutput](),
+		outputFormatArgument(),
+		timezoneArgument(),
+		mcp.WithString("subreddit",
+			mcp.Required(),
+			mcp.Description("Subreddit name (without the 'r/' prefix)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Latest posts sampled; a larger sample reaches further back"),
+			mcp.DefaultNumber(defaultStatsSample),
+			mcp.Min(1),
+			mcp.Max(maxStatsSample),
+		),
 	)
 
-	// Add tool handlers
-	s.AddTool(searchTool, handleRedditSearch)
-	s.AddTool(postTool, handleRedditPost)
-	s.AddTool(commentsTool, handleRedditComments)
+	// 17. Keyword Trend Tool
+	keywordTrendTool := mcp.NewTool("keyword_trend",
+		mcp.WithDescription("Count how often posts in one or more subreddits mention a keyword per day or week over a recent window, with their average score, to tell whether interest is growing or fading"),
+		readOnlyTool("Keyword trend"),
+		mcp.WithOutputSchema[keywordTrendOutput](),
+		outputFormatArgument(),
+		timezoneArgument(),
+		includeNSFWArgument(),
+		excludeStickiedArgument(),
+		minScoreArgument(),
+		mcp.WithString("keyword",
+			mcp.Required(),
+			mcp.Description("Keyword or phrase to count; Reddit search syntax such as quotes and OR works"),
+		),
+		mcp.WithArray("subreddits",
+			mcp.Required(),
+			mcp.Description("Subreddit names (without the 'r/' prefix)"),
+			mcp.WithStringItems(),
+			mcp.MinItems(1),
+			mcp.MaxItems(maxTrendSubreddits),
+		),
+		mcp.WithNumber("days",
+			mcp.Description("Days back from now the trend covers"),
+			mcp.DefaultNumber(defaultTrendDays),
+			mcp.Min(2),
+			mcp.Max(maxTrendDays),
+		),
+		mcp.WithString("bucket",
+			mcp.Description("Count per day or per week; defaults to day up to 60 days and week beyond"),
+			mcp.Enum("day", "week"),
+		),
+	)
+
+	// 18. Post Tracking Tools
+	trackPostTool := mcp.NewTool("track_post",
+		mcp.WithDescription("Start following how a post performs: its score, comment count and awards are snapshotted in the background for track_report. Tracking a post again extends it"),
+		localStateTool("Track a Reddit post", false, false),
+		mcp.WithOutputSchema[trackedPostOutput](),
+		outputFormatArgument(),
+		mcp.WithString("post_id",
+			mcp.Required(),
+			mcp.Description("ID of the post to track"),
+		),
+		mcp.WithNumber("hours",
+			mcp.Description("How long to keep taking snapshots"),
+			mcp.DefaultNumber(defaultTrackHours),
+			mcp.Min(1),
+			mcp.Max(maxTrackHours),
+		),
+	)
+	trackReportTool := mcp.NewTool("track_report",
+		mcp.WithDescription("Report how tracked posts have done since tracking started: score, comment and award changes, hourly rates and, for a single post, its snapshots over time"),
+		localStateTool("Report on tracked posts", true, false),
+		mcp.WithOutputSchema[trackReportOutput](),
+		outputFormatArgument(),
+		timezoneArgument(),
+		mcp.WithString("post_id",
+			mcp.Description("Tracked post to report on in detail (defaults to a summary of every tracked post)"),
+		),
+	)
+	trackStopTool := mcp.NewTool("track_stop",
+		mcp.WithDescription("Stop tracking a post, discarding its snapshots"),
+		localStateTool("Stop tracking a Reddit post", false, true),
+		outputFormatArgument(),
+		mcp.WithString("post_id",
+			mcp.Required(),
+			mcp.Description("ID of the tracked post"),
+		),
+	)
+
+	// 19. Comment Stream Tool
+	commentStreamTool := mcp.NewTool("comment_stream",
+		mcp.WithDescription("Follow a post's discussion live: the first call returns its latest comments, and each later call in the session only the comments posted since the previous one, replies included"),
+		// Each call moves the session's checkpoint, so repeating it returns less
+		localWriteTool("Stream new Reddit comments"),
+		mcp.WithOutputSchema[commentStreamOutput](),
+		outputFormatArgument(),
+		timezoneArgument(),
+		citationsArgument(),
+		revealSpoilersArgument(),
+		excludeBotsArgument(),
+		minScoreArgument(),
+		minLengthArgument(),
+		authorArgument(),
+		mcp.WithString("post_id",
+			mcp.Required(),
+			mcp.Description("Reddit post ID (with or without prefix)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Newest comments to read on each call (1-100); in busy threads, comments beyond these between two calls are missed"),
+			mcp.DefaultNumber(maxStreamComments),
+			mcp.Min(1),
+			mcp.Max(maxStreamComments),
+		),
+		mcp.WithBoolean("reset",
+			mcp.Description("Forget where this session was in the post's comments and start again from the latest"),
+			mcp.DefaultBool(false),
+		),
+	)
 
-	// Start the server
-	if err := server.ServeStdio(s); err != nil {
-		fmt.Printf("Server error: %v\n", err)
+	// 20. Post Stream Tool
+	postStreamTool := mcp.NewTool("post_stream",
+		mcp.WithDescription("For bots: return a subreddit's new posts that this consumer hasn't been handed before, oldest first. Each post is handed out once per consumer, across calls and, with the server's stream state file, across restarts"),
+		// Each call saves the consumer's checkpoint; only peek leaves it alone
+		localWriteTool("Stream new Reddit posts"),
+		mcp.WithOutputSchema[postStreamOutput](),
+		outputFormatArgument(),
+		timezoneArgument(),
+		citationsArgument(),
+		includeNSFWArgument(),
+		excludeStickiedArgument(),
+		minScoreArgument(),
+		authorArgument(),
+		mcp.WithString("subreddit",
+			mcp.Required(),
+			mcp.Description("Subreddit to stream, without the r/ prefix"),
+		),
+		mcp.WithString("consumer",
+			mcp.Description("Name of the bot reading the stream; each consumer has its own checkpoint (letters, digits, dots, dashes and underscores)"),
+			mcp.DefaultString("default"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Most posts to hand out in one call (1-100); the rest wait for the next call"),
+			mcp.DefaultNumber(defaultPostStreamLimit),
+			mcp.Min(1),
+			mcp.Max(maxPostStreamLimit),
+		),
+		mcp.WithBoolean("peek",
+			mcp.Description("Look at the waiting posts without marking them as handed out, leaving the stream as it was"),
+			mcp.DefaultBool(false),
+		),
+	)
+
+	// Add the tool handlers the config enables
+	registerTools(s,
+		server.ServerTool{Tool: searchTool, Handler: handleRedditSearch},
+		server.ServerTool{Tool: postTool, Handler: handleRedditPost},
+		server.ServerTool{Tool: commentsTool, Handler: handleRedditComments},
+		server.ServerTool{Tool: subredditPostsTool, Handler: handleRedditSubredditPosts},
+		server.ServerTool{Tool: nextPageTool, Handler: handleRedditNextPage},
+		server.ServerTool{Tool: capabilitiesTool, Handler: handleRedditCapabilities},
+		server.ServerTool{Tool: helpTool, Handler: handleRedditHelp},
+		server.ServerTool{Tool: batchTool, Handler: handleRedditBatch},
+		server.ServerTool{Tool: watchAddTool, Handler: handleRedditWatchAdd},
+		server.ServerTool{Tool: watchListTool, Handler: handleRedditWatchList},
+		server.ServerTool{Tool: watchRemoveTool, Handler: handleRedditWatchRemove},
+		server.ServerTool{Tool: watchMatchesTool, Handler: handleRedditWatchMatches},
+		server.ServerTool{Tool: jobAddTool, Handler: handleRedditJobAdd},
+		server.ServerTool{Tool: jobListTool, Handler: handleRedditJobList},
+		server.ServerTool{Tool: jobRemoveTool, Handler: handleRedditJobRemove},
+		server.ServerTool{Tool: jobResultsTool, Handler: handleRedditJobResults},
+		server.ServerTool{Tool: archiveSearchTool, Handler: handleRedditArchiveSearch},
+		server.ServerTool{Tool: exportThreadTool, Handler: handleRedditExportThread},
+		server.ServerTool{Tool: exportSubredditTool, Handler: handleRedditExportSubreddit},
+		server.ServerTool{Tool: digestTool, Handler: handleRedditDigest},
+		server.ServerTool{Tool: multiSearchTool, Handler: handleRedditMultiSearch},
+		server.ServerTool{Tool: userAnalysisTool, Handler: handleRedditUserAnalysis},
+		server.ServerTool{Tool: subredditStatsTool, Handler: handleRedditSubredditStats},
+		server.ServerTool{Tool: keywordTrendTool, Handler: handleRedditKeywordTrend},
+		server.ServerTool{Tool: trackPostTool, Handler: handleRedditTrackPost},
+		server.ServerTool{Tool: trackReportTool, Handler: handleRedditTrackReport},
+		server.ServerTool{Tool: trackStopTool, Handler: handleRedditTrackStop},
+		server.ServerTool{Tool: commentStreamTool, Handler: handleRedditCommentStream},
+		server.ServerTool{Tool: postStreamTool, Handler: handleRedditPostStream},
+	)
+
+	// Subreddit feeds and threads as resources
+	registerResources(s)
+
+	// Watch rules from the config file, and their matches as resources
+	startWatches(s)
+
+	// Scheduled jobs from the config file, and their results as resources
+	startJobs(s)
+
+	// Prompts for common workflows built on the tools
+	registerPrompts(s)
+
+	return s
+}
+
+// Every tool's middleware, outermost first: the audit log and progress see the
+// call as the client made it, and output_format reshapes the handler's result
+// before the structured limit and content annotations apply. reddit_batch
+// runs its sub-calls through the same chain.
+func toolMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return auditMiddleware(progressMiddleware(annotationMiddleware(structuredLimitMiddleware(outputFormatMiddleware(next)))))
+}
+
+// Annotations for tools that keep state in this server but never touch Reddit
+func localStateTool(title string, readOnly, destructive bool) mcp.ToolOption {
+	return mcp.WithToolAnnotation(mcp.ToolAnnotation{
+		Title:           title,
+		ReadOnlyHint:    mcp.ToBoolPtr(readOnly),
+		DestructiveHint: mcp.ToBoolPtr(destructive),
+		IdempotentHint:  mcp.ToBoolPtr(readOnly),
+		OpenWorldHint:   mcp.ToBoolPtr(false),
+	})
+}
+
+// Tools, by base name, that read from Reddit and write only to this server
+var localWriteTools = make(map[string]bool)
+
+// Annotations for tools that read from Reddit and change state in this server
+// alone, such as a stream's checkpoint or an export file; repeating a call doesn't repeat its result.
+// Capabilities doesn't count them as writing to Reddit.
+func localWriteTool(title string) mcp.ToolOption {
+	return func(tool *mcp.Tool) {
+		localWriteTools[tool.Name] = true
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           title,
+			ReadOnlyHint:    mcp.ToBoolPtr(false),
+			DestructiveHint: mcp.ToBoolPtr(false),
+			IdempotentHint:  mcp.ToBoolPtr(false),
+			OpenWorldHint:   mcp.ToBoolPtr(true),
+		})(tool)
 	}
 }
 
-// Helper function to make Reddit API requests
-func makeRedditRequest(endpoint string, params url.Values) (interface{}, error) {
-	// Build the full URL
-	baseURL := "https://www.reddit.com"
+// Annotations for tools that only read from Reddit. They're idempotent in
+// the sense that calling them changes nothing, although results track Reddit.
+func readOnlyTool(title string) mcp.ToolOption {
+	return mcp.WithToolAnnotation(mcp.ToolAnnotation{
+		Title:           title,
+		ReadOnlyHint:    mcp.ToBoolPtr(true),
+		DestructiveHint: mcp.ToBoolPtr(false),
+		IdempotentHint:  mcp.ToBoolPtr(true),
+		OpenWorldHint:   mcp.ToBoolPtr(true),
+	})
+}
+
+// Helper function to make Reddit API requests, decoding the response into out
+func makeRedditRequest(ctx context.Context, endpoint string, params url.Values, out interface{}) error {
+	// Don't start new work for a call the client has already cancelled
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("request cancelled: %w", err)
+	}
+
+	// Build the full URL, going through OAuth when an account is configured
+	// or the session brought its own token
+	baseURL := redditBaseURL
+	account := currentConfig().Reddit
+	sessionToken := sessionRedditToken(ctx)
+	if account.configured() || sessionToken != "" {
+		baseURL = redditOAuthURL
+	}
 	requestURL := baseURL + endpoint
 
 	if len(params) > 0 {
@@ -97,323 +923,987 @@ func makeRedditRequest(endpoint string, params url.Values) (interface{}, error)
 	}
 
 	// Create the HTTP request
-	req, err := http.NewRequest("GET", requestURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Set user-agent header to avoid rate limiting
-	req.Header.Set("User-Agent", "mcp-reddit-tool/1.0")
+	req.Header.Set("User-Agent", userAgent())
+
+	switch {
+	case sessionToken != "":
+		req.Header.Set("Authorization", "bearer "+sessionToken)
+	case account.configured():
+		token, err := redditTokens.Token(req.Context(), account)
+		if err != nil {
+			return fmt.Errorf("%w: reddit authentication failed: %v", errAuthRequired, err)
+		}
+		req.Header.Set("Authorization", "bearer "+token)
+	}
+
+	// Record the request for the audit log; the query string identifies what was fetched
+	recordEndpoint(ctx, strings.TrimPrefix(requestURL, baseURL))
+
+	// Serve repeated requests from the cache; the account is part of the key
+	// because authenticated and anonymous responses can differ
+	ttl := time.Duration(currentConfig().CacheTTL) * time.Second
+	cacheKey := account.Username + " " + requestURL
+	if sessionToken != "" {
+		cacheKey = tokenFingerprint(sessionToken) + " " + requestURL
+	}
+	if ttl > 0 && !isCacheRefresh(ctx) {
+		if body, ok := redditCache.get(cacheKey); ok {
+			slog.DebugContext(ctx, "Reddit request served from cache", "endpoint", endpoint)
+			return decodeResponse(bytes.NewReader(body), out, 0)
+		}
+	}
+
+	// Wait for a free slot so parallel work can't flood Reddit with connections
+	release, err := acquireUpstream(ctx)
+	if err != nil {
+		return fmt.Errorf("request cancelled: %w", err)
+	}
+	defer release()
 
 	// Make the request
-	client := &http.Client{}
+	client := &http.Client{CheckRedirect: checkRedditRedirect}
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		if errors.Is(err, errNotFound) || ctx.Err() != nil {
+			return err
+		}
+		return fmt.Errorf("%w: request failed: %v", errUpstreamDown, err)
 	}
 	defer resp.Body.Close()
+	slog.DebugContext(ctx, "Reddit request", "endpoint", endpoint, "status", resp.StatusCode)
 
 	// Check status code
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned error status: %d", resp.StatusCode)
+		return statusError(resp)
 	}
 
-	// Read the response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+	// Decode the response as it streams in, keeping a copy of the body when
+	// caching unless it's too large to cache
+	maxBytes := currentConfig().MaxResponseBytes
+	respBody := newLimitedReader(resp.Body, maxBytes)
+	if ttl <= 0 {
+		if err := decodeResponse(respBody, out, maxBytes); err != nil {
+			return err
+		}
+		archiveResponse(out)
+		return nil
 	}
-
-	// Try to parse as array first (for comments endpoint)
-	var arrayResult []interface{}
-	if err := json.Unmarshal(body, &arrayResult); err == nil {
-		return arrayResult, nil
+	body := &bodyCopy{}
+	if err := decodeResponse(io.TeeReader(respBody, body), out, maxBytes); err != nil {
+		return err
 	}
-
-	// If not an array, try as object
-	var mapResult map[string]interface{}
-	if err := json.Unmarshal(body, &mapResult); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	if !body.overflow {
+		redditCache.put(cacheKey, body.buf.Bytes(), ttl)
 	}
+	archiveResponse(out)
 
-	return mapResult, nil
+	return nil
+}
+
+// Decode a Reddit response body into out, streaming listings child by child
+func decodeResponse(r io.Reader, out interface{}, maxBytes int64) error {
+	var err error
+	dec := json.NewDecoder(r)
+	if sd, ok := out.(streamDecoder); ok {
+		err = sd.decodeFrom(dec)
+	} else {
+		err = dec.Decode(out)
+	}
+	if errors.Is(err, errResponseTooLarge) {
+		return fmt.Errorf("response from Reddit exceeded %d bytes; request fewer items: %w", maxBytes, err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+	return nil
 }
 
 // Handle Reddit search requests
 func handleRedditSearch(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// A cursor continues an earlier search with its original arguments
+	args, cursor, err := listingArguments("search", request)
+	if err != nil {
+		return toolError(err), nil
+	}
+
+	opts, err := newFormatOptions(args)
+	if err != nil {
+		return toolError(err), nil
+	}
+
+	dates, err := newDateRange(args, opts.loc)
+	if err != nil {
+		return toolError(err), nil
+	}
+
 	// Extract parameters
-	query, ok := request.Params.Arguments["query"].(string)
+	query, ok := args["query"].(string)
 	if !ok || query == "" {
-		return mcp.NewToolResultError("search query is required"), nil
+		return toolError(invalidInput("search query is required")), nil
+	}
+	if backend, _ := args["backend"].(string); backend == "pullpush" {
+		return handlePullpushSearch(ctx, args, opts, dates)
 	}
 
 	// Extract optional parameters
 	params := url.Values{}
+	if author, _ := args["author"].(string); author != "" {
+		query += " author:" + strings.TrimPrefix(author, "u/")
+	}
 	params.Set("q", query)
 
 	// Default limit
 	limit := 10.0
-	if limitParam, ok := request.Params.Arguments["limit"].(float64); ok {
+	if limitParam, ok := args["limit"].(float64); ok {
 		limit = limitParam
 	}
 	params.Set("limit", fmt.Sprintf("%d", int(limit)))
 
 	// Default sort
 	sort := "relevance"
-	if sortParam, ok := request.Params.Arguments["sort"].(string); ok && sortParam != "" {
+	if sortParam, ok := args["sort"].(string); ok && sortParam != "" {
 		sort = sortParam
 	}
 	params.Set("sort", sort)
+	applyCursor(params, cursor)
 
 	// Build endpoint path
 	endpoint := "/search.json"
-	if subreddit, ok := request.Params.Arguments["subreddit"].(string); ok && subreddit != "" {
+	if subreddit, ok := args["subreddit"].(string); ok && subreddit != "" {
 		endpoint = fmt.Sprintf("/r/%s/search.json", subreddit)
 	}
 
 	// Make the API call
-	result, err := makeRedditRequest(endpoint, params)
+	var result listing
+	fromFeed, err := fetchListingOrFeed(ctx, endpoint, params, &result)
 	if err != nil {
-		return mcp.NewToolResultErrorFromErr("Reddit API error", err), nil
+		return toolError(err), nil
+	}
+	leftOut := ""
+	if dates.set() {
+		if fromFeed != "" {
+			// A feed has no further pages to look through for the range
+			dropPosts(&result, func(post *item) bool { return !dates.contains(post) })
+		} else {
+			leftOut = filterDateRange(ctx, endpoint, params, &result, dates, int(limit))
+		}
 	}
+	leftOut += filterPosts(&result, opts)
+	collapseDuplicates(&result)
 
 	// Format the response
-	formattedResult, err := formatSearchResults(result)
+	formattedResult, err := formatSearchResults(&result, opts)
 	if err != nil {
 		return mcp.NewToolResultErrorFromErr("Failed to format results", err), nil
 	}
 
-	return mcp.NewToolResultText(formattedResult), nil
+	formattedResult = fromFeed + formatListingStats(&result, opts) + formattedResult
+
+	next := nextCursor("search", args, cursor, &result)
+	lastListings.remember(ctx, next)
+	output := newListingOutput(&result, nil, next)
+	return newStructuredResult(output, formattedResult+leftOut+formatNextCursor(next)), nil
 }
 
 // Handle Reddit post details requests
 func handleRedditPost(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	// Extract post ID
-	postID, ok := request.Params.Arguments["post_id"].(string)
+	postID, ok := request.GetArguments()["post_id"].(string)
 	if !ok || postID == "" {
-		return mcp.NewToolResultError("post_id is required"), nil
+		return toolError(invalidInput("post_id is required")), nil
+	}
+	opts, err := newFormatOptions(request.GetArguments())
+	if err != nil {
+		return toolError(err), nil
 	}
-
-	// Clean the post ID if it includes the "t3_" prefix
-	postID = strings.TrimPrefix(postID, "t3_")
 
 	// Make the API call
-	result, err := makeRedditRequest("/api/info.json", url.Values{"id": []string{"t3_" + postID}})
-	if err != nil {
-		return mcp.NewToolResultErrorFromErr("Reddit API error", err), nil
+	var result listing
+	endpoint, params := postDetailsRequest(postID)
+	if err := makeRedditRequest(ctx, endpoint, params, &result); err != nil {
+		return toolError(err), nil
+	}
+
+	// Very long bodies are linked as a resource instead of inlined
+	var bodyLink mcp.Content
+	redacted := false
+	if len(result.Children) > 0 {
+		redacted = redactNSFW(&result.Children[0].Data, opts)
+		redactSpoilers(&result.Children[0].Data, opts)
+		bodyLink = linkLongBody(&result.Children[0].Data, opts.maxBody)
+		if parent := result.Children[0].Data.crosspostParent(); parent != nil && bodyLink == nil {
+			bodyLink = linkLongBody(parent, opts.maxBody)
+		}
+	}
+	recovered := ""
+	if want, _ := request.GetArguments()["recover_removed"].(bool); want && len(result.Children) > 0 && !redacted {
+		recovered = recoverRemoved(ctx, []*item{&result.Children[0].Data}, nil)
 	}
 
 	// Format the response
-	formattedResult, err := formatPostDetails(result)
+	formattedResult, err := formatPostDetails(&result, opts)
+	if errors.Is(err, errNotFound) {
+		return toolError(err), nil
+	}
 	if err != nil {
 		return mcp.NewToolResultErrorFromErr("Failed to format post details", err), nil
 	}
 
-	return mcp.NewToolResultText(formattedResult), nil
+	// Let multimodal clients see image and gallery posts
+	post := &result.Children[0].Data
+	output := newPostOutput(post, true)
+	if want, _ := request.GetArguments()["link_preview"].(bool); want && !redacted && post.URL != "" &&
+		!strings.Contains(post.URL, "reddit.com") && !isRedditMediaURL(post.URL) {
+		if preview := fetchLinkPreview(ctx, post.URL); preview != nil {
+			output.LinkPreview = preview
+			formattedResult += formatLinkPreview(preview)
+		}
+	}
+	toolResult := newStructuredResult(output, formattedResult+recovered)
+	if bodyLink != nil {
+		output.BodyURI = postResourceURI(post.ID)
+		toolResult.Content = append(toolResult.Content, bodyLink)
+	}
+	if !redacted {
+		toolResult.Content = append(toolResult.Content, postImages(ctx, post)...)
+	}
+
+	return toolResult, nil
 }
 
 // Handle Reddit comments requests
 func handleRedditComments(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	// Extract post ID
-	postID, ok := request.Params.Arguments["post_id"].(string)
+	postID, ok := request.GetArguments()["post_id"].(string)
 	if !ok || postID == "" {
-		return mcp.NewToolResultError("post_id is required"), nil
+		return toolError(invalidInput("post_id is required")), nil
+	}
+	opts, err := newFormatOptions(request.GetArguments())
+	if err != nil {
+		return toolError(err), nil
 	}
-
-	// Clean the post ID if it includes the "t3_" prefix
-	postID = strings.TrimPrefix(postID, "t3_")
-
-	// Extract optional parameters
-	params := url.Values{}
 
 	// Default limit
 	limit := 25.0
-	if limitParam, ok := request.Params.Arguments["limit"].(float64); ok {
+	if limitParam, ok := request.GetArguments()["limit"].(float64); ok {
 		limit = limitParam
 	}
-	params.Set("limit", fmt.Sprintf("%d", int(limit)))
 
 	// Default sort
 	sort := "top"
-	if sortParam, ok := request.Params.Arguments["sort"].(string); ok && sortParam != "" {
+	if sortParam, ok := request.GetArguments()["sort"].(string); ok && sortParam != "" {
 		sort = sortParam
 	}
-	params.Set("sort", sort)
 
-	// Make the API call
-	result, err := makeRedditRequest(fmt.Sprintf("/comments/%s.json", postID), params)
-	if err != nil {
-		return mcp.NewToolResultErrorFromErr("Reddit API error", err), nil
+	// Make the API call, starting from one comment's replies when asked to
+	var result commentsResponse
+	endpoint, params := commentsRequest(postID, sort, int(limit), opts.depth)
+	if commentID, _ := request.GetArguments()["comment_id"].(string); commentID != "" {
+		params.Set("comment", strings.TrimPrefix(commentID, "t1_"))
+	}
+	if err := makeRedditRequest(ctx, endpoint, params, &result); err != nil {
+		return toolError(err), nil
+	}
+	redactCommentSpoilers(&result.Comments, opts)
+	leftOut := filterComments(&result.Comments, opts)
+	if want, _ := request.GetArguments()["recover_removed"].(bool); want {
+		leftOut += recoverRemoved(ctx, nil, commentItems(&result.Comments))
+	}
+	if budget, ok := request.GetArguments()["max_output_chars"].(float64); ok {
+		leftOut += fitCommentBudget(&result.Comments, int(budget), opts.depth)
+	}
+
+	// The subreddit's settings say how long hidden scores stay hidden
+	if anyScoreHidden(&result.Comments) && len(result.Post.Children) > 0 {
+		if info, err := lookupSubreddit(ctx, result.Post.Children[0].Data.Subreddit); err == nil {
+			opts.scoreHideMins = info.About.ScoreHideMins
+		}
 	}
 
 	// Format the response
-	formattedResult, err := formatComments(result)
+	formattedResult, err := formatComments(&result, opts)
 	if err != nil {
 		return mcp.NewToolResultErrorFromErr("Failed to format comments", err), nil
 	}
+	formattedResult += leftOut
+	// A summary stands in for the comments in the structured output too
+	out := newCommentsOutput(&result, opts)
+	summary, summarized := summarizeOversized(ctx, "the comment thread of post "+postID, formattedResult)
+	if summarized {
+		formattedResult = summary
+		out.Comments, out.Summary = []commentOutput{}, summary
+	}
+	toolResult := newStructuredResult(out, formattedResult)
+
+	// Point at the whole thread when the text had to be cut short
+	if max := currentConfig().MaxOutputChars; summarized || (max > 0 && len(formattedResult) > max) {
+		toolResult.Content = append(toolResult.Content, mcp.NewResourceLink(
+			commentsResourceURI(postID, sort, int(limit)), "Comments on post "+strings.TrimPrefix(postID, "t3_"),
+			"The full comment thread, without the output limit", "text/plain"))
+	}
 
-	return mcp.NewToolResultText(formattedResult), nil
+	return toolResult, nil
 }
 
-// Format search results into readable text
-func formatSearchResults(data interface{}) (string, error) {
-	// Cast to map for search results
-	dataMap, ok := data.(map[string]interface{})
-	if !ok {
-		return "", errors.New("unexpected response format")
+// Handle subreddit listing requests
+func handleRedditSubredditPosts(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// A cursor continues an earlier listing with its original arguments
+	args, cursor, err := listingArguments("subreddit_posts", request)
+	if err != nil {
+		return toolError(err), nil
 	}
 
-	// Navigate to the posts in the data structure
-	dataObject, ok := dataMap["data"].(map[string]interface{})
-	if !ok {
-		return "", errors.New("unexpected response format")
+	subreddit, ok := args["subreddit"].(string)
+	if !ok || subreddit == "" {
+		return toolError(invalidInput("subreddit is required")), nil
+	}
+	opts, err := newFormatOptions(args)
+	if err != nil {
+		return toolError(err), nil
 	}
 
-	children, ok := dataObject["children"].([]interface{})
-	if !ok {
-		return "", errors.New("no results found")
+	sort := "hot"
+	if sortParam, ok := args["sort"].(string); ok && sortParam != "" {
+		sort = sortParam
 	}
 
-	if len(children) == 0 {
-		return "No results found for this query.", nil
+	limit := float64(defaultListingLimit)
+	if limitParam, ok := args["limit"].(float64); ok {
+		limit = limitParam
 	}
 
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("Found %d results:\n\n", len(children)))
+	// Make the API call
+	var result listing
+	endpoint, params := subredditListingRequest(subreddit, sort, int(limit))
+	applyCursor(params, cursor)
+	fromFeed, err := fetchListingOrFeed(ctx, endpoint, params, &result)
+	if err != nil {
+		return toolError(err), nil
+	}
+	reportProgress(ctx, 1, 2, "Fetched posts")
+	leftOut := filterPosts(&result, opts)
 
-	for i, child := range children {
-		childData, ok := child.(map[string]interface{})["data"].(map[string]interface{})
-		if !ok {
-			continue
-		}
+	// Listings share the search result format
+	formattedResult, err := formatSearchResults(&result, opts)
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("Failed to format results", err), nil
+	}
+	formattedResult = fromFeed + formattedResult
 
-		title := childData["title"].(string)
-		author := childData["author"].(string)
-		score := int(childData["score"].(float64))
-		id := childData["id"].(string)
+	// Describe the subreddit when its metadata is available; the listing is still
+	// useful without it, including when the call is cancelled at this point
+	info, err := lookupSubreddit(ctx, subreddit)
+	if err == nil {
+		formattedResult = formatSubredditHeader(info) + formattedResult
+	}
+	reportProgress(ctx, 2, 2, "Fetched subreddit metadata")
 
-		sb.WriteString(fmt.Sprintf("%d. Title: %s\n", i+1, title))
-		sb.WriteString(fmt.Sprintf("   Author: u/%s\n", author))
-		sb.WriteString(fmt.Sprintf("   Score: %d\n", score))
-		sb.WriteString(fmt.Sprintf("   Post ID: %s\n\n", id))
+	next := nextCursor("subreddit_posts", args, cursor, &result)
+	lastListings.remember(ctx, next)
+	output := newListingOutput(&result, info, next)
+	return newStructuredResult(output, formattedResult+leftOut+formatNextCursor(next)), nil
+}
+
+// Endpoint and parameters for a post's details
+func postDetailsRequest(postID string) (string, url.Values) {
+	// Clean the post ID if it includes the "t3_" prefix
+	postID = strings.TrimPrefix(postID, "t3_")
+	return "/api/info.json", url.Values{"id": []string{"t3_" + postID}}
+}
+
+// Endpoint and parameters for a post's comment tree, depth levels deep
+func commentsRequest(postID, sort string, limit, depth int) (string, url.Values) {
+	postID = strings.TrimPrefix(postID, "t3_")
+	params := url.Values{}
+	params.Set("limit", fmt.Sprintf("%d", limit))
+	params.Set("sort", sort)
+	params.Set("depth", fmt.Sprintf("%d", depth))
+	return fmt.Sprintf("/comments/%s.json", postID), params
+}
+
+// Endpoint and parameters for a subreddit listing; pre-warming must build the
+// exact same request for its cache entries to be hit
+func subredditListingRequest(subreddit, sort string, limit int) (string, url.Values) {
+	subreddit = strings.TrimPrefix(subreddit, "r/")
+	params := url.Values{}
+	params.Set("limit", fmt.Sprintf("%d", limit))
+	return fmt.Sprintf("/r/%s/%s.json", subreddit, sort), params
+}
+
+// Format search results into readable text
+func formatSearchResults(result *listing, opts formatOptions) (string, error) {
+	if len(result.Children) == 0 {
+		return "No results found for this query.\n", nil
+	}
+
+	// Roughly 320 bytes per result covers the fixed labels, a typical title and the link
+	var sb strings.Builder
+	sb.Grow(32 + 320*len(result.Children))
+	fmt.Fprintf(&sb, "Found %d results:\n\n", len(result.Children))
+
+	for i := range result.Children {
+		post := &result.Children[i].Data
+
+		fmt.Fprintf(&sb, "%d. %sTitle: %s%s\n", i+1, opts.cite('r', post), post.Title, postMarkers(post))
+		if post.Subreddit != "" {
+			fmt.Fprintf(&sb, "   Subreddit: r/%s\n", post.Subreddit)
+		}
+		if len(post.AlsoIn) > 0 {
+			fmt.Fprintf(&sb, "   Also posted in: r/%s\n", strings.Join(post.AlsoIn, ", r/"))
+		}
+		if post.LinkFlairText != "" {
+			fmt.Fprintf(&sb, "   Flair: %s\n", post.LinkFlairText)
+		}
+		if parent := post.crosspostParent(); parent != nil {
+			fmt.Fprintf(&sb, "   Crosspost of: r/%s post %s\n", parent.Subreddit, parent.ID)
+		}
+		fmt.Fprintf(&sb, "   Author: %s\n", userLabel(post.Author))
+		fmt.Fprintf(&sb, "   Score: %d | Comments: %d", post.Score, post.NumComments)
+		if post.TotalAwardsReceived > 0 {
+			fmt.Fprintf(&sb, " | Awards: %d", post.TotalAwardsReceived)
+		}
+		sb.WriteString("\n")
+		if post.CreatedUTC > 0 {
+			fmt.Fprintf(&sb, "   Posted: %s\n", formatUnixTime(int64(post.CreatedUTC), opts.loc))
+		}
+		if post.Permalink != "" {
+			fmt.Fprintf(&sb, "   Link: %s\n", permalinkURL(post.Permalink))
+		}
+		if opts.preview {
+			if preview := postPreview(post, opts); preview != "" {
+				fmt.Fprintf(&sb, "   Preview: %s\n", preview)
+			}
+		}
+		fmt.Fprintf(&sb, "   Post ID: %s\n\n", post.ID)
 	}
 
+	opts.writeLegend(&sb)
 	return sb.String(), nil
 }
 
-// Format post details into readable text
-func formatPostDetails(data interface{}) (string, error) {
-	// Cast to map for post details
-	dataMap, ok := data.(map[string]interface{})
-	if !ok {
-		return "", errors.New("unexpected response format")
+// Why a thread can't take replies or shows odd scores, e.g. "locked (no new
+// comments)", or "" for a normal thread
+func threadStatus(post *item) string {
+	var states []string
+	if post.Locked {
+		states = append(states, "locked (no new comments)")
+	}
+	if post.Archived {
+		states = append(states, "archived (no new comments or votes)")
 	}
+	if post.ContestMode {
+		states = append(states, "contest mode (scores hidden, comments in random order)")
+	}
+	return strings.Join(states, ", ")
+}
 
-	// Navigate to the post data
-	dataObject, ok := dataMap["data"].(map[string]interface{})
-	if !ok {
-		return "", errors.New("unexpected response format")
+// Note a comment thread's reception and status, and whether it discusses a
+// spoiler, ahead of its comments
+func writeThreadStatus(sb *strings.Builder, result *commentsResponse) {
+	if len(result.Post.Children) == 0 {
+		return
 	}
+	post := &result.Post.Children[0].Data
+	if post.UpvoteRatio > 0 {
+		// How divided opinion is: on the post, and among the comments shown
+		fmt.Fprintf(sb, "Post: %d points, %.0f%% upvoted", post.Score, post.UpvoteRatio*100)
+		if total, controversial := countControversial(&result.Comments); controversial > 0 {
+			fmt.Fprintf(sb, " | %d of %d comments controversial", controversial, total)
+		}
+		sb.WriteString("\n\n")
+	}
+	if status := threadStatus(&result.Post.Children[0].Data); status != "" {
+		fmt.Fprintf(sb, "Thread status: %s\n\n", status)
+	}
+	if result.Post.Children[0].Data.Spoiler {
+		sb.WriteString("The post is tagged as a spoiler; comments may give it away.\n\n")
+	}
+}
+
+// Characters of a post body shown by include_preview
+const previewChars = 200
 
-	children, ok := dataObject["children"].([]interface{})
-	if !ok || len(children) == 0 {
-		return "", errors.New("post not found")
+// One-line preview of a listed post: the start of its body, or the site it links to
+func postPreview(post *item, opts formatOptions) string {
+	if post.Selftext == "" {
+		if u, err := url.Parse(post.URL); err == nil && u.Host != "" && !strings.Contains(post.URL, "reddit.com") {
+			return "links to " + strings.TrimPrefix(u.Hostname(), "www.")
+		}
+		return ""
+	}
+	if post.Removal != "" {
+		return shownText(post, post.Selftext)
 	}
+	if post.Spoiler && !opts.revealSpoilers {
+		return hiddenSpoiler
+	}
+	text := post.Selftext
+	if !opts.revealSpoilers {
+		text = inlineSpoiler.ReplaceAllString(text, hiddenSpoiler)
+	}
+	text = strings.Join(strings.Fields(opts.body(text)), " ")
+	if short := truncateAt(text, previewChars); len(short) < len(text) {
+		return short + "..."
+	}
+	return text
+}
 
-	postData, ok := children[0].(map[string]interface{})["data"].(map[string]interface{})
-	if !ok {
-		return "", errors.New("unexpected post data format")
+// Labels shown after a post's title, such as " [NSFW] [Spoiler]"; [Closed]
+// marks threads that no longer take comments
+func postMarkers(post *item) string {
+	markers := ""
+	if post.Over18 {
+		markers += " [NSFW]"
+	}
+	if post.Spoiler {
+		markers += " [Spoiler]"
+	}
+	if post.Locked || post.Archived {
+		markers += " [Closed]"
+	}
+	if strings.HasPrefix(post.Removal, "deleted") {
+		markers += " [Deleted]"
+	} else if post.Removal != "" {
+		markers += " [Removed]"
 	}
+	return markers
+}
 
-	var sb strings.Builder
+// Full URL of a Reddit permalink path such as /r/golang/comments/abc123/title/
+func permalinkURL(permalink string) string {
+	return redditBaseURL + permalink
+}
 
-	title := postData["title"].(string)
-	author := postData["author"].(string)
-	score := int(postData["score"].(float64))
-	upvoteRatio := postData["upvote_ratio"].(float64)
-	numComments := int(postData["num_comments"].(float64))
-	created := int64(postData["created_utc"].(float64))
+// Format post details into readable text
+func formatPostDetails(result *listing, opts formatOptions) (string, error) {
+	if len(result.Children) == 0 {
+		return "", fmt.Errorf("%w: post not found", errNotFound)
+	}
+	post := &result.Children[0].Data
 
-	sb.WriteString(fmt.Sprintf("Title: %s\n\n", title))
-	sb.WriteString(fmt.Sprintf("Author: u/%s\n", author))
-	sb.WriteString(fmt.Sprintf("Score: %d (%.0f%% upvoted)\n", score, upvoteRatio*100))
-	sb.WriteString(fmt.Sprintf("Comments: %d\n", numComments))
-	sb.WriteString(fmt.Sprintf("Created: %s\n\n", formatUnixTime(created)))
+	var sb strings.Builder
+	sb.Grow(256 + len(post.Title) + len(post.Selftext) + len(post.URL))
+
+	fmt.Fprintf(&sb, "%sTitle: %s%s\n\n", opts.cite('r', post), post.Title, postMarkers(post))
+	fmt.Fprintf(&sb, "Author: %s\n", userLabel(post.Author))
+	fmt.Fprintf(&sb, "Score: %d (%.0f%% upvoted)\n", post.Score, post.UpvoteRatio*100)
+	fmt.Fprintf(&sb, "Comments: %d\n", post.NumComments)
+	if awards := formatAwards(post); awards != "" {
+		fmt.Fprintf(&sb, "Awards: %s\n", awards)
+	}
+	fmt.Fprintf(&sb, "Created: %s\n", formatUnixTime(int64(post.CreatedUTC), opts.loc))
+	if edited := formatEdited(post); edited != "" {
+		fmt.Fprintf(&sb, "Edited: %s (%s)\n", time.Unix(int64(post.Edited), 0).In(opts.loc).Format(time.RFC3339), edited)
+	}
+	if status := threadStatus(post); status != "" {
+		fmt.Fprintf(&sb, "Status: %s\n", status)
+	}
+	sb.WriteString("\n")
 
 	// Post content
-	if selftext, ok := postData["selftext"].(string); ok && selftext != "" {
-		sb.WriteString(fmt.Sprintf("Content:\n%s\n\n", selftext))
+	if post.Selftext != "" {
+		fmt.Fprintf(&sb, "Content:\n%s\n\n", opts.body(shownText(post, post.Selftext)))
 	}
 
 	// URL if it's a link post
-	if url, ok := postData["url"].(string); ok && url != "" {
-		if !strings.Contains(url, "reddit.com") {
-			sb.WriteString(fmt.Sprintf("URL: %s\n\n", url))
+	if post.URL != "" && !strings.Contains(post.URL, "reddit.com") {
+		fmt.Fprintf(&sb, "URL: %s\n\n", post.URL)
+	}
+	sb.WriteString(formatMedia(post))
+
+	// Crossposts are empty shells; show the post they share
+	if parent := post.crosspostParent(); parent != nil {
+		fmt.Fprintf(&sb, "Crosspost of: %s%s%s\n", opts.cite('r', parent), parent.Title, postMarkers(parent))
+		fmt.Fprintf(&sb, "  Subreddit: r/%s | Author: %s | Score: %d | Post ID: %s\n", parent.Subreddit, userLabel(parent.Author), parent.Score, parent.ID)
+		if parent.Permalink != "" {
+			fmt.Fprintf(&sb, "  Link: %s\n", permalinkURL(parent.Permalink))
+		}
+		sb.WriteString("\n")
+		if parent.Selftext != "" {
+			fmt.Fprintf(&sb, "Original content:\n%s\n\n", opts.body(shownText(parent, parent.Selftext)))
+		}
+		if parent.URL != "" && !strings.Contains(parent.URL, "reddit.com") {
+			fmt.Fprintf(&sb, "Original URL: %s\n\n", parent.URL)
 		}
+		sb.WriteString(formatMedia(parent))
 	}
 
+	opts.writeLegend(&sb)
 	return sb.String(), nil
 }
 
-// Format comments into readable text
-func formatComments(data interface{}) (string, error) {
-	// Expect an array for comments
-	resultList, ok := data.([]interface{})
-	if !ok || len(resultList) < 2 {
-		return "", errors.New("unexpected response format")
+// Format comments into readable text: numbered top-level comments with their
+// replies indented beneath them, down to opts.depth levels
+func formatComments(result *commentsResponse, opts formatOptions) (string, error) {
+	if opts.view == "flat" {
+		return formatFlatComments(result, opts), nil
 	}
+	children := result.Comments.Children
 
-	// Get the comments data
-	commentsData, ok := resultList[1].(map[string]interface{})
-	if !ok {
-		return "", errors.New("comments data not found")
+	// Size the builder from the comment bodies so it grows at most once
+	size := 32 + commentTreeSize(children)
+
+	var sb strings.Builder
+	sb.Grow(size)
+	writeThreadStatus(&sb, result)
+	fmt.Fprintf(&sb, "Found %d comments:\n\n", len(children))
+
+	// Process top-level comments
+	for i := range children {
+		// Skip "more" type entries
+		if children[i].Kind == "more" {
+			continue
+		}
+		comment := &children[i].Data
+
+		fmt.Fprintf(&sb, "%d. %s:\n", i+1, commentSummary(comment, opts))
+		writeIndented(&sb, opts.body(shownText(comment, comment.Body)), "   ")
+		sb.WriteString("\n")
+		writeCommentRef(&sb, comment, "   ")
+		writeReplies(&sb, comment.Replies, 2, "   ", opts)
 	}
 
-	commentsObj, ok := commentsData["data"].(map[string]interface{})
-	if !ok {
-		return "", errors.New("comments object not found")
+	writeCommenterSummary(&sb, result, opts)
+	opts.writeLegend(&sb)
+	return sb.String(), nil
+}
+
+// Write the replies at depth, each level indented two more spaces. Where the
+// depth limit or Reddit cuts a chain short, say how to continue from there.
+func writeReplies(sb *strings.Builder, replies *listing, depth int, indent string, opts formatOptions) {
+	if replies == nil || len(replies.Children) == 0 {
+		return
+	}
+	if depth > opts.depth {
+		if parent := continueFrom(replies.Children[0].Data.ParentID); parent != "" {
+			fmt.Fprintf(sb, "%s[replies continue: call %s with comment_id %q]\n\n", indent, toolName(currentConfig(), "comments"), parent)
+		}
+		return
+	}
+	for i := range replies.Children {
+		reply := &replies.Children[i].Data
+		if replies.Children[i].Kind == "more" {
+			if parent := continueFrom(reply.ParentID); parent != "" && (reply.Count > 0 || len(reply.Children) > 0) {
+				fmt.Fprintf(sb, "%s[%d more replies: call %s with comment_id %q]\n\n", indent, max(reply.Count, len(reply.Children)), toolName(currentConfig(), "comments"), parent)
+			} else if reply.Count > 0 {
+				fmt.Fprintf(sb, "%s[%d more replies]\n\n", indent, reply.Count)
+			}
+			continue
+		}
+
+		fmt.Fprintf(sb, "%s- %s:\n", indent, commentSummary(reply, opts))
+		writeIndented(sb, opts.body(shownText(reply, reply.Body)), indent+"  ")
+		sb.WriteString("\n")
+		writeCommentRef(sb, reply, indent+"  ")
+		writeReplies(sb, reply.Replies, depth+1, indent+"  ", opts)
 	}
+}
 
-	children, ok := commentsObj["children"].([]interface{})
+// The comment ID to continue a thread from, given a reply's parent fullname;
+// "" when the parent is the post itself
+func continueFrom(parentFullname string) string {
+	id, ok := strings.CutPrefix(parentFullname, "t1_")
 	if !ok {
-		return "", errors.New("no comments found")
+		return ""
+	}
+	return id
+}
+
+// A comment in a flattened thread, with who it replied to
+type flatComment struct {
+	comment *item
+	replyTo string // author of the parent comment; "" for top-level comments
+}
+
+// Format every comment down to opts.depth as one list, highest score first
+func formatFlatComments(result *commentsResponse, opts formatOptions) string {
+	var comments []flatComment
+	flattenComments(&comments, &result.Comments, "", 1, opts.depth)
+	if opts.author != "" {
+		comments = slices.DeleteFunc(comments, func(c flatComment) bool {
+			return !strings.EqualFold(c.comment.Author, opts.author)
+		})
 	}
+	slices.SortStableFunc(comments, func(a, b flatComment) int {
+		return b.comment.Score - a.comment.Score
+	})
 
 	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("Found %d comments:\n\n", len(children)))
+	sb.Grow(32 + commentTreeSize(result.Comments.Children))
+	writeThreadStatus(&sb, result)
+	if opts.author != "" {
+		fmt.Fprintf(&sb, "Found %d comments by u/%s, highest score first:\n\n", len(comments), opts.author)
+	} else {
+		fmt.Fprintf(&sb, "Found %d comments, highest score first:\n\n", len(comments))
+	}
+	for i, c := range comments {
+		fmt.Fprintf(&sb, "%d. %s", i+1, commentSummary(c.comment, opts))
+		if c.replyTo != "" {
+			fmt.Fprintf(&sb, " | reply to %s", userLabel(c.replyTo))
+		}
+		sb.WriteString(":\n")
+		writeIndented(&sb, opts.body(shownText(c.comment, c.comment.Body)), "   ")
+		sb.WriteString("\n")
+		writeCommentRef(&sb, c.comment, "   ")
+	}
+	if opts.author == "" {
+		writeCommenterSummary(&sb, result, opts)
+	}
+	opts.writeLegend(&sb)
+	return sb.String()
+}
 
-	// Process top-level comments
-	for i, child := range children {
-		childMap, ok := child.(map[string]interface{})
-		if !ok {
+// Write who took part in the comments shown: unique commenters, the most
+// active ones and how often the post's author replied
+func writeCommenterSummary(sb *strings.Builder, result *commentsResponse, opts formatOptions) {
+	var comments []flatComment
+	flattenComments(&comments, &result.Comments, "", 1, opts.depth)
+	counts := map[string]int{}
+	op := 0
+	for _, c := range comments {
+		if c.comment.Author == "[deleted]" {
 			continue
 		}
+		counts[c.comment.Author]++
+		if c.comment.IsSubmitter {
+			op++
+		}
+	}
+	if len(counts) == 0 {
+		return
+	}
+
+	authors := slices.Collect(maps.Keys(counts))
+	slices.SortFunc(authors, func(a, b string) int {
+		if d := counts[b] - counts[a]; d != 0 {
+			return d
+		}
+		return strings.Compare(a, b)
+	})
+	var active []string
+	for _, author := range authors[:min(len(authors), 3)] {
+		if counts[author] > 1 {
+			active = append(active, fmt.Sprintf("u/%s (%d)", author, counts[author]))
+		}
+	}
 
-		// Skip "more" type entries
-		kind, ok := childMap["kind"].(string)
-		if !ok || kind == "more" {
+	fmt.Fprintf(sb, "Commenters: %d unique", len(counts))
+	if len(active) > 0 {
+		fmt.Fprintf(sb, " | Most active: %s", strings.Join(active, ", "))
+	}
+	fmt.Fprintf(sb, " | OP comments: %d\n", op)
+}
+
+// Append the comments of replies and their own replies, depth first
+func flattenComments(out *[]flatComment, replies *listing, replyTo string, depth, maxDepth int) {
+	if replies == nil || depth > maxDepth {
+		return
+	}
+	for i := range replies.Children {
+		if replies.Children[i].Kind == "more" {
 			continue
 		}
+		comment := &replies.Children[i].Data
+		*out = append(*out, flatComment{comment: comment, replyTo: replyTo})
+		flattenComments(out, comment.Replies, comment.Author, depth+1, maxDepth)
+	}
+}
 
-		childData, ok := childMap["data"].(map[string]interface{})
-		if !ok {
+// Author with their standing, score, time, edits and awards of a comment, as shown before its body
+func commentSummary(comment *item, opts formatOptions) string {
+	summary := fmt.Sprintf("%s%s%s (%s) | %s", opts.cite('c', comment), userLabel(comment.Author), authorMarkers(comment), commentScore(comment, opts), formatUnixTime(int64(comment.CreatedUTC), opts.loc))
+	if comment.Controversiality > 0 {
+		summary += " | controversial"
+	}
+	if edited := formatEdited(comment); edited != "" {
+		summary += " | edited " + edited
+	}
+	if awards := formatAwards(comment); awards != "" {
+		summary += " | Awards: " + awards
+	}
+	return summary
+}
+
+// A comment's score, e.g. "12 points"; fresh comments in some subreddits have
+// their score hidden, and Reddit reports 1 for them
+func commentScore(comment *item, opts formatOptions) string {
+	if !comment.ScoreHidden {
+		return fmt.Sprintf("%d points", comment.Score)
+	}
+	if opts.scoreHideMins > 0 {
+		shown := time.Unix(int64(comment.CreatedUTC), 0).Add(time.Duration(opts.scoreHideMins) * time.Minute)
+		if left := timeSpan(time.Until(shown)); left != "" {
+			return "score hidden for another " + left
+		}
+	}
+	return "score hidden"
+}
+
+// Comments in a tree, and how many of them Reddit flags as controversial
+func countControversial(replies *listing) (total, controversial int) {
+	if replies == nil {
+		return 0, 0
+	}
+	for i := range replies.Children {
+		if replies.Children[i].Kind == "more" {
 			continue
 		}
+		comment := &replies.Children[i].Data
+		total++
+		if comment.Controversiality > 0 {
+			controversial++
+		}
+		t, c := countControversial(comment.Replies)
+		total, controversial = total+t, controversial+c
+	}
+	return total, controversial
+}
 
-		author := childData["author"].(string)
-		body := childData["body"].(string)
-		score := int(childData["score"].(float64))
+// Whether any comment in a tree has its score hidden
+func anyScoreHidden(replies *listing) bool {
+	if replies == nil {
+		return false
+	}
+	for i := range replies.Children {
+		if replies.Children[i].Data.ScoreHidden || anyScoreHidden(replies.Children[i].Data.Replies) {
+			return true
+		}
+	}
+	return false
+}
 
-		sb.WriteString(fmt.Sprintf("%d. u/%s (%d points):\n", i+1, author, score))
-		sb.WriteString(fmt.Sprintf("   %s\n\n", strings.ReplaceAll(body, "\n", "\n   ")))
+// Labels shown after a comment author's name, such as " [OP] [Mod] [Flair: Vet tech]"
+func authorMarkers(comment *item) string {
+	markers := ""
+	if comment.IsSubmitter {
+		markers += " [OP]"
+	}
+	switch comment.Distinguished {
+	case "moderator":
+		markers += " [Mod]"
+	case "admin":
+		markers += " [Admin]"
 	}
+	if comment.AuthorFlairText != "" {
+		markers += " [Flair: " + comment.AuthorFlairText + "]"
+	}
+	return markers
+}
 
-	return sb.String(), nil
+// Total awards and the most given ones, e.g. "5 (Gold x3, Helpful x2)", or "" for none
+func formatAwards(it *item) string {
+	if it.TotalAwardsReceived <= 0 {
+		return ""
+	}
+	awards := slices.Clone(it.AllAwardings)
+	slices.SortStableFunc(awards, func(a, b award) int {
+		return b.Count - a.Count
+	})
+	var names []string
+	for _, a := range awards[:min(len(awards), 3)] {
+		if a.Count > 1 {
+			names = append(names, fmt.Sprintf("%s x%d", a.Name, a.Count))
+		} else {
+			names = append(names, a.Name)
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Sprintf("%d", it.TotalAwardsReceived)
+	}
+	return fmt.Sprintf("%d (%s)", it.TotalAwardsReceived, strings.Join(names, ", "))
+}
+
+// Write the line identifying a comment for follow-up calls, then a blank line
+func writeCommentRef(sb *strings.Builder, comment *item, indent string) {
+	fmt.Fprintf(sb, "%sComment ID: %s", indent, comment.ID)
+	if comment.Permalink != "" {
+		fmt.Fprintf(sb, " | Link: %s", permalinkURL(comment.Permalink))
+	}
+	sb.WriteString("\n\n")
+}
+
+// Rough formatted size of a comment tree
+func commentTreeSize(children []thing) int {
+	size := 0
+	for i := range children {
+		size += 160 + len(children[i].Data.Author) + len(children[i].Data.Body) + len(children[i].Data.Permalink)
+		if replies := children[i].Data.Replies; replies != nil {
+			size += commentTreeSize(replies.Children)
+		}
+	}
+	return size
+}
+
+// Write text with every line prefixed by indent, without building an intermediate string
+func writeIndented(sb *strings.Builder, text, indent string) {
+	for {
+		sb.WriteString(indent)
+		line, rest, found := strings.Cut(text, "\n")
+		sb.WriteString(line)
+		if !found {
+			return
+		}
+		sb.WriteByte('\n')
+		text = rest
+	}
+}
+
+// How long before now t was, e.g. "3 hours ago"
+func relativeTime(t, now time.Time) string {
+	if span := timeSpan(now.Sub(t)); span != "" {
+		return span + " ago"
+	}
+	return "just now"
 }
 
-// Helper function to format Unix timestamp
-func formatUnixTime(timestamp int64) string {
-	// In a real implementation, use time.Unix() to format the time
-	// For simplicity, we'll just return the timestamp as a string
-	return fmt.Sprintf("timestamp: %d", timestamp)
+// A duration in its largest whole unit, e.g. "3 hours", or "" under a minute
+func timeSpan(d time.Duration) string {
+	for _, unit := range []struct {
+		size time.Duration
+		name string
+	}{
+		{365 * 24 * time.Hour, "year"},
+		{30 * 24 * time.Hour, "month"},
+		{7 * 24 * time.Hour, "week"},
+		{24 * time.Hour, "day"},
+		{time.Hour, "hour"},
+		{time.Minute, "minute"},
+	} {
+		if n := int(d / unit.size); n >= 1 {
+			if n == 1 {
+				return "1 " + unit.name
+			}
+			return fmt.Sprintf("%d %ss", n, unit.name)
+		}
+	}
+	return ""
+}
+
+// When an edit came relative to the original, e.g. "2 hours after posting",
+// or "" for content that was never edited
+func formatEdited(it *item) string {
+	if it.Edited <= 0 {
+		return ""
+	}
+	span := timeSpan(time.Duration(float64(it.Edited)-it.CreatedUTC) * time.Second)
+	if span == "" {
+		return "within a minute of posting"
+	}
+	return span + " after posting"
+}
+
+// Format a Unix timestamp in loc in absolute (RFC 3339) and relative form,
+// e.g. "2024-05-01T14:03:00Z (3 hours ago)"
+func formatUnixTime(timestamp int64, loc *time.Location) string {
+	if timestamp <= 0 {
+		return "unknown"
+	}
+	t := time.Unix(timestamp, 0).In(loc)
+	return fmt.Sprintf("%s (%s)", t.Format(time.RFC3339), relativeTime(t, time.Now()))
 }
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Logger name reported in notifications/message
+const mcpLoggerName = "reddit_mcp_server"
+
+// Sessions that receive log records as MCP notifications, each at the level it
+// set with logging/setLevel (mcp-go defaults a session to errors only)
+type clientLogSessions struct {
+	mu       sync.RWMutex
+	server   *server.MCPServer
+	sessions map[string]struct{}
+}
+
+var clientLogs = &clientLogSessions{sessions: make(map[string]struct{})}
+
+// Route log records to the sessions of s, tracking them through hooks
+func (c *clientLogSessions) attach(s *server.MCPServer, hooks *server.Hooks) {
+	c.mu.Lock()
+	c.server = s
+	c.mu.Unlock()
+
+	hooks.AddOnRegisterSession(func(ctx context.Context, session server.ClientSession) {
+		c.mu.Lock()
+		c.sessions[session.SessionID()] = struct{}{}
+		c.mu.Unlock()
+	})
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		c.mu.Lock()
+		delete(c.sessions, session.SessionID())
+		c.mu.Unlock()
+	})
+}
+
+// Send a record to the session handling ctx, or to every session for records
+// logged outside a request. Delivery errors are dropped rather than logged,
+// which would loop back here.
+func (c *clientLogSessions) send(ctx context.Context, level mcp.LoggingLevel, data map[string]any) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.server == nil {
+		return
+	}
+
+	notification := mcp.NewLoggingMessageNotification(level, mcpLoggerName, data)
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		c.server.SendLogMessageToClient(ctx, notification)
+		return
+	}
+	for sessionID := range c.sessions {
+		c.server.SendLogMessageToSpecificClient(sessionID, notification)
+	}
+}
+
+func (c *clientLogSessions) active() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.sessions) > 0
+}
+
+// slog handler writing to next (stderr) at the configured level and also
+// forwarding every record to connected MCP clients, which filter by their own level
+type clientLogHandler struct {
+	next   slog.Handler
+	attrs  []slog.Attr
+	prefix string // group prefix for attribute keys
+}
+
+func (h *clientLogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level) || clientLogs.active()
+}
+
+func (h *clientLogHandler) Handle(ctx context.Context, r slog.Record) error {
+	var err error
+	if h.next.Enabled(ctx, r.Level) {
+		err = h.next.Handle(ctx, r)
+	}
+
+	data := map[string]any{"message": r.Message}
+	for _, attr := range h.attrs {
+		data[attr.Key] = attr.Value.Resolve().Any()
+	}
+	r.Attrs(func(attr slog.Attr) bool {
+		value := attr.Value.Resolve().Any()
+		if e, ok := value.(error); ok {
+			value = e.Error()
+		}
+		data[h.prefix+attr.Key] = value
+		return true
+	})
+	clientLogs.send(ctx, mcpLogLevel(r.Level), data)
+
+	return err
+}
+
+func (h *clientLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	prefixed := make([]slog.Attr, 0, len(h.attrs)+len(attrs))
+	prefixed = append(prefixed, h.attrs...)
+	for _, attr := range attrs {
+		prefixed = append(prefixed, slog.Attr{Key: h.prefix + attr.Key, Value: attr.Value})
+	}
+	return &clientLogHandler{next: h.next.WithAttrs(attrs), attrs: prefixed, prefix: h.prefix}
+}
+
+func (h *clientLogHandler) WithGroup(name string) slog.Handler {
+	return &clientLogHandler{next: h.next.WithGroup(name), attrs: h.attrs, prefix: h.prefix + name + "."}
+}
+
+// MCP (syslog) level for an slog level
+func mcpLogLevel(level slog.Level) mcp.LoggingLevel {
+	switch {
+	case level >= slog.LevelError:
+		return mcp.LoggingLevelError
+	case level >= slog.LevelWarn:
+		return mcp.LoggingLevelWarning
+	case level >= slog.LevelInfo:
+		return mcp.LoggingLevelInfo
+	default:
+		return mcp.LoggingLevelDebug
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Images are fetched only from Reddit's media hosts, since their URLs come
+// from post data; larger images are skipped rather than cut off
+const maxImageBytes = 4 << 20
+
+var redditMediaHosts = []string{"i.redd.it", "preview.redd.it", "external-preview.redd.it", ".redditmedia.com"}
+
+// Image URLs to show for an image or gallery post, in display order
+func postImageURLs(post *item, max int) []string {
+	var urls []string
+	if post.IsGallery && post.GalleryData != nil {
+		for _, entry := range post.GalleryData.Items {
+			media, ok := post.MediaMetadata[entry.MediaID]
+			if !ok || media.Status != "valid" || media.Kind != "Image" || media.Source.URL == "" {
+				continue
+			}
+			urls = append(urls, html.UnescapeString(media.Source.URL))
+		}
+	} else if post.PostHint == "image" && post.Preview != nil && len(post.Preview.Images) > 0 {
+		urls = append(urls, html.UnescapeString(post.Preview.Images[0].Source.URL))
+	}
+
+	if len(urls) > max {
+		urls = urls[:max]
+	}
+	return urls
+}
+
+// Whether rawURL points at one of Reddit's media hosts over HTTPS
+func isRedditMediaURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range redditMediaHosts {
+		if host == allowed || (strings.HasPrefix(allowed, ".") && strings.HasSuffix(host, allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Download the post's images as MCP image content; failures only drop the image
+func postImages(ctx context.Context, post *item) []mcp.Content {
+	var images []mcp.Content
+	for _, imageURL := range postImageURLs(post, currentConfig().MaxImages) {
+		image, err := fetchImage(ctx, imageURL)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to fetch post image", "url", imageURL, "error", err)
+			continue
+		}
+		images = append(images, image)
+	}
+	return images
+}
+
+// Fetch an image from Reddit's media hosts
+func fetchImage(ctx context.Context, imageURL string) (mcp.ImageContent, error) {
+	if !isRedditMediaURL(imageURL) {
+		return mcp.ImageContent{}, fmt.Errorf("not a Reddit media URL")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return mcp.ImageContent{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent())
+
+	release, err := acquireUpstream(ctx)
+	if err != nil {
+		return mcp.ImageContent{}, fmt.Errorf("request cancelled: %w", err)
+	}
+	defer release()
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return mcp.ImageContent{}, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return mcp.ImageContent{}, fmt.Errorf("media host returned status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(newLimitedReader(resp.Body, maxImageBytes))
+	if err != nil {
+		return mcp.ImageContent{}, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	// Trust the bytes over the header, which CDNs sometimes get wrong
+	mimeType := http.DetectContentType(data)
+	if !strings.HasPrefix(mimeType, "image/") {
+		return mcp.ImageContent{}, fmt.Errorf("unexpected content type %s", mimeType)
+	}
+
+	return mcp.NewImageContent(base64.StdEncoding.EncodeToString(data), mimeType), nil
+}
+
+// Describe a post's attached media, one line per item under a "Media:"
+// heading, or "" for posts without any
+func formatMedia(post *item) string {
+	var lines []string
+	if media := post.Media; media != nil {
+		if video := media.RedditVideo; video != nil && video.FallbackURL != "" {
+			var details []string
+			if video.Duration > 0 {
+				details = append(details, fmt.Sprintf("%d:%02d", video.Duration/60, video.Duration%60))
+			}
+			if video.Height > 0 {
+				details = append(details, fmt.Sprintf("%dp", video.Height))
+			}
+			line := "Video: " + video.FallbackURL
+			if len(details) > 0 {
+				line += " (" + strings.Join(details, ", ") + ")"
+			}
+			lines = append(lines, line)
+		}
+		if embed := media.OEmbed; embed != nil && embed.ProviderName != "" {
+			line := "Embed: " + embed.ProviderName
+			if embed.Title != "" {
+				line += fmt.Sprintf(" %q", html.UnescapeString(embed.Title))
+			}
+			lines = append(lines, line)
+		}
+	}
+	if post.IsGallery && post.GalleryData != nil {
+		lines = append(lines, fmt.Sprintf("Gallery: %d items", len(post.GalleryData.Items)))
+	} else if post.Preview != nil {
+		for _, image := range post.Preview.Images {
+			if image.Source.URL == "" {
+				continue
+			}
+			line := "Preview image: " + html.UnescapeString(image.Source.URL)
+			if image.Source.Width > 0 && image.Source.Height > 0 {
+				line += fmt.Sprintf(" (%dx%d)", image.Source.Width, image.Source.Height)
+			}
+			lines = append(lines, line)
+		}
+	}
+	// Reddit uses placeholders such as "self" and "nsfw" when there's no thumbnail
+	if strings.HasPrefix(post.Thumbnail, "https://") {
+		lines = append(lines, "Thumbnail: "+post.Thumbnail)
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+	return "Media:\n  " + strings.Join(lines, "\n  ") + "\n\n"
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	maxMultiSearchSubreddits = 10
+	maxMultiSearchResults    = 50
+	// Rank damping of reciprocal rank fusion: the first results of each
+	// subreddit lead, without one subreddit's top result swamping the rest
+	rankFusionK = 60
+)
+
+// One subreddit's part of a multi-subreddit search
+type subredditHits struct {
+	subreddit string
+	result    listing
+	err       error
+}
+
+// Search several subreddits at once and merge their results into one ranking
+func handleRedditMultiSearch(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	opts, err := newFormatOptions(args)
+	if err != nil {
+		return toolError(err), nil
+	}
+	dates, err := newDateRange(args, opts.loc)
+	if err != nil {
+		return toolError(err), nil
+	}
+	query, _ := args["query"].(string)
+	if query == "" {
+		return toolError(invalidInput("search query is required")), nil
+	}
+	if opts.author != "" {
+		query += " author:" + opts.author
+	}
+	var subreddits []string
+	if list, ok := args["subreddits"].([]any); ok {
+		for _, v := range list {
+			if name, _ := v.(string); subredditKey(name) != "" && !slices.Contains(subreddits, subredditKey(name)) {
+				subreddits = append(subreddits, subredditKey(name))
+			}
+		}
+	}
+	if len(subreddits) == 0 {
+		return toolError(invalidInput("subreddits needs at least one subreddit")), nil
+	}
+	if len(subreddits) > maxMultiSearchSubreddits {
+		return toolError(invalidInput("at most %d subreddits can be searched at once", maxMultiSearchSubreddits)), nil
+	}
+	sort, _ := args["sort"].(string)
+	if sort == "" {
+		sort = "relevance"
+	}
+	limit := 10
+	if n, ok := args["limit"].(float64); ok {
+		limit = min(max(int(n), 1), maxMultiSearchResults)
+	}
+
+	// Every subreddit is asked for the whole limit, since any of them may
+	// hold all the best results; the upstream pool bounds how many run at once
+	hits := make([]subredditHits, len(subreddits))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	finished := 0
+	for i, subreddit := range subreddits {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			params := url.Values{}
+			params.Set("q", query)
+			params.Set("restrict_sr", "1")
+			params.Set("sort", sort)
+			params.Set("limit", fmt.Sprint(min(limit, 100)))
+			hits[i].subreddit = subreddit
+			hits[i].err = makeRedditRequest(ctx, fmt.Sprintf("/r/%s/search.json", subreddit), params, &hits[i].result)
+
+			mu.Lock()
+			finished++
+			done := finished
+			mu.Unlock()
+			reportProgress(ctx, float64(done), float64(len(subreddits)), "Searched r/"+subreddit)
+		}()
+	}
+	wg.Wait()
+
+	// Subreddits a cancellation cut off are noted together rather than as failures
+	var failures strings.Builder
+	failed, cancelled := 0, 0
+	for _, h := range hits {
+		switch {
+		case h.err == nil:
+		case ctx.Err() != nil && (errors.Is(h.err, context.Canceled) || errors.Is(h.err, context.DeadlineExceeded)):
+			failed++
+			cancelled++
+		default:
+			failed++
+			fmt.Fprintf(&failures, "r/%s couldn't be searched: %v\n", h.subreddit, h.err)
+		}
+	}
+	if failed == len(hits) {
+		return toolError(hits[0].err), nil
+	}
+	if cancelled > 0 {
+		failures.WriteString(cancelledNote(fmt.Sprintf("results cover %d of the %d subreddits", len(hits)-failed, len(hits))))
+	}
+
+	result := mergeSearchHits(hits, sort)
+	leftOut := ""
+	if dates.set() {
+		n := dropPosts(&result, func(post *item) bool { return !dates.contains(post) })
+		if n > 0 {
+			leftOut += fmt.Sprintf("Date range %s left out %d results.\n", dates, n)
+		}
+	}
+	leftOut += filterPosts(&result, opts)
+	collapseDuplicates(&result)
+	result.Children = result.Children[:min(limit, len(result.Children))]
+
+	formattedResult, err := formatSearchResults(&result, opts)
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("Failed to format results", err), nil
+	}
+	formattedResult = formatListingStats(&result, opts) + formattedResult
+	output := newListingOutput(&result, nil, nil)
+	return newStructuredResult(output, formattedResult+leftOut+failures.String()), nil
+}
+
+// Merge per-subreddit results into one listing. Relevance and hot ranks
+// aren't comparable across subreddits, so they're combined by reciprocal rank
+// fusion (score breaking ties); new and top order by what they sort on.
+func mergeSearchHits(hits []subredditHits, sort string) listing {
+	type ranked struct {
+		child  thing
+		fusion float64
+	}
+	var all []ranked
+	for _, h := range hits {
+		for rank, child := range h.result.Children {
+			all = append(all, ranked{child, 1 / float64(rankFusionK+rank+1)})
+		}
+	}
+	slices.SortStableFunc(all, func(a, b ranked) int {
+		x, y := &a.child.Data, &b.child.Data
+		switch sort {
+		case "new":
+			return cmp.Compare(y.CreatedUTC, x.CreatedUTC)
+		case "top":
+			return cmp.Compare(y.Score, x.Score)
+		}
+		return cmp.Or(cmp.Compare(b.fusion, a.fusion), cmp.Compare(y.Score, x.Score))
+	})
+	var result listing
+	for _, r := range all {
+		result.Children = append(result.Children, r.child)
+	}
+	return result
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// The most recent listing of each session, so reddit_next_page can continue
+// it; a nil cursor means that listing has no more pages
+type sessionListings struct {
+	mu       sync.Mutex
+	sessions map[string]*listingCursor
+}
+
+var lastListings = &sessionListings{sessions: make(map[string]*listingCursor)}
+
+// Forget the listings of sessions when they disconnect
+func (l *sessionListings) addHooks(hooks *server.Hooks) {
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		l.mu.Lock()
+		delete(l.sessions, session.SessionID())
+		l.mu.Unlock()
+	})
+}
+
+// Session key for ctx; calls outside a session share one slot
+func listingSessionKey(ctx context.Context) string {
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		return session.SessionID()
+	}
+	return ""
+}
+
+type listingRecordKey struct{}
+
+// Where a reddit_batch sub-call's listing is kept instead of the session, so
+// the batch can remember the last one in request order once all are done
+type listingRecord struct {
+	next *listingCursor
+	set  bool
+}
+
+// Remember the cursor following the listing just returned in ctx's session
+func (l *sessionListings) remember(ctx context.Context, next *listingCursor) {
+	if record, ok := ctx.Value(listingRecordKey{}).(*listingRecord); ok {
+		record.next, record.set = next, true
+		return
+	}
+	l.mu.Lock()
+	l.sessions[listingSessionKey(ctx)] = next
+	l.mu.Unlock()
+}
+
+func (l *sessionListings) last(ctx context.Context) (next *listingCursor, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	next, ok = l.sessions[listingSessionKey(ctx)]
+	return next, ok
+}
+
+// Tools (by base name) whose listings reddit_next_page can continue
+var pagedTools = map[string]server.ToolHandlerFunc{
+	"search":          handleRedditSearch,
+	"subreddit_posts": handleRedditSubredditPosts,
+}
+
+// Continue the session's last listing by calling its tool with the saved cursor
+func handleRedditNextPage(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	cfg := currentConfig()
+	next, ok := lastListings.last(ctx)
+	if !ok {
+		return toolError(invalidInput("there is no listing to continue; call %s or %s first",
+			toolName(cfg, "search"), toolName(cfg, "subreddit_posts"))), nil
+	}
+	if next == nil {
+		return mcp.NewToolResultText("The last listing has no more results."), nil
+	}
+	handler, ok := pagedTools[next.Tool]
+	if !ok {
+		return toolError(invalidInput("the last listing came from %s, which can't be continued", toolName(cfg, next.Tool))), nil
+	}
+
+	continued := mcp.CallToolRequest{}
+	continued.Params.Name = toolName(cfg, next.Tool)
+	continued.Params.Arguments = map[string]any{"cursor": next.encode()}
+	return handler(ctx, continued)
+}
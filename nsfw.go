@@ -0,0 +1,37 @@
+package main
+
+import "github.com/mark3labs/mcp-go/mcp"
+
+// The shared include_nsfw argument of tools that return posts
+func includeNSFWArgument() mcp.ToolOption {
+	return mcp.WithBoolean("include_nsfw",
+		mcp.Description("Include posts Reddit marks NSFW (over 18); otherwise they're left out of results and their bodies hidden. Defaults to the server's setting, normally false"),
+	)
+}
+
+// Drop NSFW posts from result unless opts allow them, returning how many were dropped
+func filterNSFW(result *listing, opts formatOptions) int {
+	if opts.includeNSFW {
+		return 0
+	}
+	return dropPosts(result, func(post *item) bool {
+		return post.Over18
+	})
+}
+
+// Hide an NSFW post's body, link and media unless opts allow them, reporting whether it was hidden
+func redactNSFW(post *item, opts formatOptions) bool {
+	if opts.includeNSFW {
+		return false
+	}
+	for i := range post.CrosspostParentList {
+		redactNSFW(&post.CrosspostParentList[i], opts)
+	}
+	if !post.Over18 {
+		return false
+	}
+	post.Selftext = "[NSFW body hidden; call again with include_nsfw true to show it]"
+	post.URL = ""
+	post.Preview, post.GalleryData, post.Media, post.Thumbnail = nil, nil, nil, ""
+	return true
+}
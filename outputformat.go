@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// The shared output_format argument of every tool
+func outputFormatArgument() mcp.ToolOption {
+	return mcp.WithString("output_format",
+		mcp.Description("text (plain prose), markdown (linked titles, quoted bodies), json (the structured output as raw JSON), jsonl (one JSON object per post or comment), or csv or tsv (a table with a header row, for post listings). Results without a structured form, such as help, and formats that don't fit a result keep the plain text"),
+		mcp.Enum("text", "markdown", "json", "jsonl", "csv", "tsv"),
+		mcp.DefaultString("text"),
+	)
+}
+
+// Tool middleware re-rendering a successful result's text from its structured
+// content when the call asks for markdown, json or a table. Handlers always produce
+// the plain text; only the first text block is replaced, so images and
+// resource links stay as they are.
+func outputFormatMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		format, _ := request.GetArguments()["output_format"].(string)
+		switch format {
+		case "", "text":
+			return next(ctx, request)
+		case "markdown", "json", "jsonl", "csv", "tsv":
+		default:
+			return toolError(invalidInput("output_format must be text, markdown, json, jsonl, csv or tsv")), nil
+		}
+
+		result, err := next(ctx, request)
+		if err != nil || result == nil || result.IsError || result.StructuredContent == nil {
+			return result, err
+		}
+
+		var text string
+		switch format {
+		case "json":
+			b, err := json.MarshalIndent(result.StructuredContent, "", "  ")
+			if err != nil {
+				return toolError(fmt.Errorf("failed to encode output: %w", err)), nil
+			}
+			text = string(b)
+		case "markdown":
+			var ok bool
+			if text, ok = formatMarkdown(result.StructuredContent); !ok {
+				// Tools without a richer rendering keep their text
+				return result, nil
+			}
+		case "jsonl":
+			var ok bool
+			if text, ok = formatJSONLines(result.StructuredContent); !ok {
+				return result, nil
+			}
+		case "csv", "tsv":
+			listing, ok := result.StructuredContent.(*listingOutput)
+			if !ok {
+				// Only post listings are tables
+				return result, nil
+			}
+			sep := ','
+			if format == "tsv" {
+				sep = '\t'
+			}
+			if text, err = formatTable(listing, sep, false); err != nil {
+				return toolError(fmt.Errorf("failed to encode output: %w", err)), nil
+			}
+		}
+		// Every rendering is held to the same output size as the text
+		text = truncateOutput(text, currentConfig().MaxOutputChars)
+
+		for i, content := range result.Content {
+			if _, ok := content.(mcp.TextContent); ok {
+				result.Content[i] = mcp.NewTextContent(text)
+				break
+			}
+		}
+		return result, nil
+	}
+}
+
+// Lines of jsonl output: an item's structured output tagged with its kind
+type taggedPost struct {
+	Kind string `json:"kind"`
+	postOutput
+}
+
+type taggedComment struct {
+	Kind string `json:"kind"`
+	commentOutput
+}
+
+// Posts or comments as JSON Lines, one object per line with a "kind" of post
+// or comment holding the same fields as the structured output; a listing's
+// cursor gets a final line of its own kind
+func formatJSONLines(structured any) (string, bool) {
+	var sb strings.Builder
+	enc := json.NewEncoder(&sb)
+	switch out := structured.(type) {
+	case *listingOutput:
+		for _, post := range out.Posts {
+			enc.Encode(taggedPost{"post", post})
+		}
+		if out.NextCursor != "" {
+			enc.Encode(map[string]string{"kind": "next_cursor", "next_cursor": out.NextCursor})
+		}
+	case *postOutput:
+		enc.Encode(taggedPost{"post", *out})
+	case *commentsOutput:
+		for _, comment := range out.Comments {
+			enc.Encode(taggedComment{"comment", comment})
+		}
+		if out.Summary != "" {
+			enc.Encode(map[string]string{"kind": "summary", "summary": out.Summary})
+		}
+	case *archiveOutput:
+		// Archived items carry their own kind
+		for _, it := range out.Items {
+			enc.Encode(it)
+		}
+	default:
+		return "", false
+	}
+	return sb.String(), true
+}
+
+// Columns of csv and tsv listings
+var tableHeader = []string{"id", "title", "subreddit", "author", "score", "num_comments", "created", "flair", "nsfw", "url", "permalink"}
+
+// A listing as a table with a header row and a row per post, fields separated
+// by sep; withBody adds a last column with each post's body
+func formatTable(out *listingOutput, sep rune, withBody bool) (string, error) {
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+	w.Comma = sep
+	header := tableHeader
+	if withBody {
+		header = append(slices.Clone(tableHeader), "selftext")
+	}
+	w.Write(header)
+	for _, post := range out.Posts {
+		row := []string{
+			post.ID, post.Title, post.Subreddit, post.Author,
+			strconv.Itoa(post.Score), strconv.Itoa(post.NumComments), post.Created,
+			post.Flair, strconv.FormatBool(post.NSFW), post.URL, post.Permalink,
+		}
+		if withBody {
+			row = append(row, post.Selftext)
+		}
+		w.Write(row)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", err
+	}
+	if out.NextCursor != "" {
+		// Outside the table, where scripts can skip it
+		fmt.Fprintf(&sb, "\nnext_cursor: %s\n", out.NextCursor)
+	}
+	return sb.String(), nil
+}
+
+// Markdown rendering of a tool's structured output, if it has one
+func formatMarkdown(structured any) (string, bool) {
+	var sb strings.Builder
+	switch out := structured.(type) {
+	case *listingOutput:
+		markdownListing(&sb, out)
+	case *postOutput:
+		markdownPost(&sb, out)
+	case *commentsOutput:
+		markdownComments(&sb, out)
+	default:
+		return "", false
+	}
+	return sb.String(), true
+}
+
+func markdownListing(sb *strings.Builder, out *listingOutput) {
+	if sub := out.Subreddit; sub != nil {
+		fmt.Fprintf(sb, "## r/%s", sub.Name)
+		if sub.Title != "" {
+			fmt.Fprintf(sb, ": %s", sub.Title)
+		}
+		fmt.Fprintf(sb, "\n\n%d subscribers\n\n", sub.Subscribers)
+		if sub.Description != "" {
+			markdownQuote(sb, sub.Description)
+		}
+	}
+	if len(out.Posts) == 0 {
+		sb.WriteString("No results found.\n")
+	}
+	for i, post := range out.Posts {
+		fmt.Fprintf(sb, "%d. **%s**", i+1, markdownLink(post.Title, post.URL))
+		if post.NSFW {
+			sb.WriteString(" `NSFW`")
+		}
+		if post.Spoiler {
+			sb.WriteString(" `Spoiler`")
+		}
+		if post.Locked || post.Archived {
+			sb.WriteString(" `Closed`")
+		}
+		if post.Flair != "" {
+			fmt.Fprintf(sb, " _%s_", post.Flair)
+		}
+		sb.WriteString("  \n")
+		if post.Subreddit != "" && len(post.AlsoIn) > 0 {
+			fmt.Fprintf(sb, "   r/%s (also r/%s) · ", post.Subreddit, strings.Join(post.AlsoIn, ", r/"))
+		} else if post.Subreddit != "" {
+			fmt.Fprintf(sb, "   r/%s · ", post.Subreddit)
+		} else {
+			sb.WriteString("   ")
+		}
+		comments := fmt.Sprintf("%d comments", post.NumComments)
+		if post.Permalink != "" {
+			comments = fmt.Sprintf("[%s](%s)", comments, post.Permalink)
+		}
+		fmt.Fprintf(sb, "%s · %d points · %s · post ID `%s`\n", userLabel(post.Author), post.Score, comments, post.ID)
+	}
+	if out.NextCursor != "" {
+		fmt.Fprintf(sb, "\nMore results: pass cursor `%s` for the next page.\n", out.NextCursor)
+	}
+}
+
+func markdownPost(sb *strings.Builder, post *postOutput) {
+	fmt.Fprintf(sb, "# %s\n\n", markdownLink(post.Title, post.URL))
+	fmt.Fprintf(sb, "*%s · %d points", userLabel(post.Author), post.Score)
+	if post.UpvoteRatio > 0 {
+		fmt.Fprintf(sb, " (%.0f%% upvoted)", post.UpvoteRatio*100)
+	}
+	fmt.Fprintf(sb, " · %d comments", post.NumComments)
+	if post.Created != "" {
+		fmt.Fprintf(sb, " · %s", post.Created)
+	}
+	fmt.Fprintf(sb, "* · post ID `%s`\n\n", post.ID)
+	if status := threadStatus(&item{Locked: post.Locked, Archived: post.Archived, ContestMode: post.ContestMode}); status != "" {
+		fmt.Fprintf(sb, "**Thread status:** %s\n\n", status)
+	}
+	if post.Removed != "" {
+		fmt.Fprintf(sb, "*This post was %s.*\n\n", post.Removed)
+		if post.Recovered != "" {
+			sb.WriteString("*Archived copy from pullpush:*\n\n")
+			markdownQuote(sb, post.Recovered)
+		}
+	} else if post.Selftext != "" {
+		markdownQuote(sb, post.Selftext)
+	}
+	if post.BodyURI != "" {
+		fmt.Fprintf(sb, "The body is shortened; read `%s` for all of it.\n", post.BodyURI)
+	}
+	if post.CrosspostOf != "" {
+		fmt.Fprintf(sb, "Crosspost of post `%s` in r/%s:\n\n", post.CrosspostOf, post.CrosspostSubreddit)
+		if post.CrosspostSelftext != "" {
+			markdownQuote(sb, post.CrosspostSelftext)
+		}
+	}
+}
+
+func markdownComments(sb *strings.Builder, out *commentsOutput) {
+	if out.Summary != "" {
+		sb.WriteString("## Summary\n\n" + out.Summary)
+		return
+	}
+	fmt.Fprintf(sb, "## %d comments\n\n", len(out.Comments))
+	for _, comment := range out.Comments {
+		// Threaded replies nest one block quote level deeper than their parent
+		level := comment.Depth
+		if out.View == "flat" {
+			level = 1
+		}
+		outer := strings.Repeat("> ", level-1)
+		author := "**" + userLabel(comment.Author) + "**"
+		if comment.IsOP {
+			author += " `OP`"
+		}
+		if comment.Distinguished != "" {
+			author += " `" + comment.Distinguished + "`"
+		}
+		if comment.Flair != "" {
+			author += " _" + comment.Flair + "_"
+		}
+		score := fmt.Sprintf("%d points", comment.Score)
+		if comment.ScoreHidden {
+			score = "score hidden"
+		}
+		if comment.Controversial {
+			score += " · controversial"
+		}
+		fmt.Fprintf(sb, "%s%s · %s · %s\n%s\n", outer, author, score,
+			markdownLink("comment "+comment.ID, comment.Permalink), strings.TrimSpace(outer))
+		body := comment.Body
+		if comment.Removed != "" {
+			body = "*[" + comment.Removed + "]*"
+			if comment.Recovered != "" {
+				body += " *Archived copy from pullpush:*\n\n" + comment.Recovered
+			}
+		}
+		markdownQuoteLevel(sb, body, level)
+	}
+}
+
+// Title linked to url, or just the title without one
+func markdownLink(title, url string) string {
+	title = strings.NewReplacer("[", `\[`, "]", `\]`).Replace(title)
+	if url == "" {
+		return title
+	}
+	return fmt.Sprintf("[%s](%s)", title, url)
+}
+
+// Quote text as a Markdown block quote followed by a blank line
+func markdownQuote(sb *strings.Builder, text string) {
+	markdownQuoteLevel(sb, text, 1)
+}
+
+// Quote text nested level block quotes deep
+func markdownQuoteLevel(sb *strings.Builder, text string, level int) {
+	prefix := strings.TrimSpace(strings.Repeat("> ", level))
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		if line == "" {
+			sb.WriteString(prefix + "\n")
+		} else {
+			fmt.Fprintf(sb, "%s %s\n", prefix, line)
+		}
+	}
+	sb.WriteString("\n")
+}
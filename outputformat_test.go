@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestFormatTable(t *testing.T) {
+	post := postOutput{ID: "a1", Title: `Say "hi", world`, Subreddit: "golang", Author: "gopher", Score: 7, NumComments: 2, Created: "2024-01-01T00:00:00Z", Permalink: "https://www.reddit.com/r/golang/comments/a1/"}
+	tests := []struct {
+		name     string
+		out      *listingOutput
+		sep      rune
+		withBody bool
+		want     []string // lines expected in the output
+	}{
+		{"csv quotes fields", &listingOutput{Posts: []postOutput{post}}, ',', false,
+			[]string{strings.Join(tableHeader, ","), `a1,"Say ""hi"", world",golang,gopher,7,2,2024-01-01T00:00:00Z,,false,,https://www.reddit.com/r/golang/comments/a1/`}},
+		{"tsv", &listingOutput{Posts: []postOutput{post}}, '\t', false,
+			[]string{strings.Join(tableHeader, "\t"), "a1\t\"Say \"\"hi\"\", world\"\tgolang\tgopher\t7\t2\t2024-01-01T00:00:00Z\t\tfalse\t\thttps://www.reddit.com/r/golang/comments/a1/"}},
+		{"with bodies", &listingOutput{Posts: []postOutput{{ID: "b2", Selftext: "line one\nline two"}}}, ',', true,
+			[]string{strings.Join(tableHeader, ",") + ",selftext", `b2,,,,0,0,,,false,,,"line one`, `line two"`}},
+		{"header only", &listingOutput{}, ',', false, []string{strings.Join(tableHeader, ",")}},
+		{"cursor after the table", &listingOutput{NextCursor: "t3_next"}, ',', false, []string{strings.Join(tableHeader, ","), "", "next_cursor: t3_next"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := formatTable(tt.out, tt.sep, tt.withBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n"); strings.Join(lines, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("formatTable() =\n%s\nwant\n%s", got, strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestFormatJSONLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    any
+		ok    bool
+		kinds []string
+	}{
+		{"listing", &listingOutput{Posts: []postOutput{{ID: "a"}, {ID: "b"}}, NextCursor: "t3_b"}, true, []string{"post", "post", "next_cursor"}},
+		{"post", &postOutput{ID: "a"}, true, []string{"post"}},
+		{"comments", &commentsOutput{Comments: []commentOutput{{ID: "c"}}}, true, []string{"comment"}},
+		{"summary", &commentsOutput{Comments: []commentOutput{}, Summary: "People agree."}, true, []string{"summary"}},
+		{"other output", &capabilitiesOutput{}, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := formatJSONLines(tt.in)
+			if ok != tt.ok {
+				t.Fatalf("formatJSONLines() ok = %v, want %v", ok, tt.ok)
+			}
+			var kinds []string
+			for _, line := range strings.Split(strings.TrimSuffix(got, "\n"), "\n") {
+				if line == "" {
+					continue
+				}
+				var object struct {
+					Kind string `json:"kind"`
+				}
+				if err := json.Unmarshal([]byte(line), &object); err != nil {
+					t.Fatalf("line %q isn't JSON: %v", line, err)
+				}
+				kinds = append(kinds, object.Kind)
+			}
+			if strings.Join(kinds, " ") != strings.Join(tt.kinds, " ") {
+				t.Errorf("kinds = %v, want %v", kinds, tt.kinds)
+			}
+		})
+	}
+}
+
+func TestOutputFormatMiddleware(t *testing.T) {
+	prose := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("Examples for every tool."), nil
+	}
+	listing := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return newStructuredResult(&listingOutput{Posts: []postOutput{{ID: "a1", Title: "Hello"}}}, "1. Hello"), nil
+	}
+	tests := []struct {
+		name    string
+		handler server.ToolHandlerFunc
+		format  string
+		want    string // prefix of the text returned
+		isError bool
+	}{
+		{"text", listing, "text", "1. Hello", false},
+		{"json", listing, "json", "{", false},
+		{"csv", listing, "csv", "id,title", false},
+		{"no structured output, json", prose, "json", "Examples for every tool.", false},
+		{"no structured output, markdown", prose, "markdown", "Examples for every tool.", false},
+		{"no structured output, csv", prose, "csv", "Examples for every tool.", false},
+		{"unknown format", prose, "yaml", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			call := mcp.CallToolRequest{}
+			call.Params.Arguments = map[string]any{"output_format": tt.format}
+			result, err := outputFormatMiddleware(tt.handler)(context.Background(), call)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.IsError != tt.isError {
+				t.Fatalf("IsError = %v, want %v", result.IsError, tt.isError)
+			}
+			if tt.isError {
+				return
+			}
+			text := result.Content[0].(mcp.TextContent).Text
+			if !strings.HasPrefix(text, tt.want) {
+				t.Errorf("text = %q, want it to start with %q", text, tt.want)
+			}
+		})
+	}
+}
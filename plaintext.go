@@ -0,0 +1,65 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Reddit markdown constructs rewritten by stripMarkdown, applied in order
+var markdownRewrites = []struct {
+	pattern *regexp.Regexp
+	replace string
+}{
+	// Spoilers and inline code keep their text
+	{regexp.MustCompile(`>!(.+?)!<`), "$1"},
+	{regexp.MustCompile("`([^`\n]+)`"), "$1"},
+	// Links become "text (url)"; bare autolinks just the url
+	{regexp.MustCompile(`!?\[([^\]\n]*)\]\((https?://[^)\s]+|/[^)\s]*)\)`), "$1 ($2)"},
+	{regexp.MustCompile(`<(https?://[^>\s]+)>`), "$1"},
+	// Emphasis, strikethrough and superscript
+	{regexp.MustCompile(`\*\*\*(.+?)\*\*\*`), "$1"},
+	{regexp.MustCompile(`\*\*(.+?)\*\*`), "$1"},
+	{regexp.MustCompile(`__(.+?)__`), "$1"},
+	{regexp.MustCompile(`(^|[^\w*])\*([^*\s](?:[^*\n]*[^*\s])?)\*`), "$1$2"},
+	{regexp.MustCompile(`(^|[^\w])_([^_\s](?:[^_\n]*[^_\s])?)_([^\w]|$)`), "$1$2$3"},
+	{regexp.MustCompile(`~~(.+?)~~`), "$1"},
+	{regexp.MustCompile(`\^\(([^)\n]*)\)`), "$1"},
+	{regexp.MustCompile(`\^(\S+)`), "$1"},
+	// Headings, block quotes, rules, code fences and table separators
+	{regexp.MustCompile(`(?m)^#{1,6}[ \t]+`), ""},
+	{regexp.MustCompile(`(?m)^(?:>[ \t]?)+`), ""},
+	{regexp.MustCompile(`(?m)^[ \t]*(?:[-*_][ \t]*){3,}$\n?`), ""},
+	{regexp.MustCompile("(?m)^[ \t]*```.*$\n?"), ""},
+	{regexp.MustCompile(`(?m)^[ \t]*\|?(?:[ \t]*:?-+:?[ \t]*\|)+(?:[ \t]*:?-+:?[ \t]*)?$\n?`), ""},
+	// Table rows lose their outer pipes
+	{regexp.MustCompile(`(?m)^[ \t]*\|[ \t]*(.*?)[ \t]*\|[ \t]*$`), "$1"},
+}
+
+// Characters markdown lets authors escape with a backslash
+const markdownEscapable = "\\*_~^>#|[]()!`-"
+
+// Escaped characters are swapped for private-use runes while the rewrites
+// run, so \*literal\* asterisks aren't taken for emphasis
+const escapeBase = '\uE000'
+
+var markdownEscape = regexp.MustCompile(`\\[` + regexp.QuoteMeta(markdownEscapable) + `]`)
+
+// Rewrite Reddit markdown as plain text: links as "text (url)", emphasis,
+// spoiler and code markers dropped, tables as pipe-separated cells. Meant for
+// clients that show tool output verbatim.
+func stripMarkdown(text string) string {
+	// Reddit pads empty paragraphs with zero-width spaces
+	text = strings.ReplaceAll(text, "\u200b", "")
+	text = markdownEscape.ReplaceAllStringFunc(text, func(escaped string) string {
+		return string(escapeBase + rune(strings.IndexByte(markdownEscapable, escaped[1])))
+	})
+	for _, rw := range markdownRewrites {
+		text = rw.pattern.ReplaceAllString(text, rw.replace)
+	}
+	return strings.Map(func(r rune) rune {
+		if i := int(r - escapeBase); i >= 0 && i < len(markdownEscapable) {
+			return rune(markdownEscapable[i])
+		}
+		return r
+	}, text)
+}
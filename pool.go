@@ -0,0 +1,21 @@
+package main
+
+import "context"
+
+// Slots for simultaneous Reddit requests; sized from -max-concurrency at startup
+var upstreamSlots = make(chan struct{}, 4)
+
+// Size the upstream pool; only safe before the server starts handling requests
+func setUpstreamConcurrency(n int) {
+	upstreamSlots = make(chan struct{}, max(n, 1))
+}
+
+// Wait for an upstream slot, giving up if ctx is cancelled first
+func acquireUpstream(ctx context.Context) (release func(), err error) {
+	select {
+	case upstreamSlots <- struct{}{}:
+		return func() { <-upstreamSlots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
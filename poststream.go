@@ -0,0 +1,326 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// Posts handed out per call by default, and at most
+	defaultPostStreamLimit = 25
+	maxPostStreamLimit     = 100
+	// Newest posts read per call looking for unseen ones
+	maxPostStreamScan = 500
+	// How far before the checkpoint to look again: posts held by the spam
+	// filter or awaiting approval show up in /new after newer ones
+	postStreamLookback = time.Hour
+	// How long a handed-out post is remembered; long past the lookback
+	postStreamSeenFor = 7 * 24 * time.Hour
+)
+
+// Stream checkpoints and the posts each stream has handed out, by owner,
+// consumer and subreddit
+const postStreamSchema = `
+CREATE TABLE IF NOT EXISTS post_stream_checkpoints (
+	owner     TEXT NOT NULL,    -- fingerprint of the client's token; '' without tokens
+	consumer  TEXT NOT NULL,
+	subreddit TEXT NOT NULL,
+	since     INTEGER NOT NULL, -- created_utc of the newest post handed out
+	updated   INTEGER NOT NULL, -- Unix seconds of the last call
+	PRIMARY KEY (owner, consumer, subreddit)
+);
+CREATE TABLE IF NOT EXISTS post_stream_seen (
+	owner     TEXT NOT NULL,
+	consumer  TEXT NOT NULL,
+	subreddit TEXT NOT NULL,
+	name      TEXT NOT NULL,    -- t3_ fullname
+	seen_at   INTEGER NOT NULL, -- Unix seconds it was handed out
+	PRIMARY KEY (owner, consumer, subreddit, name)
+);
+`
+
+// Whether the state file predates owners: it has the tables without an owner column
+const postStreamWithoutOwners = `
+SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'post_stream_checkpoints')
+	AND NOT EXISTS (SELECT 1 FROM pragma_table_info('post_stream_checkpoints') WHERE name = 'owner')`
+
+// Move streams from before owners to the empty owner, where tokenless clients find them
+const postStreamAddOwners = `
+BEGIN;
+ALTER TABLE post_stream_checkpoints RENAME TO post_stream_checkpoints_old;
+ALTER TABLE post_stream_seen RENAME TO post_stream_seen_old;
+` + postStreamSchema + `
+INSERT INTO post_stream_checkpoints SELECT '', consumer, subreddit, since, updated FROM post_stream_checkpoints_old;
+INSERT INTO post_stream_seen SELECT '', consumer, subreddit, name, seen_at FROM post_stream_seen_old;
+DROP TABLE post_stream_checkpoints_old;
+DROP TABLE post_stream_seen_old;
+COMMIT;
+`
+
+// Names of stream consumers: one per bot reading a subreddit
+var consumerPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// The stream state database; an in-memory one, lost on restart, unless
+// -stream-state names a file
+var postStreams struct {
+	mu         sync.Mutex
+	db         *sql.DB
+	persistent bool
+	// Held per consumer and subreddit over a call, so two calls can't hand
+	// out the same posts
+	calls map[string]*sync.Mutex
+}
+
+// Open (or create) the stream state database at path
+func openPostStreams(path string) error {
+	db, err := openStreamDB(path)
+	if err != nil {
+		return err
+	}
+	postStreams.mu.Lock()
+	defer postStreams.mu.Unlock()
+	postStreams.db, postStreams.persistent = db, true
+	return nil
+}
+
+func openStreamDB(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stream state: %w", err)
+	}
+	// One connection, which also keeps an in-memory database alive
+	db.SetMaxOpenConns(1)
+	db.SetConnMaxLifetime(0)
+	db.SetConnMaxIdleTime(0)
+	if _, err := db.Exec("PRAGMA busy_timeout = 5000"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to set up stream state %s: %w", path, err)
+	}
+	schema := postStreamSchema
+	var withoutOwners bool
+	if err := db.QueryRow(postStreamWithoutOwners).Scan(&withoutOwners); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to read stream state %s: %w", path, err)
+	}
+	if withoutOwners {
+		schema = postStreamAddOwners
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to set up stream state %s: %w", path, err)
+	}
+	return db, nil
+}
+
+// The stream state database, whether it outlives the process, and the lock
+// of one consumer's stream of a subreddit
+func postStreamState(key string) (*sql.DB, bool, *sync.Mutex, error) {
+	postStreams.mu.Lock()
+	defer postStreams.mu.Unlock()
+	if postStreams.db == nil {
+		db, err := openStreamDB(":memory:")
+		if err != nil {
+			return nil, false, nil, err
+		}
+		postStreams.db = db
+	}
+	if postStreams.calls == nil {
+		postStreams.calls = make(map[string]*sync.Mutex)
+	}
+	lock := postStreams.calls[key]
+	if lock == nil {
+		lock = &sync.Mutex{}
+		postStreams.calls[key] = lock
+	}
+	return postStreams.db, postStreams.persistent, lock, nil
+}
+
+// Output of reddit_post_stream
+type postStreamOutput struct {
+	Subreddit  string       `json:"subreddit"`
+	Consumer   string       `json:"consumer"`
+	Since      string       `json:"since,omitempty" jsonschema:"Creation time of the newest post handed out before this call, in RFC 3339 format; absent on the consumer's first call"`
+	Posts      []postOutput `json:"posts" jsonschema:"Posts not handed out to this consumer before, oldest first"`
+	More       bool         `json:"more" jsonschema:"More unseen posts are waiting; call again for them"`
+	Persistent bool         `json:"persistent" jsonschema:"The checkpoint survives server restarts"`
+}
+
+// Hand out a subreddit's new posts that this consumer hasn't been given yet
+func handleRedditPostStream(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	opts, err := newFormatOptions(args)
+	if err != nil {
+		return toolError(err), nil
+	}
+	subreddit, _ := args["subreddit"].(string)
+	subreddit = subredditKey(subreddit)
+	if subreddit == "" {
+		return toolError(invalidInput("subreddit is required")), nil
+	}
+	consumer, _ := args["consumer"].(string)
+	if consumer == "" {
+		consumer = "default"
+	}
+	if !consumerPattern.MatchString(consumer) {
+		return toolError(invalidInput("consumer must be 1-64 letters, digits, dots, dashes or underscores")), nil
+	}
+	limit := defaultPostStreamLimit
+	if n, ok := args["limit"].(float64); ok {
+		limit = min(max(int(n), 1), maxPostStreamLimit)
+	}
+	peek, _ := args["peek"].(bool)
+	// Consumers are named by clients, so each client token has its own
+	owner := clientToken(ctx)
+
+	db, persistent, lock, err := postStreamState(owner + " " + consumer + " " + subreddit)
+	if err != nil {
+		return toolError(err), nil
+	}
+	lock.Lock()
+	defer lock.Unlock()
+
+	var since int64
+	err = db.QueryRowContext(ctx, `SELECT since FROM post_stream_checkpoints WHERE owner = ? AND consumer = ? AND subreddit = ?`, owner, consumer, subreddit).Scan(&since)
+	first := errors.Is(err, sql.ErrNoRows)
+	if err != nil && !first {
+		return toolError(fmt.Errorf("reading stream checkpoint: %w", err)), nil
+	}
+
+	// Newest first, never from the cache, back past the checkpoint's lookback;
+	// a first call only looks at the latest page
+	floor := since - int64(postStreamLookback.Seconds())
+	scan := maxPostStreamScan
+	if first {
+		scan = limit
+	}
+	endpoint, params := subredditListingRequest(subreddit, "new", min(scan, exportPageSize))
+	passed := func(page *listing) bool {
+		n := len(page.Children)
+		return n > 0 && int64(page.Children[n-1].Data.CreatedUTC) < floor
+	}
+	recent, _, err := pageListing(withCacheRefresh(ctx), endpoint, params, scan, "new posts", passed)
+	if err != nil {
+		return toolError(err), nil
+	}
+
+	seen, err := postStreamSeen(ctx, db, owner, consumer, subreddit)
+	if err != nil {
+		return toolError(err), nil
+	}
+	fresh := &listing{}
+	for _, child := range recent.Children {
+		if !seen[child.Data.Name] && (first || int64(child.Data.CreatedUTC) >= floor) {
+			fresh.Children = append(fresh.Children, child)
+		}
+	}
+	slices.SortFunc(fresh.Children, func(a, b thing) int {
+		return cmp.Or(cmp.Compare(a.Data.CreatedUTC, b.Data.CreatedUTC), strings.Compare(a.Data.Name, b.Data.Name))
+	})
+	more := len(fresh.Children) > limit
+	fresh.Children = fresh.Children[:min(len(fresh.Children), limit)]
+
+	// Everything taken counts as seen, including the posts the filters leave out
+	taken := slices.Clone(fresh.Children)
+	leftOut := filterPosts(fresh, opts)
+	if !peek {
+		if err := advancePostStream(ctx, db, owner, consumer, subreddit, since, taken); err != nil {
+			return toolError(err), nil
+		}
+	}
+
+	out := &postStreamOutput{Subreddit: subreddit, Consumer: consumer, More: more, Persistent: persistent, Posts: []postOutput{}}
+	if !first {
+		_, out.Since = createdTimes(float64(since))
+	}
+	for i := range fresh.Children {
+		out.Posts = append(out.Posts, *newPostOutput(&fresh.Children[i].Data, false))
+	}
+	return newStructuredResult(out, formatPostStream(out, fresh, peek, opts)+leftOut), nil
+}
+
+// Fullnames of the posts a stream has handed out
+func postStreamSeen(ctx context.Context, db *sql.DB, owner, consumer, subreddit string) (map[string]bool, error) {
+	rows, err := db.QueryContext(ctx, `SELECT name FROM post_stream_seen WHERE owner = ? AND consumer = ? AND subreddit = ?`, owner, consumer, subreddit)
+	if err != nil {
+		return nil, fmt.Errorf("reading seen posts: %w", err)
+	}
+	defer rows.Close()
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("reading seen posts: %w", err)
+		}
+		seen[name] = true
+	}
+	return seen, rows.Err()
+}
+
+// Record the posts taken as seen and move the checkpoint past them, in one
+// transaction so a failure hands them out again rather than losing them
+func advancePostStream(ctx context.Context, db *sql.DB, owner, consumer, subreddit string, since int64, taken []thing) error {
+	now := time.Now()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("saving stream checkpoint: %w", err)
+	}
+	defer tx.Rollback()
+	for _, child := range taken {
+		since = max(since, int64(child.Data.CreatedUTC))
+		if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO post_stream_seen (owner, consumer, subreddit, name, seen_at) VALUES (?, ?, ?, ?, ?)`,
+			owner, consumer, subreddit, child.Data.Name, now.Unix()); err != nil {
+			return fmt.Errorf("saving seen posts: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM post_stream_seen WHERE owner = ? AND consumer = ? AND subreddit = ? AND seen_at < ?`,
+		owner, consumer, subreddit, now.Add(-postStreamSeenFor).Unix()); err != nil {
+		return fmt.Errorf("pruning seen posts: %w", err)
+	}
+	// A first call with nothing new still starts the stream from now
+	if len(taken) == 0 && since == 0 {
+		since = now.Unix()
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO post_stream_checkpoints (owner, consumer, subreddit, since, updated) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (owner, consumer, subreddit) DO UPDATE SET since = excluded.since, updated = excluded.updated`,
+		owner, consumer, subreddit, since, now.Unix()); err != nil {
+		return fmt.Errorf("saving stream checkpoint: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("saving stream checkpoint: %w", err)
+	}
+	return nil
+}
+
+func formatPostStream(out *postStreamOutput, posts *listing, peek bool, opts formatOptions) string {
+	var sb strings.Builder
+	switch {
+	case len(posts.Children) == 0 && out.Since == "":
+		fmt.Fprintf(&sb, "No posts in r/%s yet for %s; later calls return posts from now on.\n", out.Subreddit, out.Consumer)
+	case len(posts.Children) == 0:
+		fmt.Fprintf(&sb, "No new posts in r/%s for %s. Call again to check for more.\n", out.Subreddit, out.Consumer)
+	default:
+		fmt.Fprintf(&sb, "New posts in r/%s for %s, oldest first:\n\n", out.Subreddit, out.Consumer)
+		text, _ := formatSearchResults(posts, opts)
+		sb.WriteString(text)
+	}
+	if out.More {
+		sb.WriteString("More new posts are waiting; call again for them.\n")
+	}
+	switch {
+	case peek:
+		sb.WriteString("Peeked: these posts weren't marked as seen and come again on the next call.\n")
+	case !out.Persistent:
+		sb.WriteString("Seen posts are only kept in memory; start the server with --stream-state <file> to keep them across restarts.\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestAdvancePostStream(t *testing.T) {
+	posts := func(created ...float64) []thing {
+		var list []thing
+		for i, c := range created {
+			list = append(list, thing{Kind: "t3", Data: item{Name: "t3_" + string(rune('a'+i)), CreatedUTC: c}})
+		}
+		return list
+	}
+	tests := []struct {
+		name  string
+		since int64
+		taken []thing
+		want  int64 // checkpoint after the call; 0 for about now
+		seen  int
+	}{
+		{"first call with nothing new", 0, nil, 0, 0},
+		{"moves to the newest post", 100, posts(150, 300, 200), 300, 3},
+		{"never moves back", 500, posts(400), 500, 1},
+		{"nothing new keeps the checkpoint", 500, nil, 500, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := openStreamDB(":memory:")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			ctx := context.Background()
+			if err := advancePostStream(ctx, db, "owner", "bot", "golang", tt.since, tt.taken); err != nil {
+				t.Fatal(err)
+			}
+
+			var since int64
+			if err := db.QueryRow(`SELECT since FROM post_stream_checkpoints WHERE owner = 'owner' AND consumer = 'bot' AND subreddit = 'golang'`).Scan(&since); err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == 0 && since < 1_700_000_000 || tt.want != 0 && since != tt.want {
+				t.Errorf("checkpoint = %d, want %d", since, tt.want)
+			}
+			seen, err := postStreamSeen(ctx, db, "owner", "bot", "golang")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(seen) != tt.seen {
+				t.Errorf("seen %d posts, want %d", len(seen), tt.seen)
+			}
+			for _, child := range tt.taken {
+				if !seen[child.Data.Name] {
+					t.Errorf("%s not recorded as seen", child.Data.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestPostStreamOwners(t *testing.T) {
+	db, err := openStreamDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	taken := []thing{{Kind: "t3", Data: item{Name: "t3_a", CreatedUTC: 100}}}
+	if err := advancePostStream(ctx, db, "alice", "bot", "golang", 0, taken); err != nil {
+		t.Fatal(err)
+	}
+
+	seen, err := postStreamSeen(ctx, db, "mallory", "bot", "golang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 0 {
+		t.Errorf("another owner's consumer saw %d posts, want 0", len(seen))
+	}
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM post_stream_checkpoints WHERE owner = 'mallory'`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("another owner has %d checkpoints, want 0", n)
+	}
+}
+
+func TestOpenStreamDBAddsOwners(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "streams.db")
+	old, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE post_stream_checkpoints (consumer TEXT NOT NULL, subreddit TEXT NOT NULL, since INTEGER NOT NULL, updated INTEGER NOT NULL, PRIMARY KEY (consumer, subreddit))`,
+		`CREATE TABLE post_stream_seen (consumer TEXT NOT NULL, subreddit TEXT NOT NULL, name TEXT NOT NULL, seen_at INTEGER NOT NULL, PRIMARY KEY (consumer, subreddit, name))`,
+		`INSERT INTO post_stream_checkpoints VALUES ('bot', 'golang', 300, 1)`,
+		`INSERT INTO post_stream_seen VALUES ('bot', 'golang', 't3_a', 1)`,
+	} {
+		if _, err := old.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old.Close()
+
+	db, err := openStreamDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var since int64
+	if err := db.QueryRow(`SELECT since FROM post_stream_checkpoints WHERE owner = '' AND consumer = 'bot' AND subreddit = 'golang'`).Scan(&since); err != nil {
+		t.Fatal(err)
+	}
+	if since != 300 {
+		t.Errorf("checkpoint = %d, want 300", since)
+	}
+	seen, err := postStreamSeen(context.Background(), db, "", "bot", "golang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !seen["t3_a"] {
+		t.Error("t3_a not kept as seen")
+	}
+}
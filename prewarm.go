@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Keep the about and hot data of pinned subreddits in the cache, refreshing
+// each entry shortly before it would expire. The pinned list and TTL are read
+// from the live config on every pass, so reloads take effect on the next one.
+func warmPinnedSubreddits(ctx context.Context) {
+	for {
+		cfg := currentConfig()
+		ttl := time.Duration(cfg.CacheTTL) * time.Second
+
+		if ttl > 0 {
+			for _, subreddit := range cfg.PinnedSubreddits {
+				if ctx.Err() != nil {
+					return
+				}
+				warmSubreddit(withCacheRefresh(ctx), subreddit)
+			}
+		}
+
+		// Refresh at 80% of the TTL, and check back periodically while caching is off
+		wait := ttl * 4 / 5
+		if wait <= 0 {
+			wait = time.Minute
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Fetch the requests a session is likely to start with for subreddit
+func warmSubreddit(ctx context.Context, subreddit string) {
+	if _, err := lookupSubreddit(ctx, subreddit); err != nil {
+		slog.Warn("Failed to warm subreddit metadata", "subreddit", subreddit, "error", err)
+	}
+
+	var hot listing
+	endpoint, params := subredditListingRequest(subreddit, "hot", defaultListingLimit)
+	if err := makeRedditRequest(ctx, endpoint, params, &hot); err != nil {
+		slog.Warn("Failed to warm subreddit hot posts", "subreddit", subreddit, "error", err)
+	}
+}
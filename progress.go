@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Progress of one tool call whose client asked for progress notifications
+type progressTracker struct {
+	mu    sync.Mutex
+	token mcp.ProgressToken
+	done  float64
+}
+
+type progressTrackerKey struct{}
+
+// Tool middleware attaching a progress tracker when the call carries a progressToken
+func progressMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if meta := request.Params.Meta; meta != nil && meta.ProgressToken != nil {
+			ctx = context.WithValue(ctx, progressTrackerKey{}, &progressTracker{token: meta.ProgressToken})
+		}
+		return next(ctx, request)
+	}
+}
+
+// Report that done of total steps of the tool call in ctx are complete (total 0
+// if unknown). Progress never goes backwards, as the protocol requires, so
+// nested operations can report their own steps without coordinating.
+func reportProgress(ctx context.Context, done, total float64, message string) {
+	tracker, ok := ctx.Value(progressTrackerKey{}).(*progressTracker)
+	if !ok {
+		return
+	}
+	s := server.ServerFromContext(ctx)
+	if s == nil {
+		return
+	}
+
+	tracker.mu.Lock()
+	if done <= tracker.done {
+		tracker.mu.Unlock()
+		return
+	}
+	tracker.done = done
+	tracker.mu.Unlock()
+
+	params := map[string]any{"progressToken": tracker.token, "progress": done}
+	if total > 0 {
+		params["total"] = total
+	}
+	if message != "" {
+		params["message"] = message
+	}
+	if err := s.SendNotificationToClient(ctx, string(mcp.MethodNotificationProgress), params); err != nil {
+		slog.Debug("Failed to send progress notification", "error", err)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Register prompts that walk the model through common research workflows
+// using the tools above, so users get good results without crafting the
+// instructions themselves
+func registerPrompts(s *server.MCPServer) {
+	s.AddPrompt(
+		mcp.NewPrompt("summarize_thread",
+			mcp.WithPromptDescription("Summarize a Reddit post and its discussion"),
+			mcp.WithArgument("post_id",
+				mcp.RequiredArgument(),
+				mcp.ArgumentDescription("ID of the post (without the 't3_' prefix)"),
+			),
+			mcp.WithArgument("focus",
+				mcp.ArgumentDescription("Optional aspect of the discussion to concentrate on"),
+			),
+		),
+		handleSummarizeThreadPrompt,
+	)
+
+	s.AddPrompt(
+		mcp.NewPrompt("compare_subreddits",
+			mcp.WithPromptDescription("Compare the focus, tone and activity of several subreddits"),
+			mcp.WithArgument("subreddits",
+				mcp.RequiredArgument(),
+				mcp.ArgumentDescription("Comma-separated subreddit names (without the 'r/' prefix)"),
+			),
+			mcp.WithArgument("topic",
+				mcp.ArgumentDescription("Optional topic to compare how each subreddit discusses"),
+			),
+		),
+		handleCompareSubredditsPrompt,
+	)
+
+	s.AddPrompt(
+		mcp.NewPrompt("draft_post_for_subreddit",
+			mcp.WithPromptDescription("Draft a post that fits a subreddit's rules and culture"),
+			mcp.WithArgument("subreddit",
+				mcp.RequiredArgument(),
+				mcp.ArgumentDescription("Subreddit to post in (without the 'r/' prefix)"),
+			),
+			mcp.WithArgument("topic",
+				mcp.RequiredArgument(),
+				mcp.ArgumentDescription("What the post should be about"),
+			),
+		),
+		handleDraftPostPrompt,
+	)
+}
+
+func handleSummarizeThreadPrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	postID := strings.TrimPrefix(request.Params.Arguments["post_id"], "t3_")
+	if postID == "" {
+		return nil, invalidInput("post_id is required")
+	}
+
+	cfg := currentConfig()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Summarize the Reddit thread for post %s.\n\n", postID)
+	fmt.Fprintf(&sb, "1. Call %s with post_id %q to read the post itself.\n", toolName(cfg, "post"), postID)
+	fmt.Fprintf(&sb, "2. Call %s with post_id %q, sort \"top\" and limit 100 to read the discussion.\n", toolName(cfg, "comments"), postID)
+	sb.WriteString("3. Write a summary covering: what the post asks or claims, the main viewpoints in the comments " +
+		"(with rough levels of agreement), any notable facts, links or advice, and open questions.\n")
+	if focus := request.Params.Arguments["focus"]; focus != "" {
+		fmt.Fprintf(&sb, "\nConcentrate on: %s\n", focus)
+	}
+	sb.WriteString("\nAttribute claims to commenters by username and don't present opinions as facts.")
+
+	return promptResult("Summarize a Reddit thread", sb.String()), nil
+}
+
+func handleCompareSubredditsPrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	var subreddits []string
+	(*stringList)(&subreddits).Set(request.Params.Arguments["subreddits"])
+	if len(subreddits) < 2 {
+		return nil, invalidInput("subreddits must name at least two subreddits")
+	}
+	for i, subreddit := range subreddits {
+		subreddits[i] = strings.TrimPrefix(subreddit, "r/")
+	}
+	topic := request.Params.Arguments["topic"]
+
+	cfg := currentConfig()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Compare these subreddits: r/%s.\n\n", strings.Join(subreddits, ", r/"))
+	sb.WriteString("For each subreddit:\n")
+	if topic != "" {
+		fmt.Fprintf(&sb, "1. Call %s with query %q, the subreddit, sort \"top\" and limit 10.\n", toolName(cfg, "search"), topic)
+	} else {
+		fmt.Fprintf(&sb, "1. Call %s with the subreddit, sort \"top\" and limit 10.\n", toolName(cfg, "subreddit_posts"))
+	}
+	fmt.Fprintf(&sb, "2. Call %s with the subreddit, sort \"new\" and limit 10 to gauge current activity.\n", toolName(cfg, "subreddit_posts"))
+	sb.WriteString("\nThen compare them side by side: audience and size, typical subjects, tone, how active they are, ")
+	if topic != "" {
+		fmt.Fprintf(&sb, "and how each one discusses %s. ", topic)
+	} else {
+		sb.WriteString("and what kind of question or post does well in each. ")
+	}
+	sb.WriteString("Finish with a recommendation of which subreddit suits which purpose.")
+
+	return promptResult("Compare subreddits", sb.String()), nil
+}
+
+func handleDraftPostPrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	subreddit := strings.TrimPrefix(request.Params.Arguments["subreddit"], "r/")
+	if subreddit == "" {
+		return nil, invalidInput("subreddit is required")
+	}
+	topic := request.Params.Arguments["topic"]
+	if topic == "" {
+		return nil, invalidInput("topic is required")
+	}
+
+	cfg := currentConfig()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Draft a post for r/%s about: %s\n\n", subreddit, topic)
+	fmt.Fprintf(&sb, "1. Call %s with subreddit %q, sort \"top\" and limit 10; the header describes the community.\n", toolName(cfg, "subreddit_posts"), subreddit)
+	fmt.Fprintf(&sb, "2. Call %s with query %q and subreddit %q to check whether this was asked recently.\n", toolName(cfg, "search"), topic, subreddit)
+	sb.WriteString("3. Write a title and body that match the style of the successful posts, " +
+		"respect the subreddit's rules and don't duplicate an existing thread.\n")
+	sb.WriteString("\nIf a recent thread already covers the topic, say so and link it instead of drafting a duplicate. " +
+		"Don't submit anything; only present the draft.")
+
+	return promptResult("Draft a post for r/"+subreddit, sb.String()), nil
+}
+
+// A prompt made of a single user message
+func promptResult(description, text string) *mcp.GetPromptResult {
+	return mcp.NewGetPromptResult(description, []mcp.PromptMessage{
+		mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text)),
+	})
+}
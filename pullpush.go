@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Results pullpush returns per request at most
+const maxPullpushResults = 100
+
+// How pullpush orders results for each search sort; it has no relevance or
+// hot ranking, so relevance falls back to newest first
+var pullpushSorts = map[string]string{
+	"relevance": "created_utc",
+	"new":       "created_utc",
+	"top":       "score",
+}
+
+// Search the pullpush.io archive of Reddit, kind being "submission" or
+// "comment"; results come back as a listing like Reddit's own
+func fetchPullpush(ctx context.Context, kind string, params url.Values) (listing, error) {
+	var result listing
+	base := currentConfig().Pullpush
+	if base == "" {
+		return result, invalidInput("the pullpush backend is disabled on this server")
+	}
+	if err := ctx.Err(); err != nil {
+		return result, fmt.Errorf("request cancelled: %w", err)
+	}
+	requestURL := strings.TrimSuffix(base, "/") + "/reddit/search/" + kind + "/?" + params.Encode()
+	recordEndpoint(ctx, strings.TrimPrefix(requestURL, base))
+
+	var page struct {
+		Data []item `json:"data"`
+	}
+	ttl := time.Duration(currentConfig().CacheTTL) * time.Second
+	cacheKey := "pullpush " + requestURL
+	body, cached := redditCache.get(cacheKey)
+	if !cached || ttl <= 0 || isCacheRefresh(ctx) {
+		var err error
+		if body, err = getPullpush(ctx, requestURL); err != nil {
+			return result, err
+		}
+		if ttl > 0 {
+			redditCache.put(cacheKey, body, ttl)
+		}
+	}
+	if err := json.Unmarshal(body, &page); err != nil {
+		return result, fmt.Errorf("%w: unreadable response: %v", errPullpush, err)
+	}
+
+	prefix := "t3"
+	if kind == "comment" {
+		prefix = "t1"
+	}
+	for _, data := range page.Data {
+		if data.Name == "" {
+			data.Name = prefix + "_" + data.ID
+		}
+		result.Children = append(result.Children, thing{Kind: prefix, Data: data})
+	}
+	return result, nil
+}
+
+func getPullpush(ctx context.Context, requestURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent())
+
+	// pullpush is slow and small; the upstream pool keeps it from being flooded too
+	release, err := acquireUpstream(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("request cancelled: %w", err)
+	}
+	defer release()
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: request failed: %v", errPullpush, err)
+	}
+	defer resp.Body.Close()
+	slog.DebugContext(ctx, "pullpush request", "url", requestURL, "status", resp.StatusCode)
+	switch {
+	case resp.StatusCode == http.StatusBadRequest:
+		return nil, invalidInput("pullpush rejected the search (status %d)", resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("%w: pullpush returned %d", errPullpush, resp.StatusCode)
+	}
+	maxBytes := currentConfig().MaxResponseBytes
+	body, err := io.ReadAll(newLimitedReader(resp.Body, maxBytes))
+	if errors.Is(err, errResponseTooLarge) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading response: %v", errPullpush, err)
+	}
+	return bytes.TrimSpace(body), nil
+}
+
+// Search posts in the pullpush archive, for content older than Reddit's
+// search reliably reaches
+func handlePullpushSearch(ctx context.Context, args map[string]any, opts formatOptions, dates dateRange) (*mcp.CallToolResult, error) {
+	query, _ := args["query"].(string)
+	sort, _ := args["sort"].(string)
+	if sort == "" {
+		sort = "relevance"
+	}
+	sortType, ok := pullpushSorts[sort]
+	if !ok {
+		return toolError(invalidInput("the pullpush backend can't sort by %s; use relevance, new or top", sort)), nil
+	}
+	limit := 10
+	if n, ok := args["limit"].(float64); ok {
+		limit = min(max(int(n), 1), maxPullpushResults)
+	}
+
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("size", fmt.Sprint(limit))
+	params.Set("sort", "desc")
+	params.Set("sort_type", sortType)
+	if subreddit, _ := args["subreddit"].(string); subreddit != "" {
+		params.Set("subreddit", subredditKey(subreddit))
+	}
+	if opts.author != "" {
+		params.Set("author", opts.author)
+	}
+	// The archive filters dates itself, so no paging is needed for a range
+	if !dates.after.IsZero() {
+		params.Set("after", fmt.Sprint(dates.after.Unix()))
+	}
+	if !dates.before.IsZero() {
+		params.Set("before", fmt.Sprint(dates.before.Unix()))
+	}
+
+	result, err := fetchPullpush(ctx, "submission", params)
+	if err != nil {
+		return toolError(err), nil
+	}
+	full := len(result.Children) == limit
+	leftOut := filterPosts(&result, opts)
+	collapseDuplicates(&result)
+
+	formattedResult, err := formatSearchResults(&result, opts)
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("Failed to format results", err), nil
+	}
+	formattedResult = "From the pullpush archive; scores and comment counts are as archived, not current.\n\n" +
+		formatListingStats(&result, opts) + formattedResult
+	if full && sortType == "created_utc" {
+		leftOut += "For older results, pass the date of the last one as before_date.\n"
+	}
+	output := newListingOutput(&result, nil, nil)
+	return newStructuredResult(output, formattedResult+leftOut), nil
+}
+
+// The recover_removed argument of tools showing posts and comments
+func recoverRemovedArgument() mcp.ToolOption {
+	return mcp.WithBoolean("recover_removed",
+		mcp.Description("Look up removed and deleted posts and comments in the pullpush archive and show the text it kept, labelled as an archived copy"),
+		mcp.DefaultBool(false),
+	)
+}
+
+// Fill in the text pullpush archived for removed and deleted items, returning
+// a note on how many were recovered; failures leave the items as they were
+func recoverRemoved(ctx context.Context, posts, comments []*item) string {
+	wanted := 0
+	recovered := 0
+	for _, batch := range []struct {
+		kind  string
+		items []*item
+	}{{"submission", posts}, {"comment", comments}} {
+		byID := map[string]*item{}
+		for _, it := range batch.items {
+			if it.Removal != "" {
+				byID[it.ID] = it
+			}
+		}
+		wanted += len(byID)
+		ids := slices.Sorted(maps.Keys(byID))
+		for chunk := range slices.Chunk(ids, maxPullpushResults) {
+			params := url.Values{}
+			params.Set("ids", strings.Join(chunk, ","))
+			params.Set("size", fmt.Sprint(len(chunk)))
+			found, err := fetchPullpush(ctx, batch.kind, params)
+			if err != nil {
+				return fmt.Sprintf("Couldn't recover removed content from the pullpush archive: %v\n", err)
+			}
+			for i := range found.Children {
+				archived := &found.Children[i].Data
+				it := byID[archived.ID]
+				text := archived.Body
+				if batch.kind == "submission" {
+					text = archived.Selftext
+				}
+				// The archive may only have caught the placeholder too
+				switch strings.TrimSpace(text) {
+				case "", removedText, deletedText:
+					continue
+				}
+				if it == nil {
+					continue
+				}
+				it.Recovered = text
+				recovered++
+			}
+		}
+	}
+	if wanted == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Recovered %d of %d removed or deleted posts and comments from the pullpush archive; recovered text is an archived copy and may predate edits.\n", recovered, wanted)
+}
+
+// The comments of a tree, replies included
+func commentItems(replies *listing) []*item {
+	if replies == nil {
+		return nil
+	}
+	var items []*item
+	for i := range replies.Children {
+		if replies.Children[i].Kind == "more" {
+			continue
+		}
+		items = append(items, &replies.Children[i].Data)
+		items = append(items, commentItems(replies.Children[i].Data.Replies)...)
+	}
+	return items
+}
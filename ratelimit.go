@@ -0,0 +1,94 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Token bucket for a single client
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Per-client token-bucket limiter keyed by bearer token or remote address
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{buckets: make(map[string]*bucket)}
+}
+
+// Take a token for key, returning how long to wait if none is available
+func (l *rateLimiter) allow(key string, perMinute, burst int, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	perSec := float64(perMinute) / 60
+	capacity := float64(max(burst, 1))
+
+	// Forget clients that have been idle long enough to have a full bucket
+	if now.Sub(l.swept) > time.Minute {
+		refill := time.Duration(capacity / perSec * float64(time.Second))
+		for k, b := range l.buckets {
+			if now.Sub(b.last) > refill {
+				delete(l.buckets, k)
+			}
+		}
+		l.swept = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*perSec)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / perSec * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// Reject client messages over quota with 429 Too Many Requests
+func (l *rateLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Only POSTs carry client messages; SSE streams are long-lived GETs
+		cfg := currentConfig()
+		if r.Method != http.MethodPost || cfg.ClientRate <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ok, wait := l.allow(clientKey(r), cfg.ClientRate, cfg.ClientBurst, time.Now())
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Identify the client by its token, falling back to the remote IP
+func clientKey(r *http.Request) string {
+	if token := requestToken(r); token != "" {
+		return "token:" + token
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	// One step of a client's calls: when, and whether it should get through
+	type call struct {
+		at      time.Duration
+		key     string
+		allowed bool
+	}
+	tests := []struct {
+		name      string
+		perMinute int
+		burst     int
+		calls     []call
+	}{
+		{"burst then refused", 60, 2, []call{{0, "a", true}, {0, "a", true}, {0, "a", false}}},
+		{"refills over time", 60, 1, []call{{0, "a", true}, {0, "a", false}, {time.Second, "a", true}}},
+		{"clients are separate", 60, 1, []call{{0, "a", true}, {0, "b", true}, {0, "a", false}}},
+		{"burst below one allows one", 60, 0, []call{{0, "a", true}, {0, "a", false}}},
+		{"refill caps at the burst", 60, 2, []call{{0, "a", true}, {time.Hour, "a", true}, {time.Hour, "a", true}, {time.Hour, "a", false}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newRateLimiter()
+			for i, c := range tt.calls {
+				ok, wait := l.allow(c.key, tt.perMinute, tt.burst, start.Add(c.at))
+				if ok != c.allowed {
+					t.Fatalf("call %d: allow() = %v, want %v", i, ok, c.allowed)
+				}
+				if !ok && wait <= 0 {
+					t.Errorf("call %d: refused without a wait", i)
+				}
+			}
+		})
+	}
+}
+
+func TestClientKey(t *testing.T) {
+	r := httptest.NewRequest("POST", "/mcp", nil)
+	r.RemoteAddr = "192.0.2.1:5000"
+	if got := clientKey(r); got != "ip:192.0.2.1" {
+		t.Errorf("clientKey() = %q, want the remote IP", got)
+	}
+	r.Header.Set("Authorization", "Bearer s3cret")
+	if got := clientKey(r); got != "token:s3cret" {
+		t.Errorf("clientKey() = %q, want the token", got)
+	}
+}
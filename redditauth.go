@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reddit's OAuth API, used instead of the public JSON API when an account is configured
+const (
+	redditOAuthURL = "https://oauth.reddit.com"
+	redditTokenURL = "https://www.reddit.com/api/v1/access_token"
+)
+
+// Credentials for a Reddit "script" app; without a username the app authenticates application-only
+type redditAccount struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+}
+
+// Whether API requests should go through OAuth
+func (a redditAccount) configured() bool {
+	return a.ClientID != ""
+}
+
+// Fill unset fields from the REDDIT_* environment variables
+func (a *redditAccount) fromEnv() {
+	for _, v := range []struct {
+		field *string
+		env   string
+	}{
+		{&a.ClientID, "REDDIT_CLIENT_ID"},
+		{&a.ClientSecret, "REDDIT_CLIENT_SECRET"},
+		{&a.Username, "REDDIT_USERNAME"},
+		{&a.Password, "REDDIT_PASSWORD"},
+	} {
+		if *v.field == "" {
+			*v.field = os.Getenv(v.env)
+		}
+	}
+}
+
+// Caches the access token for the configured account until shortly before it expires
+type tokenSource struct {
+	mu      sync.Mutex
+	account redditAccount
+	token   string
+	expiry  time.Time
+}
+
+var redditTokens = &tokenSource{}
+
+// Return a valid access token for account, fetching a new one when needed
+func (t *tokenSource) Token(ctx context.Context, account redditAccount) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// A reloaded account invalidates the cached token
+	if t.account == account && t.token != "" && time.Now().Before(t.expiry) {
+		return t.token, nil
+	}
+
+	token, expiresIn, err := fetchRedditToken(ctx, account)
+	if err != nil {
+		return "", err
+	}
+
+	t.account = account
+	t.token = token
+	t.expiry = time.Now().Add(expiresIn - time.Minute)
+	return t.token, nil
+}
+
+// Exchange account credentials for an access token
+func fetchRedditToken(ctx context.Context, account redditAccount) (string, time.Duration, error) {
+	form := url.Values{}
+	if account.Username != "" {
+		form.Set("grant_type", "password")
+		form.Set("username", account.Username)
+		form.Set("password", account.Password)
+	} else {
+		form.Set("grant_type", "client_credentials")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, redditTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.SetBasicAuth(account.ClientID, account.ClientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", userAgent())
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("token endpoint returned error status: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		AccessToken string  `json:"access_token"`
+		ExpiresIn   float64 `json:"expires_in"`
+		Error       string  `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", 0, fmt.Errorf("failed to parse token response: %w", err)
+	}
+	if result.AccessToken == "" {
+		return "", 0, fmt.Errorf("token request rejected: %s", result.Error)
+	}
+
+	return result.AccessToken, time.Duration(result.ExpiresIn) * time.Second, nil
+}
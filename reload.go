@@ -0,0 +1,71 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Level of the default logger, adjustable on reload
+var logLevel = new(slog.LevelVar)
+
+// Send logs to stderr so they never mix with the stdio transport, and to
+// connected clients as MCP log notifications
+func setupLogging(cfg *config) {
+	logLevel.Set(parseLogLevel(cfg.LogLevel))
+	stderr := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel})
+	slog.SetDefault(slog.New(&clientLogHandler{next: stderr}))
+}
+
+func parseLogLevel(name string) slog.Level {
+	var level slog.Level
+	level.UnmarshalText([]byte(name))
+	return level
+}
+
+// Callbacks run after a successful reload with the previous and new config
+var reloadHooks []func(old, cfg *config)
+
+// Register fn to run after each reload; only call during startup
+func onReload(fn func(old, cfg *config)) {
+	reloadHooks = append(reloadHooks, fn)
+}
+
+// Re-read the config on every SIGHUP and swap it in
+func watchReload(args []string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		reloadConfig(args)
+	}
+}
+
+// Replace the live config, keeping the old one if the new one is invalid.
+// Sessions are unaffected; listener settings only apply after a restart.
+func reloadConfig(args []string) {
+	cfg, err := parseConfig(args, io.Discard)
+	if err != nil {
+		slog.Error("Config reload failed, keeping previous config", "error", err)
+		return
+	}
+
+	old := liveConfig.Swap(cfg)
+	logLevel.Set(parseLogLevel(cfg.LogLevel))
+
+	if old != nil && (old.Transport != cfg.Transport || old.Addr != cfg.Addr || old.Path != cfg.Path || old.BasePath != cfg.BasePath ||
+		old.TLSCert != cfg.TLSCert || old.TLSKey != cfg.TLSKey || old.TLSSelfSigned != cfg.TLSSelfSigned ||
+		old.KeepAlive != cfg.KeepAlive || old.SessionIdleTimeout != cfg.SessionIdleTimeout || old.MaxConcurrency != cfg.MaxConcurrency) {
+		slog.Warn("Transport, TLS, session and concurrency settings changed; restart to apply them")
+	}
+	if old != nil && old.Archive != cfg.Archive {
+		slog.Warn("Archive database changed; restart to apply it")
+	}
+	if old != nil {
+		for _, hook := range reloadHooks {
+			hook(old, cfg)
+		}
+	}
+	slog.Info("Config reloaded", "file", cfg.ConfigFile)
+}
@@ -0,0 +1,64 @@
+package main
+
+import "strings"
+
+// Placeholders Reddit puts in place of the author or text of content that's gone
+const (
+	deletedText = "[deleted]"
+	removedText = "[removed]"
+)
+
+// Why a post or comment is gone, such as "removed by moderators", or "" if it
+// isn't. Posts say who removed them in removed_by_category; comments only
+// leave a placeholder body behind.
+func removalReason(it *item) string {
+	switch it.RemovedByCategory {
+	case "":
+	case "moderator":
+		return "removed by moderators"
+	case "automod_filtered":
+		return "held by AutoModerator"
+	case "deleted", "author":
+		return "deleted by its author"
+	case "reddit", "anti_evil_ops", "community_ops":
+		return "removed by Reddit"
+	case "copyright_takedown":
+		return "removed for a copyright claim"
+	case "content_takedown":
+		return "removed for a legal request"
+	default:
+		return "removed (" + strings.ReplaceAll(it.RemovedByCategory, "_", " ") + ")"
+	}
+
+	text := it.Body
+	if it.Title != "" {
+		text = it.Selftext
+	}
+	switch strings.TrimSpace(text) {
+	case removedText:
+		return "removed by moderators"
+	case deletedText:
+		return "deleted by its author"
+	}
+	return ""
+}
+
+// The text of a post or comment as shown: its body, or why it's gone
+// followed by any copy recovered from the archive
+func shownText(it *item, text string) string {
+	switch {
+	case it.Removal != "" && it.Recovered != "":
+		return "[" + it.Removal + "; archived copy from pullpush follows]\n" + it.Recovered
+	case it.Removal != "":
+		return "[" + it.Removal + "]"
+	}
+	return text
+}
+
+// A username as shown, e.g. "u/spez"; accounts that no longer exist are "[deleted]"
+func userLabel(name string) string {
+	if name == "" || name == deletedText {
+		return deletedText
+	}
+	return "u/" + name
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// URI templates for subreddit feeds (e.g. reddit://r/golang/hot?limit=25) and threads
+const (
+	subredditFeedTemplate = "reddit://r/{subreddit}/{sort}{?limit}"
+	postTemplate          = "reddit://post/{id}"
+	postCommentsTemplate  = "reddit://post/{id}/comments{?sort,limit}"
+)
+
+// URI of a post resource
+func postResourceURI(postID string) string {
+	return "reddit://post/" + strings.TrimPrefix(postID, "t3_")
+}
+
+// URI of a comment thread resource
+func commentsResourceURI(postID, sort string, limit int) string {
+	return fmt.Sprintf("%s/comments?sort=%s&limit=%d", postResourceURI(postID), url.QueryEscape(sort), limit)
+}
+
+// Replace a post body longer than max characters with an excerpt, returning
+// a link to the post resource holding the full text (nil if the body fits)
+func linkLongBody(post *item, max int) mcp.Content {
+	if max <= 0 || len(post.Selftext) <= max {
+		return nil
+	}
+	excerpt := truncateAt(post.Selftext, max)
+	post.Selftext = fmt.Sprintf("%s\n[truncated, %d more chars; call again with max_body_length 0 or read resource %s for the full text]",
+		excerpt, len(post.Selftext)-len(excerpt), postResourceURI(post.ID))
+	return mcp.NewResourceLink(postResourceURI(post.ID), post.Title, "The full post, including its body", "text/plain")
+}
+
+// Register subreddit feed and thread resources: templates for any subreddit
+// or post, plus a listed resource per favorite subreddit that follows reloads
+func registerResources(s *server.MCPServer) {
+	s.AddResourceTemplate(
+		mcp.NewResourceTemplate(postTemplate, "Reddit post",
+			mcp.WithTemplateDescription("Details of a post by ID, as found in 'Post ID:' lines of search results"),
+			mcp.WithTemplateMIMEType("text/plain"),
+		),
+		handlePostResource,
+	)
+	s.AddResourceTemplate(
+		mcp.NewResourceTemplate(postCommentsTemplate, "Reddit comment thread",
+			mcp.WithTemplateDescription("Comments on a post; sort is top, new, controversial, old or qa, limit is 1-100"),
+			mcp.WithTemplateMIMEType("text/plain"),
+		),
+		handlePostCommentsResource,
+	)
+
+	s.AddResourceTemplate(
+		mcp.NewResourceTemplate(subredditFeedTemplate, "Subreddit feed",
+			mcp.WithTemplateDescription("Posts from a subreddit listing; sort is hot, new, top or rising, limit is 1-100"),
+			mcp.WithTemplateMIMEType("text/plain"),
+		),
+		func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			args := request.Params.Arguments
+			return readSubredditFeed(ctx, request.Params.URI,
+				resourceArg(args, "subreddit"), resourceArg(args, "sort"), resourceArg(args, "limit"))
+		},
+	)
+
+	syncFavoriteResources(s, nil, currentConfig().FavoriteSubreddits)
+	onReload(func(old, cfg *config) {
+		syncFavoriteResources(s, old.FavoriteSubreddits, cfg.FavoriteSubreddits)
+	})
+}
+
+// URI of a favorite subreddit's hot feed
+func favoriteFeedURI(subreddit string) string {
+	return fmt.Sprintf("reddit://r/%s/hot", subredditKey(subreddit))
+}
+
+// Add resources for newly favorited subreddits and drop the ones no longer configured
+func syncFavoriteResources(s *server.MCPServer, old, current []string) {
+	var removed []string
+	for _, subreddit := range old {
+		if !slices.Contains(current, subreddit) {
+			removed = append(removed, favoriteFeedURI(subreddit))
+		}
+	}
+	if len(removed) > 0 {
+		s.DeleteResources(removed...)
+	}
+
+	for _, subreddit := range current {
+		if slices.Contains(old, subreddit) {
+			continue
+		}
+		name := subredditKey(subreddit)
+		s.AddResource(
+			mcp.NewResource(favoriteFeedURI(subreddit), "r/"+name+" (hot)",
+				mcp.WithResourceDescription("Hot posts in favorite subreddit r/"+name),
+				mcp.WithMIMEType("text/plain"),
+			),
+			func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+				return readSubredditFeed(ctx, request.Params.URI, name, "hot", "")
+			},
+		)
+	}
+}
+
+// Fetch and render a subreddit feed resource
+func readSubredditFeed(ctx context.Context, uri, subreddit, sort, rawLimit string) ([]mcp.ResourceContents, error) {
+	if subreddit == "" {
+		return nil, invalidInput("subreddit is required")
+	}
+	switch sort {
+	case "hot", "new", "top", "rising":
+	default:
+		return nil, invalidInput("sort must be hot, new, top or rising, not %q", sort)
+	}
+
+	limit := defaultListingLimit
+	if rawLimit != "" {
+		n, err := strconv.Atoi(rawLimit)
+		if err != nil || n < 1 || n > 100 {
+			return nil, invalidInput("limit must be a number from 1 to 100")
+		}
+		limit = n
+	}
+
+	var result listing
+	endpoint, params := subredditListingRequest(subreddit, sort, limit)
+	if err := makeRedditRequest(ctx, endpoint, params, &result); err != nil {
+		return nil, err
+	}
+
+	opts := defaultFormatOptions()
+	leftOut := filterPosts(&result, opts)
+	text, err := formatSearchResults(&result, opts)
+	if err != nil {
+		return nil, err
+	}
+	text += leftOut
+	if info, err := lookupSubreddit(ctx, subreddit); err == nil {
+		text = formatSubredditHeader(info) + text
+	}
+
+	return textResource(uri, text), nil
+}
+
+// Read a post resource
+func handlePostResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	postID := resourceArg(request.Params.Arguments, "id")
+	if postID == "" {
+		return nil, invalidInput("post id is required")
+	}
+
+	var result listing
+	endpoint, params := postDetailsRequest(postID)
+	if err := makeRedditRequest(ctx, endpoint, params, &result); err != nil {
+		return nil, err
+	}
+
+	opts := defaultFormatOptions()
+	if len(result.Children) > 0 {
+		redactNSFW(&result.Children[0].Data, opts)
+		redactSpoilers(&result.Children[0].Data, opts)
+	}
+	text, err := formatPostDetails(&result, opts)
+	if err != nil {
+		return nil, err
+	}
+	return textResource(request.Params.URI, text), nil
+}
+
+// Read a comment thread resource
+func handlePostCommentsResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	args := request.Params.Arguments
+	postID := resourceArg(args, "id")
+	if postID == "" {
+		return nil, invalidInput("post id is required")
+	}
+
+	sort := resourceArg(args, "sort")
+	switch sort {
+	case "":
+		sort = "top"
+	case "top", "new", "controversial", "old", "qa":
+	default:
+		return nil, invalidInput("sort must be top, new, controversial, old or qa, not %q", sort)
+	}
+
+	limit := 25
+	if raw := resourceArg(args, "limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > 100 {
+			return nil, invalidInput("limit must be a number from 1 to 100")
+		}
+		limit = n
+	}
+
+	var result commentsResponse
+	endpoint, params := commentsRequest(postID, sort, limit, defaultCommentDepth)
+	if err := makeRedditRequest(ctx, endpoint, params, &result); err != nil {
+		return nil, err
+	}
+
+	opts := defaultFormatOptions()
+	redactCommentSpoilers(&result.Comments, opts)
+	leftOut := filterComments(&result.Comments, opts)
+	text, err := formatComments(&result, opts)
+	if err != nil {
+		return nil, err
+	}
+	text += leftOut
+	return textResource(request.Params.URI, text), nil
+}
+
+// Wrap rendered text as the contents of the resource at uri. Tool results link
+// here when they're cut short, so unlike tool output this isn't truncated; the
+// client chose to read it, and upstream responses are still size-limited.
+func textResource(uri, text string) []mcp.ResourceContents {
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      uri,
+			MIMEType: "text/plain",
+			Text:     text,
+		},
+	}
+}
+
+// Template variables arrive as string lists; return the first value of name
+func resourceArg(args map[string]any, name string) string {
+	switch v := args[name].(type) {
+	case string:
+		return v
+	case []string:
+		if len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// An Atom feed as Reddit serves it for listings and searches
+type atomFeed struct {
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID       string `xml:"id"` // the post's fullname, t3_...
+	Title    string `xml:"title"`
+	Author   string `xml:"author>name"`
+	Category struct {
+		Term string `xml:"term,attr"`
+	} `xml:"category"`
+	Link struct {
+		Href string `xml:"href,attr"`
+	} `xml:"link"`
+	Published string `xml:"published"`
+	Content   string `xml:"content"`
+}
+
+var (
+	// The rendered self text inside an entry's HTML content
+	feedSelftext = regexp.MustCompile(`(?s)<div class="md">(.*?)</div>`)
+	// The entry's "[link]" anchor, pointing at what a link post links to
+	feedLink = regexp.MustCompile(`<a href="([^"]+)">\[link\]</a>`)
+	// Tags that end a line of text before the rest are dropped
+	feedBreaks = regexp.MustCompile(`(?i)</p>|<br\s*/?>|</li>|</h[1-6]>|</pre>|</blockquote>`)
+	feedTags   = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// Whether a failed JSON request is one the RSS feeds may still answer:
+// Reddit blocks and rate limits anonymous API clients well before its feeds
+func feedFallback(ctx context.Context, err error) bool {
+	if !currentConfig().RSSFallback || ctx.Err() != nil {
+		return false
+	}
+	return errors.Is(err, errForbidden) || errors.Is(err, errRateLimited)
+}
+
+// Fetch a listing or search endpoint as JSON, falling back to its RSS feed when
+// the API refuses; the note says what the feed leaves out, and is empty when
+// the JSON endpoint answered
+func fetchListingOrFeed(ctx context.Context, endpoint string, params url.Values, result *listing) (string, error) {
+	err := makeRedditRequest(ctx, endpoint, params, result)
+	if err == nil || !feedFallback(ctx, err) {
+		return "", err
+	}
+	slog.InfoContext(ctx, "Reddit refused the JSON request, reading the RSS feed instead", "endpoint", endpoint, "error", err)
+	feed, feedErr := fetchFeed(ctx, endpoint, params)
+	if feedErr != nil {
+		slog.DebugContext(ctx, "RSS fallback failed", "endpoint", endpoint, "error", feedErr)
+		return "", err
+	}
+	*result = feed
+	return fmt.Sprintf("Reddit's API refused the request (%v), so these posts come from its RSS feed: scores, comment counts, flairs and further pages aren't available.\n", err), nil
+}
+
+// Read the RSS (Atom) feed of a .json listing or search endpoint
+func fetchFeed(ctx context.Context, endpoint string, params url.Values) (listing, error) {
+	var result listing
+	if err := ctx.Err(); err != nil {
+		return result, fmt.Errorf("request cancelled: %w", err)
+	}
+	requestURL := redditBaseURL + strings.TrimSuffix(endpoint, ".json") + ".rss"
+	if len(params) > 0 {
+		requestURL += "?" + params.Encode()
+	}
+	recordEndpoint(ctx, strings.TrimPrefix(requestURL, redditBaseURL))
+
+	ttl := time.Duration(currentConfig().CacheTTL) * time.Second
+	cacheKey := "rss " + requestURL
+	body, cached := redditCache.get(cacheKey)
+	if !cached || ttl <= 0 || isCacheRefresh(ctx) {
+		var err error
+		if body, err = getFeed(ctx, requestURL); err != nil {
+			return result, err
+		}
+		if ttl > 0 {
+			redditCache.put(cacheKey, body, ttl)
+		}
+	}
+
+	var feed atomFeed
+	if err := xml.Unmarshal(body, &feed); err != nil {
+		return result, fmt.Errorf("failed to parse RSS feed: %w", err)
+	}
+	for _, entry := range feed.Entries {
+		// Feeds of comment searches and the like aren't posts
+		if !strings.HasPrefix(entry.ID, "t3_") {
+			continue
+		}
+		result.Children = append(result.Children, thing{Kind: "t3", Data: entry.post()})
+	}
+	return result, nil
+}
+
+func getFeed(ctx context.Context, requestURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent())
+
+	release, err := acquireUpstream(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("request cancelled: %w", err)
+	}
+	defer release()
+
+	client := &http.Client{CheckRedirect: checkRedditRedirect}
+	resp, err := client.Do(req)
+	if err != nil {
+		if errors.Is(err, errNotFound) || ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: request failed: %v", errUpstreamDown, err)
+	}
+	defer resp.Body.Close()
+	slog.DebugContext(ctx, "Reddit RSS request", "url", requestURL, "status", resp.StatusCode)
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp)
+	}
+	maxBytes := currentConfig().MaxResponseBytes
+	body, err := io.ReadAll(newLimitedReader(resp.Body, maxBytes))
+	if errors.Is(err, errResponseTooLarge) {
+		return nil, fmt.Errorf("RSS feed from Reddit exceeded %d bytes; request fewer items: %w", maxBytes, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading RSS feed: %w", err)
+	}
+	return bytes.TrimSpace(body), nil
+}
+
+// The post an entry describes, as far as the feed tells
+func (e atomEntry) post() item {
+	post := item{
+		ID:        strings.TrimPrefix(e.ID, "t3_"),
+		Name:      e.ID,
+		Title:     e.Title,
+		Author:    strings.TrimPrefix(e.Author, "/u/"),
+		Subreddit: e.Category.Term,
+		Permalink: strings.TrimPrefix(e.Link.Href, redditBaseURL),
+	}
+	if published, err := time.Parse(time.RFC3339, e.Published); err == nil {
+		post.CreatedUTC = float64(published.Unix())
+	}
+	if m := feedSelftext.FindStringSubmatch(e.Content); m != nil {
+		post.Selftext = feedText(m[1])
+	}
+	// Self posts link to themselves
+	post.URL = e.Link.Href
+	if m := feedLink.FindStringSubmatch(e.Content); m != nil {
+		post.URL = html.UnescapeString(m[1])
+	}
+	return post
+}
+
+// Plain text of the HTML Reddit renders into a feed, one line per paragraph
+func feedText(rendered string) string {
+	text := feedBreaks.ReplaceAllString(rendered, "$0\n")
+	text = html.UnescapeString(feedTags.ReplaceAllString(text, ""))
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n\n")
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Emoji and pictographs: the miscellaneous symbols and dingbats blocks, the
+// arrows and stars block and the supplementary emoji blocks, skin tone
+// modifiers included. Other symbols, such as ^, ` and currency signs, are kept.
+var emojiRanges = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x2600, Hi: 0x27bf, Stride: 1},
+		{Lo: 0x2b00, Hi: 0x2bff, Stride: 1},
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x1f000, Hi: 0x1faff, Stride: 1},
+	},
+}
+
+// Combining marks kept on one letter; more are stacked "Zalgo" text
+const maxCombiningMarks = 2
+
+// Clean a flair or username for downstream systems that choke on emoji and
+// control characters. "strip" drops emoji with their joiners, variation
+// selectors and keycaps, control, format and bidi characters, and combining
+// marks stacked past maxCombiningMarks; "ascii" also decomposes accented
+// letters and keeps only their ASCII base, dropping what has none.
+func sanitizeName(text, mode string) string {
+	if mode == "ascii" {
+		text = norm.NFKD.String(text)
+	}
+	var sb strings.Builder
+	marks := 0
+	for _, r := range text {
+		mark := unicode.In(r, unicode.Mn, unicode.Me)
+		if !mark {
+			marks = 0
+		}
+		switch {
+		case r == ' ':
+		case mode == "ascii" && r > unicode.MaxASCII:
+			continue
+		case unicode.Is(emojiRanges, r), unicode.Is(unicode.Variation_Selector, r), unicode.Is(unicode.Regional_Indicator, r):
+			continue
+		case r == '\u20e3':
+			// The keycap of emoji like 1️⃣
+			continue
+		case mark:
+			if marks++; marks > maxCombiningMarks {
+				continue
+			}
+		case unicode.IsSpace(r):
+			r = ' '
+		case unicode.In(r, unicode.Cc, unicode.Cf), !unicode.IsPrint(r):
+			// Controls, zero-width joiners and bidi overrides among them
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	// Emoji leave gaps behind, e.g. "🔥 Top Contributor 🔥"
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestSanitizeName(t *testing.T) {
+	tests := []struct {
+		name, in, mode, want string
+	}{
+		{"plain", "gopher_42", "strip", "gopher_42"},
+		{"ascii symbols kept", "a^b `c` $5 +1 ~x", "strip", "a^b `c` $5 +1 ~x"},
+		{"emoji dropped", "🔥 Top Contributor 🔥", "strip", "Top Contributor"},
+		{"joined emoji", "fam 👨\u200d👩\u200d👧 ily", "strip", "fam ily"},
+		{"skin tone and variation selector", "👍🏽 ok ❤\ufe0f", "strip", "ok"},
+		{"flags", "from 🇩🇪", "strip", "from"},
+		{"keycap", "number 1\ufe0f\u20e3", "strip", "number 1"},
+		{"accents kept", "Café Ñandú", "strip", "Café Ñandú"},
+		{"non-emoji symbols kept", "© ™ € ½ →", "strip", "© ™ € ½ →"},
+		{"bidi override", "user\u202eevil", "strip", "userevil"},
+		{"zero width", "zero\u200bwidth", "strip", "zerowidth"},
+		{"control characters", "tab\there\x07", "strip", "tab here"},
+		{"stacked marks", "Z\u0301\u0302\u0303\u0304algo", "strip", "Z\u0301\u0302algo"},
+		{"ascii folds accents", "Café 🔥", "ascii", "Cafe"},
+		{"ascii drops what has no base", "日本 fan", "ascii", "fan"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeName(tt.in, tt.mode); got != tt.want {
+				t.Errorf("sanitizeName(%q, %q) = %q, want %q", tt.in, tt.mode, got, tt.want)
+			}
+		})
+	}
+}
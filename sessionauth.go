@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Header carrying a client's own Reddit OAuth access token
+const redditTokenHeader = "X-Reddit-Access-Token"
+
+// Reddit access tokens supplied by clients on the network transports, keyed by
+// session ID. A token sticks to its session, so clients only need to send the
+// header once (SSE clients on a message POST), and a new value replaces it.
+type sessionTokenRegistry struct {
+	mu     sync.Mutex
+	tokens map[string]string
+}
+
+var sessionRedditTokens = &sessionTokenRegistry{tokens: make(map[string]string)}
+
+// Forget a session's token when it disconnects
+func (r *sessionTokenRegistry) addHooks(hooks *server.Hooks) {
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		r.mu.Lock()
+		delete(r.tokens, session.SessionID())
+		r.mu.Unlock()
+	})
+}
+
+type sessionRedditTokenKey struct{}
+
+// HTTP context function for the sse and http transports: remember the token a
+// request presents for its session and attach the session's token to ctx.
+// Without -session-reddit-tokens the header is ignored.
+func withSessionRedditToken(ctx context.Context, r *http.Request) context.Context {
+	if !currentConfig().SessionRedditTokens {
+		return ctx
+	}
+	session := server.ClientSessionFromContext(ctx)
+	token := strings.TrimSpace(r.Header.Get(redditTokenHeader))
+	if session == nil {
+		// Requests before initialize have no session to remember the token for
+		if token == "" {
+			return ctx
+		}
+		return context.WithValue(ctx, sessionRedditTokenKey{}, token)
+	}
+
+	sessionRedditTokens.mu.Lock()
+	if token != "" {
+		sessionRedditTokens.tokens[session.SessionID()] = token
+	} else {
+		token = sessionRedditTokens.tokens[session.SessionID()]
+	}
+	sessionRedditTokens.mu.Unlock()
+	if token == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, sessionRedditTokenKey{}, token)
+}
+
+// The Reddit access token the calling session supplied, if any
+func sessionRedditToken(ctx context.Context) string {
+	if !currentConfig().SessionRedditTokens {
+		return ""
+	}
+	token, _ := ctx.Value(sessionRedditTokenKey{}).(string)
+	return token
+}
+
+// Cache key prefix identifying a token without keeping the token itself
+func tokenFingerprint(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "token:" + hex.EncodeToString(sum[:8])
+}
+
+// Cache key prefix keeping what a session fetched with its own token, which
+// may include private subreddits, from every other session; "" without one
+func sessionCacheScope(ctx context.Context) string {
+	if token := sessionRedditToken(ctx); token != "" {
+		return tokenFingerprint(token) + " "
+	}
+	return ""
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"regexp"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// The shared reveal_spoilers argument of tools that return bodies
+func revealSpoilersArgument() mcp.ToolOption {
+	return mcp.WithBoolean("reveal_spoilers",
+		mcp.Description("Show spoiler-tagged post bodies and >!inline spoilers!< instead of hiding them"),
+		mcp.DefaultBool(false),
+	)
+}
+
+// Reddit's >!inline spoiler!< markup
+var inlineSpoiler = regexp.MustCompile(`>!(.+?)!<`)
+
+const hiddenSpoiler = "[spoiler hidden]"
+
+// Hide a spoiler-tagged post's body and media, and inline spoilers in other
+// posts, unless opts reveal them
+func redactSpoilers(post *item, opts formatOptions) {
+	if opts.revealSpoilers {
+		return
+	}
+	for i := range post.CrosspostParentList {
+		redactSpoilers(&post.CrosspostParentList[i], opts)
+	}
+	if !post.Spoiler {
+		post.Selftext = inlineSpoiler.ReplaceAllString(post.Selftext, hiddenSpoiler)
+		return
+	}
+	if post.Selftext != "" {
+		post.Selftext = "[Spoiler body hidden; call again with reveal_spoilers true to show it]"
+	}
+	post.Preview, post.GalleryData, post.Media, post.Thumbnail = nil, nil, nil, ""
+}
+
+// Hide inline spoilers throughout a comment tree unless opts reveal them
+func redactCommentSpoilers(replies *listing, opts formatOptions) {
+	if replies == nil || opts.revealSpoilers {
+		return
+	}
+	for i := range replies.Children {
+		comment := &replies.Children[i].Data
+		comment.Body = inlineSpoiler.ReplaceAllString(comment.Body, hiddenSpoiler)
+		redactCommentSpoilers(comment.Replies, opts)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Aggregate stats for the posts of a search: the dates they span, their
+// typical score and the subreddits they come from; "" for fewer than two
+func formatListingStats(result *listing, opts formatOptions) string {
+	posts := result.Children
+	if len(posts) < 2 {
+		return ""
+	}
+
+	scores := make([]int, 0, len(posts))
+	total := 0
+	var oldest, newest float64
+	subreddits := map[string]int{}
+	for i := range posts {
+		post := &posts[i].Data
+		scores = append(scores, post.Score)
+		total += post.Score
+		if post.CreatedUTC > 0 && (oldest == 0 || post.CreatedUTC < oldest) {
+			oldest = post.CreatedUTC
+		}
+		newest = max(newest, post.CreatedUTC)
+		if post.Subreddit != "" {
+			subreddits[post.Subreddit]++
+		}
+	}
+	slices.Sort(scores)
+	median := float64(scores[len(scores)/2])
+	if len(scores)%2 == 0 {
+		median = float64(scores[len(scores)/2-1]+scores[len(scores)/2]) / 2
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Overview:\n")
+	if oldest > 0 {
+		day := func(ts float64) string { return time.Unix(int64(ts), 0).In(opts.loc).Format("2006-01-02") }
+		fmt.Fprintf(&sb, "  Dates: %s to %s\n", day(oldest), day(newest))
+	}
+	fmt.Fprintf(&sb, "  Score: average %.0f, median %g, range %d to %d\n", float64(total)/float64(len(scores)), median, scores[0], scores[len(scores)-1])
+	if len(subreddits) > 0 {
+		names := slices.Collect(maps.Keys(subreddits))
+		slices.SortFunc(names, func(a, b string) int {
+			return cmp.Or(subreddits[b]-subreddits[a], strings.Compare(a, b))
+		})
+		shares := make([]string, 0, min(len(names), 5))
+		for _, name := range names[:min(len(names), 5)] {
+			shares = append(shares, fmt.Sprintf("r/%s %d", name, subreddits[name]))
+		}
+		if len(names) > 5 {
+			shares = append(shares, fmt.Sprintf("%d more", len(names)-5))
+		}
+		fmt.Fprintf(&sb, "  Subreddits: %s\n", strings.Join(shares, ", "))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
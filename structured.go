@@ -0,0 +1,195 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Structured tool output, returned alongside the text rendering so clients can
+// read IDs, scores and timestamps without parsing prose. The json tags are the
+// wire format and the jsonschema tags become the advertised output schemas.
+
+type postOutput struct {
+	ID          string   `json:"id" jsonschema:"Post ID, usable as post_id in other tools"`
+	Title       string   `json:"title"`
+	Subreddit   string   `json:"subreddit,omitempty" jsonschema:"Subreddit name without the r/ prefix"`
+	AlsoIn      []string `json:"also_in,omitempty" jsonschema:"Other subreddits the same link or crosspost appeared in; those results were merged into this one"`
+	Flair       string   `json:"flair,omitempty" jsonschema:"Link flair text"`
+	NSFW        bool     `json:"nsfw,omitempty"`
+	Spoiler     bool     `json:"spoiler,omitempty"`
+	Locked      bool     `json:"locked,omitempty" jsonschema:"No new comments are accepted"`
+	Archived    bool     `json:"archived,omitempty" jsonschema:"No new comments or votes are accepted"`
+	ContestMode bool     `json:"contest_mode,omitempty" jsonschema:"Comment scores are hidden and their order randomized"`
+	Author      string   `json:"author" jsonschema:"Username without the u/ prefix"`
+	Score       int      `json:"score"`
+	UpvoteRatio float64  `json:"upvote_ratio,omitempty" jsonschema:"Fraction of votes that are upvotes, from 0 to 1"`
+	NumComments int      `json:"num_comments"`
+	Awards      int      `json:"awards,omitempty" jsonschema:"Total awards received"`
+	CreatedUTC  int64    `json:"created_utc" jsonschema:"Creation time in Unix seconds"`
+	Created     string   `json:"created" jsonschema:"Creation time in RFC 3339 format"`
+	EditedUTC   int64    `json:"edited_utc,omitempty" jsonschema:"Time of the last edit in Unix seconds; absent if never edited"`
+	URL         string   `json:"url,omitempty" jsonschema:"Link target for link posts"`
+	Permalink   string   `json:"permalink,omitempty" jsonschema:"The post's page on Reddit"`
+	Selftext    string   `json:"selftext,omitempty" jsonschema:"Body of text posts"`
+	BodyURI     string   `json:"body_uri,omitempty" jsonschema:"Resource with the full body, when selftext was shortened"`
+	Removed     string   `json:"removed,omitempty" jsonschema:"Why the post is gone, e.g. removed by moderators or deleted by its author; selftext is then Reddit's placeholder"`
+	Recovered   string   `json:"recovered,omitempty" jsonschema:"The removed or deleted body as archived by pullpush, when recover_removed found it; an old copy, not what Reddit shows"`
+
+	LinkPreview *linkPreview `json:"link_preview,omitempty" jsonschema:"Title and description of the linked page, when link_preview was asked for"`
+
+	CrosspostOf        string `json:"crosspost_of,omitempty" jsonschema:"For crossposts, ID of the original post"`
+	CrosspostSubreddit string `json:"crosspost_subreddit,omitempty" jsonschema:"For crossposts, subreddit of the original post"`
+	CrosspostSelftext  string `json:"crosspost_selftext,omitempty" jsonschema:"For crossposts, body of the original post"`
+}
+
+type subredditOutput struct {
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Subscribers int    `json:"subscribers"`
+}
+
+// Output of reddit_search and reddit_subreddit_posts
+type listingOutput struct {
+	Subreddit  *subredditOutput `json:"subreddit,omitempty" jsonschema:"The subreddit listed, when known"`
+	Posts      []postOutput     `json:"posts"`
+	NextCursor string           `json:"next_cursor,omitempty" jsonschema:"Pass as cursor to the same tool for the next page; absent on the last page"`
+}
+
+type commentOutput struct {
+	ID            string `json:"id"`
+	ParentID      string `json:"parent_id,omitempty" jsonschema:"ID of the comment replied to; absent for top-level comments"`
+	Depth         int    `json:"depth" jsonschema:"1 for top-level comments, 2 for their replies and so on"`
+	Author        string `json:"author" jsonschema:"Username without the u/ prefix"`
+	Flair         string `json:"author_flair,omitempty" jsonschema:"The author's flair in the subreddit"`
+	Distinguished string `json:"distinguished,omitempty" jsonschema:"moderator or admin when the author commented in that role"`
+	IsOP          bool   `json:"is_op,omitempty" jsonschema:"The author wrote the post"`
+	Body          string `json:"body"`
+	Removed       string `json:"removed,omitempty" jsonschema:"Why the comment is gone, e.g. removed by moderators or deleted by its author; body is then Reddit's placeholder"`
+	Recovered     string `json:"recovered,omitempty" jsonschema:"The removed or deleted body as archived by pullpush, when recover_removed found it; an old copy, not what Reddit shows"`
+	Score         int    `json:"score" jsonschema:"Meaningless while score_hidden is set"`
+	ScoreHidden   bool   `json:"score_hidden,omitempty" jsonschema:"The subreddit still hides this new comment's score"`
+	Controversial bool   `json:"controversial,omitempty" jsonschema:"Many upvotes and downvotes, nearly balanced"`
+	Awards        int    `json:"awards,omitempty" jsonschema:"Total awards received"`
+	CreatedUTC    int64  `json:"created_utc" jsonschema:"Creation time in Unix seconds"`
+	Created       string `json:"created" jsonschema:"Creation time in RFC 3339 format"`
+	EditedUTC     int64  `json:"edited_utc,omitempty" jsonschema:"Time of the last edit in Unix seconds; absent if never edited"`
+	Permalink     string `json:"permalink,omitempty" jsonschema:"The comment's page on Reddit"`
+}
+
+// Output of reddit_comments
+type commentsOutput struct {
+	View        string          `json:"view" jsonschema:"threaded or flat"`
+	PostScore   int             `json:"post_score,omitempty"`
+	UpvoteRatio float64         `json:"upvote_ratio,omitempty" jsonschema:"Fraction of votes on the post that are upvotes, from 0 to 1"`
+	Comments    []commentOutput `json:"comments" jsonschema:"Comments in thread order, each reply following its parent, or highest score first for the flat view"`
+	Summary     string          `json:"summary,omitempty" jsonschema:"Summary of a thread too large to return, made by the client's model; comments is then empty and the full thread is linked as a resource"`
+}
+
+// Unix seconds and RFC 3339 forms of a Reddit created_utc value
+func createdTimes(createdUTC float64) (int64, string) {
+	if createdUTC == 0 {
+		return 0, ""
+	}
+	sec := int64(createdUTC)
+	return sec, time.Unix(sec, 0).UTC().Format(time.RFC3339)
+}
+
+func newPostOutput(post *item, withBody bool) *postOutput {
+	out := &postOutput{
+		ID:          post.ID,
+		Title:       post.Title,
+		Subreddit:   post.Subreddit,
+		AlsoIn:      post.AlsoIn,
+		Flair:       post.LinkFlairText,
+		NSFW:        post.Over18,
+		Spoiler:     post.Spoiler,
+		Locked:      post.Locked,
+		Archived:    post.Archived,
+		ContestMode: post.ContestMode,
+		Author:      post.Author,
+		Score:       post.Score,
+		UpvoteRatio: post.UpvoteRatio,
+		NumComments: post.NumComments,
+		Awards:      post.TotalAwardsReceived,
+		URL:         post.URL,
+		Removed:     post.Removal,
+		Recovered:   post.Recovered,
+	}
+	if post.Permalink != "" {
+		out.Permalink = permalinkURL(post.Permalink)
+	}
+	out.CreatedUTC, out.Created = createdTimes(post.CreatedUTC)
+	out.EditedUTC = int64(post.Edited)
+	if parent := post.crosspostParent(); parent != nil {
+		out.CrosspostOf, out.CrosspostSubreddit = parent.ID, parent.Subreddit
+		if withBody {
+			out.CrosspostSelftext = parent.Selftext
+		}
+	}
+	if withBody {
+		out.Selftext = post.Selftext
+	}
+	return out
+}
+
+func newListingOutput(result *listing, info *subredditInfo, next *listingCursor) *listingOutput {
+	out := &listingOutput{Posts: make([]postOutput, 0, len(result.Children))}
+	if next != nil {
+		out.NextCursor = next.encode()
+	}
+	for i := range result.Children {
+		out.Posts = append(out.Posts, *newPostOutput(&result.Children[i].Data, false))
+	}
+	if info != nil {
+		out.Subreddit = &subredditOutput{
+			Name:        info.About.DisplayName,
+			Title:       info.About.Title,
+			Description: info.About.PublicDescription,
+			Subscribers: info.About.Subscribers,
+		}
+	}
+	return out
+}
+
+func newCommentsOutput(result *commentsResponse, opts formatOptions) *commentsOutput {
+	out := &commentsOutput{View: opts.view, Comments: make([]commentOutput, 0, len(result.Comments.Children))}
+	if len(result.Post.Children) > 0 {
+		out.PostScore, out.UpvoteRatio = result.Post.Children[0].Data.Score, result.Post.Children[0].Data.UpvoteRatio
+	}
+	appendComments(out, &result.Comments, "", 1, opts.depth)
+	if opts.author != "" {
+		out.Comments = slices.DeleteFunc(out.Comments, func(c commentOutput) bool {
+			return !strings.EqualFold(c.Author, opts.author)
+		})
+	}
+	if opts.view == "flat" {
+		slices.SortStableFunc(out.Comments, func(a, b commentOutput) int {
+			return b.Score - a.Score
+		})
+	}
+	return out
+}
+
+// Append the comments of replies and their own replies, depth first
+func appendComments(out *commentsOutput, replies *listing, parentID string, depth, maxDepth int) {
+	if replies == nil || depth > maxDepth {
+		return
+	}
+	for i := range replies.Children {
+		if replies.Children[i].Kind == "more" {
+			continue
+		}
+		comment := &replies.Children[i].Data
+		c := commentOutput{ID: comment.ID, ParentID: parentID, Depth: depth, Author: comment.Author, Body: comment.Body, Score: comment.Score, ScoreHidden: comment.ScoreHidden, Controversial: comment.Controversiality > 0, Awards: comment.TotalAwardsReceived,
+			Flair: comment.AuthorFlairText, Distinguished: comment.Distinguished, IsOP: comment.IsSubmitter, Removed: comment.Removal, Recovered: comment.Recovered}
+		c.CreatedUTC, c.Created = createdTimes(comment.CreatedUTC)
+		c.EditedUTC = int64(comment.Edited)
+		if comment.Permalink != "" {
+			c.Permalink = permalinkURL(comment.Permalink)
+		}
+		out.Comments = append(out.Comments, c)
+		appendComments(out, comment.Replies, comment.ID, depth+1, maxDepth)
+	}
+}
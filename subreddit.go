@@ -0,0 +1,210 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Subreddit metadata rarely changes within a conversation
+const (
+	subredditInfoCapacity = 128
+	subredditInfoTTL      = time.Hour
+)
+
+// About data of a subreddit (the t5 object)
+type subredditAbout struct {
+	DisplayName       string  `json:"display_name"`
+	Title             string  `json:"title"`
+	PublicDescription string  `json:"public_description"`
+	Subscribers       int     `json:"subscribers"`
+	ActiveUsers       int     `json:"active_user_count"`
+	Over18            bool    `json:"over18"`
+	SubmissionType    string  `json:"submission_type"`
+	CreatedUTC        float64 `json:"created_utc"`
+	ScoreHideMins     int     `json:"comment_score_hide_mins"` // how long new comments' scores stay hidden
+}
+
+// Text fields arrive HTML-escaped, like those of posts
+func (a *subredditAbout) UnmarshalJSON(b []byte) error {
+	type plain subredditAbout
+	if err := json.Unmarshal(b, (*plain)(a)); err != nil {
+		return err
+	}
+	a.Title = html.UnescapeString(a.Title)
+	a.PublicDescription = html.UnescapeString(a.PublicDescription)
+	return nil
+}
+
+type subredditRule struct {
+	ShortName   string `json:"short_name"`
+	Description string `json:"description"`
+	Kind        string `json:"kind"`
+}
+
+type flairTemplate struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+// Subset of the post requirements submission validation cares about
+type postRequirements struct {
+	TitleRequiredStrings  []string `json:"title_required_strings"`
+	TitleBlacklistedWords []string `json:"title_blacklisted_words"`
+	BodyRestrictionPolicy string   `json:"body_restriction_policy"`
+	TitleTextMinLength    int      `json:"title_text_min_length"`
+	TitleTextMaxLength    int      `json:"title_text_max_length"`
+	IsFlairRequired       bool     `json:"is_flair_required"`
+}
+
+// Everything known about a subreddit; flairs and requirements need an OAuth account
+type subredditInfo struct {
+	About        subredditAbout
+	Rules        []subredditRule
+	Flairs       []flairTemplate
+	Requirements *postRequirements
+}
+
+type subredditInfoEntry struct {
+	name    string
+	info    *subredditInfo
+	fetched time.Time
+}
+
+// LRU of subreddit metadata keyed by lowercase subreddit name, prefixed with
+// the token fingerprint of sessions reading Reddit with their own token
+type subredditInfoCache struct {
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+var subredditInfos = &subredditInfoCache{order: list.New(), entries: make(map[string]*list.Element)}
+
+func (c *subredditInfoCache) get(name string) (*subredditInfo, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[name]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*subredditInfoEntry)
+	if time.Since(entry.fetched) > subredditInfoTTL {
+		c.order.Remove(elem)
+		delete(c.entries, name)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.info, true
+}
+
+func (c *subredditInfoCache) put(name string, info *subredditInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[name]; ok {
+		elem.Value = &subredditInfoEntry{name: name, info: info, fetched: time.Now()}
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[name] = c.order.PushFront(&subredditInfoEntry{name: name, info: info, fetched: time.Now()})
+	for c.order.Len() > subredditInfoCapacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*subredditInfoEntry).name)
+	}
+}
+
+// Normalize a subreddit name into its cache key
+func subredditKey(name string) string {
+	return strings.ToLower(strings.TrimPrefix(name, "r/"))
+}
+
+// Return metadata for subreddit, from the LRU when possible
+func lookupSubreddit(ctx context.Context, subreddit string) (*subredditInfo, error) {
+	name := subredditKey(subreddit)
+	key := sessionCacheScope(ctx) + name
+	if info, ok := subredditInfos.get(key); ok && !isCacheRefresh(ctx) {
+		return info, nil
+	}
+
+	info, err := fetchSubredditInfo(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	subredditInfos.put(key, info)
+	return info, nil
+}
+
+// Fetch about data and rules, plus flairs and requirements when authenticated
+func fetchSubredditInfo(ctx context.Context, subreddit string) (*subredditInfo, error) {
+	info := &subredditInfo{}
+
+	var about struct {
+		Kind string         `json:"kind"`
+		Data subredditAbout `json:"data"`
+	}
+	if err := makeRedditRequest(ctx, subredditAboutEndpoint(subreddit), nil, &about); err != nil {
+		return nil, err
+	}
+	if about.Kind != "t5" {
+		return nil, fmt.Errorf("%w: subreddit r/%s", errNotFound, subreddit)
+	}
+	info.About = about.Data
+
+	// The remaining requests are best-effort, so a cancellation would otherwise
+	// go unnoticed and leave incomplete metadata in the cache
+	var rules struct {
+		Rules []subredditRule `json:"rules"`
+	}
+	if err := makeRedditRequest(ctx, fmt.Sprintf("/r/%s/about/rules.json", subreddit), nil, &rules); err == nil {
+		info.Rules = rules.Rules
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// These endpoints only exist on the OAuth API; they're best-effort extras
+	if currentConfig().Reddit.configured() {
+		var flairs []flairTemplate
+		if err := makeRedditRequest(ctx, fmt.Sprintf("/r/%s/api/link_flair_v2", subreddit), nil, &flairs); err == nil {
+			info.Flairs = flairs
+		}
+		var requirements postRequirements
+		if err := makeRedditRequest(ctx, fmt.Sprintf("/api/v1/%s/post_requirements", subreddit), nil, &requirements); err == nil {
+			info.Requirements = &requirements
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return info, nil
+}
+
+// Endpoint for a subreddit's about data
+func subredditAboutEndpoint(subreddit string) string {
+	return fmt.Sprintf("/r/%s/about.json", strings.TrimPrefix(subreddit, "r/"))
+}
+
+// One-paragraph header describing a subreddit for listing output
+func formatSubredditHeader(info *subredditInfo) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "r/%s", info.About.DisplayName)
+	if info.About.Title != "" {
+		fmt.Fprintf(&sb, ": %s", info.About.Title)
+	}
+	fmt.Fprintf(&sb, " (%d subscribers)\n", info.About.Subscribers)
+	if info.About.PublicDescription != "" {
+		fmt.Fprintf(&sb, "%s\n", info.About.PublicDescription)
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
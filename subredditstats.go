@@ -0,0 +1,274 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// Latest posts sampled by default, and at most
+	defaultStatsSample = 100
+	maxStatsSample     = 1000
+	// Contributors and flairs listed in the stats
+	maxStatsContributors = 10
+	maxStatsFlairs       = 15
+)
+
+type scoreDistribution struct {
+	Min    int     `json:"min"`
+	P25    int     `json:"p25"`
+	Median int     `json:"median"`
+	P75    int     `json:"p75"`
+	P90    int     `json:"p90"`
+	Max    int     `json:"max"`
+	Mean   float64 `json:"mean"`
+}
+
+type commentCounts struct {
+	Median      int     `json:"median"`
+	P90         int     `json:"p90"`
+	Mean        float64 `json:"mean"`
+	Uncommented float64 `json:"uncommented" jsonschema:"Share of posts without a single comment, from 0 to 1"`
+}
+
+type contributor struct {
+	Author       string  `json:"author" jsonschema:"Username without the u/ prefix"`
+	Posts        int     `json:"posts"`
+	AverageScore float64 `json:"average_score"`
+}
+
+type flairCount struct {
+	Flair        string  `json:"flair" jsonschema:"Post flair; empty for posts without one"`
+	Posts        int     `json:"posts"`
+	AverageScore float64 `json:"average_score"`
+}
+
+// Output of reddit_subreddit_stats
+type subredditStatsOutput struct {
+	Subreddit    string            `json:"subreddit"`
+	Subscribers  int               `json:"subscribers,omitempty"`
+	ActiveUsers  int               `json:"active_users,omitempty" jsonschema:"Users Reddit counts as online now"`
+	Posts        int               `json:"posts" jsonschema:"Latest posts sampled"`
+	Oldest       string            `json:"oldest,omitempty" jsonschema:"Creation time of the oldest sampled post, in RFC 3339 format"`
+	Complete     bool              `json:"complete" jsonschema:"The sample holds every post Reddit lists for the subreddit"`
+	PostsPerDay  float64           `json:"posts_per_day" jsonschema:"Posts a day from the oldest sampled post until now"`
+	Timezone     string            `json:"timezone" jsonschema:"Timezone the weekdays are counted in"`
+	ByWeekday    []weekdayCount    `json:"by_weekday" jsonschema:"Sampled posts per day of the week, from Monday"`
+	Scores       scoreDistribution `json:"scores"`
+	Comments     commentCounts     `json:"comments" jsonschema:"Comments per post"`
+	Authors      int               `json:"authors" jsonschema:"Distinct authors of the sampled posts"`
+	Contributors []contributor     `json:"contributors" jsonschema:"Most frequent posters, most posts first"`
+	Flairs       []flairCount      `json:"flairs" jsonschema:"Posts per flair, most used first"`
+}
+
+// Describe how active a community is from its latest posts
+func handleRedditSubredditStats(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	opts, err := newFormatOptions(args)
+	if err != nil {
+		return toolError(err), nil
+	}
+	subreddit, _ := args["subreddit"].(string)
+	subreddit = subredditKey(subreddit)
+	if subreddit == "" {
+		return toolError(invalidInput("subreddit is required")), nil
+	}
+	sample := defaultStatsSample
+	if n, ok := args["limit"].(float64); ok {
+		sample = min(max(int(n), 1), maxStatsSample)
+	}
+
+	// Subscriber counts are a nice-to-have, but a missing subreddit ends it here
+	info, err := lookupSubreddit(ctx, subreddit)
+	if errors.Is(err, errNotFound) || ctx.Err() != nil {
+		return toolError(err), nil
+	}
+	endpoint, params := subredditListingRequest(subreddit, "new", exportPageSize)
+	recent, _, err := pageListing(ctx, endpoint, params, sample, "posts", nil)
+	if err != nil {
+		return toolError(err), nil
+	}
+	out := subredditStats(subreddit, &recent, opts)
+	if info != nil {
+		out.Subscribers = info.About.Subscribers
+		out.ActiveUsers = info.About.ActiveUsers
+	}
+	return newStructuredResult(out, formatSubredditStats(out, opts)), nil
+}
+
+func subredditStats(subreddit string, recent *listing, opts formatOptions) *subredditStatsOutput {
+	out := &subredditStatsOutput{
+		Subreddit:    subreddit,
+		Posts:        len(recent.Children),
+		Complete:     recent.After == "",
+		Timezone:     opts.loc.String(),
+		ByWeekday:    make([]weekdayCount, 7),
+		Contributors: []contributor{},
+		Flairs:       []flairCount{},
+	}
+	for i := range out.ByWeekday {
+		out.ByWeekday[i].Day = time.Weekday((i + 1) % 7).String()
+	}
+	if out.Posts == 0 {
+		return out
+	}
+
+	var scores, comments []int
+	authors := map[string]*contributor{}
+	flairs := map[string]*flairCount{}
+	var oldest float64
+	uncommented := 0
+	for _, child := range recent.Children {
+		post := &child.Data
+		scores = append(scores, post.Score)
+		comments = append(comments, post.NumComments)
+		if post.NumComments == 0 {
+			uncommented++
+		}
+		if post.CreatedUTC > 0 {
+			created := time.Unix(int64(post.CreatedUTC), 0).In(opts.loc)
+			out.ByWeekday[(int(created.Weekday())+6)%7].Count++
+			if oldest == 0 || post.CreatedUTC < oldest {
+				oldest = post.CreatedUTC
+			}
+		}
+		// Deleted accounts aren't one contributor
+		if post.Author != "" && post.Author != "[deleted]" {
+			c := authors[post.Author]
+			if c == nil {
+				c = &contributor{Author: post.Author}
+				authors[post.Author] = c
+			}
+			c.Posts++
+			c.AverageScore += float64(post.Score)
+		}
+		f := flairs[post.LinkFlairText]
+		if f == nil {
+			f = &flairCount{Flair: post.LinkFlairText}
+			flairs[post.LinkFlairText] = f
+		}
+		f.Posts++
+		f.AverageScore += float64(post.Score)
+	}
+
+	if oldest > 0 {
+		_, out.Oldest = createdTimes(oldest)
+		// A day at least, so a burst of posts in the last hour isn't read as a rate
+		days := max(time.Since(time.Unix(int64(oldest), 0)).Hours()/24, 1)
+		out.PostsPerDay = round1(float64(out.Posts) / days)
+	}
+	slices.Sort(scores)
+	slices.Sort(comments)
+	out.Scores = scoreDistribution{
+		Min:    scores[0],
+		P25:    percentile(scores, 25),
+		Median: percentile(scores, 50),
+		P75:    percentile(scores, 75),
+		P90:    percentile(scores, 90),
+		Max:    scores[len(scores)-1],
+		Mean:   round1(mean(scores)),
+	}
+	out.Comments = commentCounts{
+		Median:      percentile(comments, 50),
+		P90:         percentile(comments, 90),
+		Mean:        round1(mean(comments)),
+		Uncommented: math.Round(float64(uncommented)/float64(out.Posts)*100) / 100,
+	}
+
+	out.Authors = len(authors)
+	for _, c := range authors {
+		c.AverageScore = round1(c.AverageScore / float64(c.Posts))
+	}
+	ranked := slices.SortedFunc(maps.Values(authors), func(a, b *contributor) int {
+		return cmp.Or(cmp.Compare(b.Posts, a.Posts), strings.Compare(a.Author, b.Author))
+	})
+	for _, c := range ranked[:min(maxStatsContributors, len(ranked))] {
+		out.Contributors = append(out.Contributors, *c)
+	}
+	for _, f := range flairs {
+		f.AverageScore = round1(f.AverageScore / float64(f.Posts))
+	}
+	byUse := slices.SortedFunc(maps.Values(flairs), func(a, b *flairCount) int {
+		return cmp.Or(cmp.Compare(b.Posts, a.Posts), strings.Compare(a.Flair, b.Flair))
+	})
+	for _, f := range byUse[:min(maxStatsFlairs, len(byUse))] {
+		out.Flairs = append(out.Flairs, *f)
+	}
+	return out
+}
+
+// The nearest-rank percentile p of sorted values
+func percentile(sorted []int, p float64) int {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[min(max(rank-1, 0), len(sorted)-1)]
+}
+
+func mean(values []int) float64 {
+	total := 0
+	for _, v := range values {
+		total += v
+	}
+	return float64(total) / float64(len(values))
+}
+
+func round1(x float64) float64 {
+	return math.Round(x*10) / 10
+}
+
+func formatSubredditStats(out *subredditStatsOutput, opts formatOptions) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Stats for r/%s", out.Subreddit)
+	if out.Subscribers > 0 {
+		fmt.Fprintf(&sb, " | %d subscribers", out.Subscribers)
+		if out.ActiveUsers > 0 {
+			fmt.Fprintf(&sb, ", %d online", out.ActiveUsers)
+		}
+	}
+	sb.WriteString("\n")
+	if out.Posts == 0 {
+		sb.WriteString("Reddit lists no posts for this subreddit.\n")
+		return sb.String()
+	}
+	oldest, _ := time.Parse(time.RFC3339, out.Oldest)
+	fmt.Fprintf(&sb, "Sampled the latest %d posts, back to %s", out.Posts, formatUnixTime(oldest.Unix(), opts.loc))
+	if out.Complete {
+		sb.WriteString(", every post Reddit lists")
+	}
+	sb.WriteString("\n\n")
+
+	fmt.Fprintf(&sb, "Activity: %g posts a day from %d authors\n", out.PostsPerDay, out.Authors)
+	sb.WriteString("By weekday:")
+	for _, day := range out.ByWeekday {
+		fmt.Fprintf(&sb, " %s %d", day.Day[:3], day.Count)
+	}
+	sb.WriteString("\n")
+	s := out.Scores
+	fmt.Fprintf(&sb, "Scores: median %d, middle half %d to %d, top tenth above %d, mean %g, range %d to %d\n", s.Median, s.P25, s.P75, s.P90, s.Mean, s.Min, s.Max)
+	c := out.Comments
+	fmt.Fprintf(&sb, "Comments per post: median %d, top tenth above %d, mean %g; %.0f%% of posts have none\n", c.Median, c.P90, c.Mean, c.Uncommented*100)
+
+	if len(out.Contributors) > 0 {
+		sb.WriteString("\nTop contributors:\n")
+		for _, c := range out.Contributors {
+			fmt.Fprintf(&sb, "- %s: %d posts, average score %g\n", userLabel(c.Author), c.Posts, c.AverageScore)
+		}
+	}
+	sb.WriteString("\nFlairs:\n")
+	for _, f := range out.Flairs {
+		name := f.Flair
+		if name == "" {
+			name = "(no flair)"
+		}
+		fmt.Fprintf(&sb, "- %s: %d posts (%.0f%%), average score %g\n", name, f.Posts, float64(f.Posts)/float64(out.Posts)*100, f.AverageScore)
+	}
+	return sb.String()
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// A subscribed subreddit feed and the sessions watching it
+type feedSubscription struct {
+	subreddit string
+	sort      string
+	limit     int
+	sessions  map[string]struct{}
+	seen      map[string]struct{} // post IDs of the last poll; nil until the first one
+}
+
+// Feed resources subscribed to by each session, keyed by resource URI
+type subscriptionRegistry struct {
+	mu    sync.Mutex
+	feeds map[string]*feedSubscription
+}
+
+var feedSubscriptions = &subscriptionRegistry{feeds: make(map[string]*feedSubscription)}
+
+// Track resources/subscribe and resources/unsubscribe for feed resources
+func addSubscriptionHooks(hooks *server.Hooks) {
+	hooks.AddAfterSubscribe(func(ctx context.Context, id any, message *mcp.SubscribeRequest, result *mcp.EmptyResult) {
+		if session := server.ClientSessionFromContext(ctx); session != nil {
+			feedSubscriptions.subscribe(session.SessionID(), message.Params.URI)
+		}
+	})
+	hooks.AddAfterUnsubscribe(func(ctx context.Context, id any, message *mcp.UnsubscribeRequest, result *mcp.EmptyResult) {
+		if session := server.ClientSessionFromContext(ctx); session != nil {
+			feedSubscriptions.unsubscribe(session.SessionID(), message.Params.URI)
+		}
+	})
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		feedSubscriptions.dropSession(session.SessionID())
+	})
+}
+
+// Parse a subreddit feed URI (reddit://r/{subreddit}/{sort}{?limit})
+func parseFeedURI(uri string) (subreddit, sort string, limit int, ok bool) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "reddit" || u.Host != "r" {
+		return "", "", 0, false
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) != 2 || parts[0] == "" {
+		return "", "", 0, false
+	}
+	switch parts[1] {
+	case "hot", "new", "top", "rising":
+	default:
+		return "", "", 0, false
+	}
+
+	limit = defaultListingLimit
+	if raw := u.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > 100 {
+			return "", "", 0, false
+		}
+		limit = n
+	}
+	return subredditKey(parts[0]), parts[1], limit, true
+}
+
+// Record that session wants updates for uri; URIs other than feeds are ignored
+func (r *subscriptionRegistry) subscribe(sessionID, uri string) {
+	subreddit, sort, limit, ok := parseFeedURI(uri)
+	if !ok {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	feed, ok := r.feeds[uri]
+	if !ok {
+		feed = &feedSubscription{subreddit: subreddit, sort: sort, limit: limit, sessions: make(map[string]struct{})}
+		r.feeds[uri] = feed
+	}
+	feed.sessions[sessionID] = struct{}{}
+}
+
+func (r *subscriptionRegistry) unsubscribe(sessionID, uri string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if feed, ok := r.feeds[uri]; ok {
+		delete(feed.sessions, sessionID)
+		if len(feed.sessions) == 0 {
+			delete(r.feeds, uri)
+		}
+	}
+}
+
+// Forget every subscription of a disconnected session
+func (r *subscriptionRegistry) dropSession(sessionID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for uri, feed := range r.feeds {
+		delete(feed.sessions, sessionID)
+		if len(feed.sessions) == 0 {
+			delete(r.feeds, uri)
+		}
+	}
+}
+
+// URIs with at least one subscriber
+func (r *subscriptionRegistry) uris() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	uris := make([]string, 0, len(r.feeds))
+	for uri := range r.feeds {
+		uris = append(uris, uri)
+	}
+	return uris
+}
+
+// Poll subscribed feeds and notify their subscribers when new posts appear.
+// The interval is read from the live config on every pass; 0 pauses polling.
+func pollSubscriptions(ctx context.Context, s *server.MCPServer) {
+	for {
+		interval := time.Duration(currentConfig().SubscriptionPoll) * time.Second
+		if interval > 0 {
+			for _, uri := range feedSubscriptions.uris() {
+				if ctx.Err() != nil {
+					return
+				}
+				feedSubscriptions.poll(withCacheRefresh(ctx), s, uri)
+			}
+		} else {
+			interval = time.Minute
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Fetch one feed and send notifications/resources/updated if it has posts
+// that weren't there on the previous poll
+func (r *subscriptionRegistry) poll(ctx context.Context, s *server.MCPServer, uri string) {
+	r.mu.Lock()
+	feed, ok := r.feeds[uri]
+	r.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	var result listing
+	endpoint, params := subredditListingRequest(feed.subreddit, feed.sort, feed.limit)
+	if err := makeRedditRequest(ctx, endpoint, params, &result); err != nil {
+		slog.Warn("Failed to poll subscribed feed", "uri", uri, "error", err)
+		return
+	}
+
+	seen := make(map[string]struct{}, len(result.Children))
+	for _, child := range result.Children {
+		seen[child.Data.ID] = struct{}{}
+	}
+
+	r.mu.Lock()
+	// The last subscriber may have left while the request was in flight
+	if r.feeds[uri] != feed {
+		r.mu.Unlock()
+		return
+	}
+	changed := false
+	if feed.seen != nil {
+		for id := range seen {
+			if _, ok := feed.seen[id]; !ok {
+				changed = true
+				break
+			}
+		}
+	}
+	feed.seen = seen
+	var sessions []string
+	if changed {
+		for sessionID := range feed.sessions {
+			sessions = append(sessions, sessionID)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, sessionID := range sessions {
+		err := s.SendNotificationToSpecificClient(sessionID, mcp.MethodNotificationResourceUpdated, map[string]any{"uri": uri})
+		if err != nil {
+			slog.Debug("Failed to notify subscriber", "uri", uri, "session", sessionID, "error", err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Sampling requests made for one oversized thread, and the tokens asked for each digest
+const (
+	maxSummaryChunks    = 8
+	summaryChunkTokens  = 600
+	summarySystemPrompt = "You summarize parts of Reddit comment threads for another assistant. " +
+		"Keep the main viewpoints, notable facts, links and advice, and name the usernames making key points. " +
+		"Answer with the summary only."
+)
+
+// Whether the session handling ctx lets the server sample its model
+func clientSupportsSampling(ctx context.Context) bool {
+	session, ok := server.ClientSessionFromContext(ctx).(server.SessionWithClientInfo)
+	return ok && session.GetClientCapabilities().Sampling != nil
+}
+
+// When -summarize-oversized is on and text is over the output budget, ask the
+// client's model to summarize it chunk by chunk and return the joined digests.
+// Returns false when summarizing isn't possible, so the caller truncates instead.
+func summarizeOversized(ctx context.Context, title, text string) (string, bool) {
+	cfg := currentConfig()
+	max := cfg.MaxOutputChars
+	if !cfg.SummarizeOversized || max <= 0 || len(text) <= max || !clientSupportsSampling(ctx) {
+		return "", false
+	}
+	s := server.ServerFromContext(ctx)
+	if s == nil {
+		return "", false
+	}
+
+	chunks := splitChunks(text, max)
+	omitted := 0
+	if len(chunks) > maxSummaryChunks {
+		omitted = len(chunks) - maxSummaryChunks
+		chunks = chunks[:maxSummaryChunks]
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Summary of %s (%d characters, over the %d character output limit), made by the client's model in %d parts:\n\n",
+		title, len(text), max, len(chunks))
+	for i, chunk := range chunks {
+		request := mcp.CreateMessageRequest{CreateMessageParams: mcp.CreateMessageParams{
+			Messages: []mcp.SamplingMessage{{
+				Role:    mcp.RoleUser,
+				Content: mcp.NewTextContent(fmt.Sprintf("Part %d of %d of %s:\n\n%s", i+1, len(chunks), title, chunk)),
+			}},
+			SystemPrompt: summarySystemPrompt,
+			MaxTokens:    summaryChunkTokens,
+		}}
+		result, err := s.RequestSampling(ctx, request)
+		if err != nil {
+			slog.WarnContext(ctx, "Sampling failed, truncating instead", "error", err)
+			return "", false
+		}
+		digest, ok := samplingText(result)
+		if !ok {
+			slog.WarnContext(ctx, "Sampling returned no text, truncating instead", "model", result.Model)
+			return "", false
+		}
+
+		fmt.Fprintf(&sb, "Part %d:\n%s\n\n", i+1, strings.TrimSpace(digest))
+		reportProgress(ctx, float64(i+1), float64(len(chunks)), fmt.Sprintf("Summarized part %d of %d", i+1, len(chunks)))
+	}
+	if omitted > 0 {
+		fmt.Fprintf(&sb, "[%d further parts were not summarized. Request fewer comments to cover them.]\n", omitted)
+	}
+
+	return sb.String(), true
+}
+
+// Split text into chunks of at most size bytes, preferring line boundaries
+func splitChunks(text string, size int) []string {
+	var chunks []string
+	for len(text) > size {
+		chunk := truncateAt(text, size)
+		chunks = append(chunks, chunk)
+		text = text[len(chunk):]
+	}
+	if text != "" {
+		chunks = append(chunks, text)
+	}
+	return chunks
+}
+
+// Text of a sampling result; clients may answer with content of other kinds
+func samplingText(result *mcp.CreateMessageResult) (string, bool) {
+	switch content := result.Content.(type) {
+	case mcp.TextContent:
+		return content.Text, content.Text != ""
+	case *mcp.TextContent:
+		return content.Text, content.Text != ""
+	case map[string]any:
+		text, _ := content["text"].(string)
+		return text, text != ""
+	}
+	return "", false
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"log/slog"
+	"regexp"
+	"slices"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Tools are defined under base names ("search") and advertised under the
+// name the config gives them: -tool-prefix plus the base name unless
+// -tool-name renames it, so several servers can be aggregated without their
+// generic tool names colliding
+func toolName(cfg *config, base string) string {
+	if name := cfg.ToolNames[base]; name != "" {
+		return name
+	}
+	return cfg.ToolPrefix + base
+}
+
+// Characters clients reliably accept in tool names
+var toolNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+func validToolName(name string) bool {
+	return toolNamePattern.MatchString(name)
+}
+
+// The tools registered on a server, keyed by base name, with the name each is
+// currently advertised under
+type toolRegistry struct {
+	mu         sync.Mutex
+	tools      []server.ServerTool
+	advertised map[string]string
+}
+
+// Register the tools the live config allows, re-syncing the advertised set on
+// reload; mcp-go sends tools/list_changed whenever it changes
+func registerTools(s *server.MCPServer, tools ...server.ServerTool) {
+	for i := range tools {
+		allowTruncated(&tools[i].Tool)
+	}
+	r := &toolRegistry{tools: tools, advertised: make(map[string]string)}
+	r.sync(s, currentConfig())
+	onReload(func(old, cfg *config) {
+		r.sync(s, cfg)
+	})
+}
+
+// Whether cfg allows the tool; an empty enabled list allows every tool not
+// disabled. Tools can be listed by base or advertised name.
+func toolEnabled(cfg *config, base string) bool {
+	listed := func(names []string) bool {
+		return slices.Contains(names, base) || slices.Contains(names, toolName(cfg, base))
+	}
+	if len(cfg.EnabledTools) > 0 && !listed(cfg.EnabledTools) {
+		return false
+	}
+	return !listed(cfg.DisabledTools)
+}
+
+// Add newly enabled or renamed tools and drop the ones no longer allowed
+func (r *toolRegistry) sync(s *server.MCPServer, cfg *config) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var added []server.ServerTool
+	var removed []string
+	taken := make(map[string]string)
+	for _, tool := range r.tools {
+		base := tool.Tool.Name
+		name := ""
+		if toolEnabled(cfg, base) {
+			name = toolName(cfg, base)
+			if other, ok := taken[name]; ok {
+				slog.Warn("Tool name already in use, not offering tool", "tool", base, "name", name, "used_by", other)
+				name = ""
+			} else {
+				taken[name] = base
+			}
+		}
+
+		current := r.advertised[base]
+		if name == current {
+			continue
+		}
+		if current != "" {
+			removed = append(removed, current)
+			delete(r.advertised, base)
+		}
+		if name != "" {
+			renamed := tool
+			renamed.Tool.Name = name
+			added = append(added, renamed)
+			r.advertised[base] = name
+		}
+	}
+	if len(removed) > 0 {
+		s.DeleteTools(removed...)
+	}
+	if len(added) > 0 {
+		s.AddTools(added...)
+	}
+
+	// Typos would otherwise silently hide every tool or none
+	known := func(name string) bool {
+		return slices.ContainsFunc(r.tools, func(tool server.ServerTool) bool {
+			return tool.Tool.Name == name || toolName(cfg, tool.Tool.Name) == name
+		})
+	}
+	for _, name := range slices.Concat(cfg.EnabledTools, cfg.DisabledTools) {
+		if !known(name) {
+			slog.Warn("Unknown tool in config", "tool", name)
+		}
+	}
+	for base := range cfg.ToolNames {
+		if !slices.ContainsFunc(r.tools, func(tool server.ServerTool) bool { return tool.Tool.Name == base }) {
+			slog.Warn("Unknown tool renamed in config", "tool", base)
+		}
+	}
+}
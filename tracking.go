@@ -0,0 +1,394 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// Posts tracked at once, finished ones included until they're dropped
+	maxTrackedPosts = 50
+	// Hours a post is tracked by default, and at most
+	defaultTrackHours = 24
+	maxTrackHours     = 7 * 24
+	// How long a finished post's snapshots are kept for reports
+	trackKeepFor = 7 * 24 * time.Hour
+	// Snapshots kept per post; the oldest are dropped first
+	maxPostSnapshots = 2500
+	// Snapshots shown in a post's report, spread evenly over the tracked time
+	trackReportPoints = 12
+	// Fullnames /api/info accepts per request
+	infoBatchSize = 100
+)
+
+// A post's standing at one moment
+type postSnapshot struct {
+	at          time.Time
+	score       int
+	comments    int
+	awards      int
+	upvoteRatio float64
+}
+
+// A post being tracked and the snapshots taken of it so far
+type trackedPost struct {
+	id, title, subreddit, permalink string
+	removal                         string
+	started, until                  time.Time
+	snapshots                       []postSnapshot
+}
+
+func (p *trackedPost) active(now time.Time) bool {
+	return now.Before(p.until)
+}
+
+func (p *trackedPost) record(post *item, at time.Time) {
+	p.title, p.removal = post.Title, post.Removal
+	p.snapshots = append(p.snapshots, postSnapshot{
+		at:          at,
+		score:       post.Score,
+		comments:    post.NumComments,
+		awards:      post.TotalAwardsReceived,
+		upvoteRatio: post.UpvoteRatio,
+	})
+	if extra := len(p.snapshots) - maxPostSnapshots; extra > 0 {
+		p.snapshots = slices.Delete(p.snapshots, 0, extra)
+	}
+}
+
+// Tracked posts by ID
+type trackRegistry struct {
+	mu    sync.Mutex
+	posts map[string]*trackedPost
+}
+
+var trackedPosts = &trackRegistry{posts: make(map[string]*trackedPost)}
+
+// Start tracking post with its first snapshot, or extend the tracking of a
+// post already tracked
+func (r *trackRegistry) add(post *item, hours int) (trackedPost, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	until := now.Add(time.Duration(hours) * time.Hour)
+	p, ok := r.posts[post.ID]
+	if !ok {
+		if len(r.posts) >= maxTrackedPosts {
+			return trackedPost{}, invalidInput("at most %d posts can be tracked; stop one first", maxTrackedPosts)
+		}
+		p = &trackedPost{id: post.ID, subreddit: post.Subreddit, permalink: permalinkURL(post.Permalink), started: now}
+		r.posts[post.ID] = p
+	}
+	if until.After(p.until) {
+		p.until = until
+	}
+	p.record(post, now)
+	return p.copy(), nil
+}
+
+func (r *trackRegistry) remove(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.posts[id]; !ok {
+		return invalidInput("post %s isn't tracked; see track_report for the tracked posts", id)
+	}
+	delete(r.posts, id)
+	return nil
+}
+
+// Copies of one tracked post, or of all with id "", safe to read without the
+// lock, most recently started first
+func (r *trackRegistry) list(id string) ([]trackedPost, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.posts[id]; id != "" && !ok {
+		return nil, invalidInput("post %s isn't tracked; start with track_post", id)
+	}
+	var list []trackedPost
+	for _, p := range r.posts {
+		if id == "" || p.id == id {
+			list = append(list, p.copy())
+		}
+	}
+	slices.SortFunc(list, func(a, b trackedPost) int { return b.started.Compare(a.started) })
+	return list, nil
+}
+
+func (p *trackedPost) copy() trackedPost {
+	c := *p
+	c.snapshots = slices.Clone(p.snapshots)
+	return c
+}
+
+// IDs of the posts still being tracked, dropping finished posts kept long enough
+func (r *trackRegistry) due(now time.Time) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var ids []string
+	for id, p := range r.posts {
+		switch {
+		case p.active(now):
+			ids = append(ids, id)
+		case now.Sub(p.until) > trackKeepFor:
+			delete(r.posts, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// Record a snapshot of each post fetched
+func (r *trackRegistry) record(posts []thing, at time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range posts {
+		post := &posts[i].Data
+		if p, ok := r.posts[post.ID]; ok && p.active(at) {
+			p.record(post, at)
+		}
+	}
+}
+
+// Snapshot tracked posts on an interval read from the live config on every
+// pass; 0 pauses tracking
+func pollTrackedPosts(ctx context.Context) {
+	for {
+		interval := time.Duration(currentConfig().TrackPoll) * time.Second
+		if interval > 0 {
+			ids := trackedPosts.due(time.Now())
+			for batch := range slices.Chunk(ids, infoBatchSize) {
+				if ctx.Err() != nil {
+					return
+				}
+				var result listing
+				if err := fetchPostInfo(withCacheRefresh(ctx), batch, &result); err != nil {
+					slog.Warn("Failed to snapshot tracked posts", "posts", len(batch), "error", err)
+					continue
+				}
+				trackedPosts.record(result.Children, time.Now())
+			}
+		} else {
+			interval = time.Minute
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Fetch several posts by ID in one request
+func fetchPostInfo(ctx context.Context, ids []string, result *listing) error {
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		names[i] = "t3_" + strings.TrimPrefix(id, "t3_")
+	}
+	return makeRedditRequest(ctx, "/api/info.json", url.Values{"id": []string{strings.Join(names, ",")}}, result)
+}
+
+type snapshotOutput struct {
+	Time        string  `json:"time" jsonschema:"When the snapshot was taken, in RFC 3339 format"`
+	Score       int     `json:"score"`
+	Comments    int     `json:"comments"`
+	Awards      int     `json:"awards"`
+	UpvoteRatio float64 `json:"upvote_ratio"`
+}
+
+type trackedPostOutput struct {
+	ID              string           `json:"id"`
+	Title           string           `json:"title"`
+	Subreddit       string           `json:"subreddit"`
+	Permalink       string           `json:"permalink"`
+	Removed         string           `json:"removed,omitempty" jsonschema:"Why the post is gone, if it has been taken down since tracking started"`
+	Started         string           `json:"started" jsonschema:"When tracking started, in RFC 3339 format"`
+	Until           string           `json:"until" jsonschema:"When tracking stops, or stopped, in RFC 3339 format"`
+	Active          bool             `json:"active" jsonschema:"Whether snapshots are still being taken"`
+	Snapshots       int              `json:"snapshots"`
+	Latest          snapshotOutput   `json:"latest"`
+	ScoreChange     int              `json:"score_change" jsonschema:"Since the first snapshot"`
+	CommentChange   int              `json:"comment_change" jsonschema:"Since the first snapshot"`
+	AwardChange     int              `json:"award_change" jsonschema:"Since the first snapshot"`
+	ScorePerHour    float64          `json:"score_per_hour" jsonschema:"Average gain since the first snapshot"`
+	CommentsPerHour float64          `json:"comments_per_hour" jsonschema:"Average gain since the first snapshot"`
+	Series          []snapshotOutput `json:"series,omitempty" jsonschema:"Snapshots spread evenly over the tracked time, oldest first; only when reporting on one post"`
+}
+
+// Output of reddit_track_report
+type trackReportOutput struct {
+	PollSeconds int                 `json:"poll_seconds" jsonschema:"Seconds between snapshots; 0 while tracking is paused"`
+	Posts       []trackedPostOutput `json:"posts"`
+}
+
+func newSnapshotOutput(s postSnapshot) snapshotOutput {
+	return snapshotOutput{
+		Time:        s.at.UTC().Format(time.RFC3339),
+		Score:       s.score,
+		Comments:    s.comments,
+		Awards:      s.awards,
+		UpvoteRatio: s.upvoteRatio,
+	}
+}
+
+func newTrackedPostOutput(p *trackedPost, withSeries bool) trackedPostOutput {
+	first, last := p.snapshots[0], p.snapshots[len(p.snapshots)-1]
+	out := trackedPostOutput{
+		ID:            p.id,
+		Title:         p.title,
+		Subreddit:     p.subreddit,
+		Permalink:     p.permalink,
+		Removed:       p.removal,
+		Started:       p.started.UTC().Format(time.RFC3339),
+		Until:         p.until.UTC().Format(time.RFC3339),
+		Active:        p.active(time.Now()),
+		Snapshots:     len(p.snapshots),
+		Latest:        newSnapshotOutput(last),
+		ScoreChange:   last.score - first.score,
+		CommentChange: last.comments - first.comments,
+		AwardChange:   last.awards - first.awards,
+	}
+	if hours := last.at.Sub(first.at).Hours(); hours > 0 {
+		out.ScorePerHour = round1(float64(out.ScoreChange) / hours)
+		out.CommentsPerHour = round1(float64(out.CommentChange) / hours)
+	}
+	if withSeries {
+		n := min(len(p.snapshots), trackReportPoints)
+		for i := range n {
+			// Evenly spaced indexes, always taking in the first and the latest
+			j := 0
+			if n > 1 {
+				j = i * (len(p.snapshots) - 1) / (n - 1)
+			}
+			out.Series = append(out.Series, newSnapshotOutput(p.snapshots[j]))
+		}
+	}
+	return out
+}
+
+// Start tracking a post's score, comments and awards
+func handleRedditTrackPost(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	postID, _ := args["post_id"].(string)
+	postID = strings.TrimPrefix(postID, "t3_")
+	if postID == "" {
+		return toolError(invalidInput("post_id is required")), nil
+	}
+	hours := defaultTrackHours
+	if n, ok := args["hours"].(float64); ok {
+		hours = min(max(int(n), 1), maxTrackHours)
+	}
+
+	var result listing
+	if err := fetchPostInfo(withCacheRefresh(ctx), []string{postID}, &result); err != nil {
+		return toolError(err), nil
+	}
+	if len(result.Children) == 0 {
+		return toolError(invalidInput("no post %s on Reddit; check the post ID", postID)), nil
+	}
+	p, err := trackedPosts.add(&result.Children[0].Data, hours)
+	if err != nil {
+		return toolError(err), nil
+	}
+	out := newTrackedPostOutput(&p, false)
+	text := fmt.Sprintf("Tracking %q (r/%s) until %s; it has %d points and %d comments now.\nSnapshots are taken every %s; see how it does with %s {\"post_id\": %q}.\n",
+		p.title, p.subreddit, formatUntil(p.until, defaultFormatOptions().loc), out.Latest.Score, out.Latest.Comments,
+		trackInterval(), toolName(currentConfig(), "track_report"), p.id)
+	return newStructuredResult(out, text), nil
+}
+
+// Report how tracked posts have done since tracking started
+func handleRedditTrackReport(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	opts, err := newFormatOptions(args)
+	if err != nil {
+		return toolError(err), nil
+	}
+	postID, _ := args["post_id"].(string)
+	list, err := trackedPosts.list(strings.TrimPrefix(postID, "t3_"))
+	if err != nil {
+		return toolError(err), nil
+	}
+	out := &trackReportOutput{PollSeconds: max(currentConfig().TrackPoll, 0), Posts: []trackedPostOutput{}}
+	for i := range list {
+		out.Posts = append(out.Posts, newTrackedPostOutput(&list[i], postID != ""))
+	}
+	return newStructuredResult(out, formatTrackReport(out, opts)), nil
+}
+
+// Stop tracking a post and drop its snapshots
+func handleRedditTrackStop(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	postID, _ := request.GetArguments()["post_id"].(string)
+	postID = strings.TrimPrefix(postID, "t3_")
+	if postID == "" {
+		return toolError(invalidInput("post_id is required")), nil
+	}
+	if err := trackedPosts.remove(postID); err != nil {
+		return toolError(err), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Stopped tracking post %s.\n", postID)), nil
+}
+
+func formatTrackReport(out *trackReportOutput, opts formatOptions) string {
+	if len(out.Posts) == 0 {
+		return fmt.Sprintf("No posts are tracked. Start with %s.\n", toolName(currentConfig(), "track_post"))
+	}
+	signed := func(n int) string { return fmt.Sprintf("%+d", n) }
+	at := func(ts string) string {
+		t, _ := time.Parse(time.RFC3339, ts)
+		return formatUnixTime(t.Unix(), opts.loc)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Snapshots every %s.\n\n", trackInterval())
+	for _, p := range out.Posts {
+		fmt.Fprintf(&sb, "%s (r/%s) | Post ID: %s\n", p.Title, p.Subreddit, p.ID)
+		state := "tracking until"
+		if !p.Active {
+			state = "tracked until"
+		}
+		until, _ := time.Parse(time.RFC3339, p.Until)
+		fmt.Fprintf(&sb, "   Since %s, %s %s; %d snapshots\n", at(p.Started), state, formatUntil(until, opts.loc), p.Snapshots)
+		if p.Removed != "" {
+			fmt.Fprintf(&sb, "   Removed since: %s\n", p.Removed)
+		}
+		fmt.Fprintf(&sb, "   Now: %d points (%.0f%% upvoted), %d comments, %d awards\n", p.Latest.Score, p.Latest.UpvoteRatio*100, p.Latest.Comments, p.Latest.Awards)
+		fmt.Fprintf(&sb, "   Change: %s points (%g an hour), %s comments (%g an hour), %s awards\n",
+			signed(p.ScoreChange), p.ScorePerHour, signed(p.CommentChange), p.CommentsPerHour, signed(p.AwardChange))
+		if len(p.Series) > 1 {
+			sb.WriteString("   Over time:\n")
+			for _, s := range p.Series {
+				fmt.Fprintf(&sb, "   %s  %6d points  %5d comments  %d awards\n", at(s.Time), s.Score, s.Comments, s.Awards)
+			}
+		}
+		sb.WriteString(p.Permalink + "\n\n")
+	}
+	return sb.String()
+}
+
+// When tracking ends, e.g. "2024-03-02T10:00:00Z (in 5 hours)", or ended
+func formatUntil(t time.Time, loc *time.Location) string {
+	if span := timeSpan(time.Until(t)); span != "" {
+		return fmt.Sprintf("%s (in %s)", t.In(loc).Format(time.RFC3339), span)
+	}
+	return formatUnixTime(t.Unix(), loc)
+}
+
+// The snapshot interval in words, e.g. "5 minutes"
+func trackInterval() string {
+	d := time.Duration(currentConfig().TrackPoll) * time.Second
+	if d <= 0 {
+		return "never (tracking is paused)"
+	}
+	if span := timeSpan(d); span != "" {
+		return span
+	}
+	return fmt.Sprintf("%d seconds", int(d.Seconds()))
+}
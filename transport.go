@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// A transport exposes the MCP server to clients
+type transport interface {
+	Serve() error
+}
+
+// Serves over standard input/output
+type stdioTransport struct {
+	server *server.MCPServer
+}
+
+func (t *stdioTransport) Serve() error {
+	return server.ServeStdio(t.server)
+}
+
+// Serves an MCP HTTP handler (SSE or streamable HTTP) on a listen address
+type httpTransport struct {
+	addr      string
+	handler   http.Handler
+	tlsConfig *tls.Config
+}
+
+func (t *httpTransport) Serve() error {
+	srv := &http.Server{
+		Addr:      t.addr,
+		Handler:   t.handler,
+		TLSConfig: t.tlsConfig,
+	}
+	if t.tlsConfig != nil {
+		// Certificates are already loaded into the TLS config
+		return srv.ListenAndServeTLS("", "")
+	}
+	return srv.ListenAndServe()
+}
+
+// Build the transport selected in the config
+func newTransport(s *server.MCPServer, cfg *config) (transport, error) {
+	if cfg.Transport == "stdio" {
+		return &stdioTransport{server: s}, nil
+	}
+
+	handler, err := newHTTPHandler(s, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &httpTransport{addr: cfg.Addr, handler: handler, tlsConfig: tlsConfig}, nil
+}
+
+// Everything the network transports serve as one handler with every route
+// under -base-path, so it can be mounted in another mux (or behind a
+// path-routing proxy) next to other MCP servers
+func newHTTPHandler(s *server.MCPServer, cfg *config) (http.Handler, error) {
+	base := basePath(cfg)
+
+	keepAlive := time.Duration(cfg.KeepAlive) * time.Second
+	idleTimeout := time.Duration(cfg.SessionIdleTimeout) * time.Second
+
+	var handler http.Handler
+	switch cfg.Transport {
+	case "sse":
+		path := cfg.Path
+		if path == "" {
+			path = "/"
+		}
+		opts := []server.SSEOption{
+			server.WithStaticBasePath(base + path),
+			server.WithSSEContextFunc(withRequestContext),
+		}
+		if keepAlive > 0 {
+			opts = append(opts, server.WithKeepAliveInterval(keepAlive))
+		}
+		handler = server.NewSSEServer(s, opts...)
+		if idleTimeout > 0 {
+			handler = sseIdleSessions.middleware(handler)
+			go sseIdleSessions.sweep(idleTimeout)
+		}
+	case "http":
+		path := cfg.Path
+		if path == "" {
+			path = "/mcp"
+		}
+		// The heartbeat applies to the GET stream clients open for notifications
+		handler = server.NewStreamableHTTPServer(s,
+			server.WithEndpointPath(base+path),
+			server.WithStateful(true),
+			server.WithHTTPContextFunc(withRequestContext),
+			server.WithHeartbeatInterval(keepAlive),
+			server.WithSessionIdleTTL(idleTimeout),
+		)
+	default:
+		return nil, fmt.Errorf("unknown transport %q", cfg.Transport)
+	}
+
+	// Each layer reads the live config so reloads apply without a restart:
+	// per-client quotas, then authentication (never an open proxy), then CORS
+	handler = newRateLimiter().middleware(handler)
+	handler = requireAuth(handler)
+	handler = withCORS(handler)
+
+	// Health endpoints stay unauthenticated so orchestrators can probe them
+	health := &healthChecker{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+base+"/healthz", health.handleHealthz)
+	mux.HandleFunc("GET "+base+"/readyz", health.handleReadyz)
+	mux.Handle(base+"/", handler)
+
+	// Watch matches as feeds for feed readers, behind the same tokens as MCP;
+	// they're served from memory, so the per-client quota doesn't apply
+	feeds := requireAuth(http.HandlerFunc(handleWatchFeed))
+	mux.Handle("GET "+feedPath(cfg, ""), feeds)
+	mux.Handle("GET "+feedPath(cfg, "{id}"), feeds)
+
+	return mux, nil
+}
+
+// Attach what the request says about its client to a tool call's context: the
+// token it authenticated with and any Reddit token of its own
+func withRequestContext(ctx context.Context, r *http.Request) context.Context {
+	return withSessionRedditToken(withClientToken(ctx, r), r)
+}
+
+// The -base-path prefix of every HTTP route, "" or starting with a slash
+func basePath(cfg *config) string {
+	base := strings.TrimSuffix(cfg.BasePath, "/")
+	if base != "" && !strings.HasPrefix(base, "/") {
+		base = "/" + base
+	}
+	return base
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	maxTrendSubreddits = 5
+	// Days a trend covers by default, and at most
+	defaultTrendDays = 30
+	maxTrendDays     = 365
+	// Search results read per subreddit; Reddit's search stops around 1000
+	maxTrendPosts = 1000
+	// Mentions a window needs before a trend is called
+	minTrendMentions = 5
+)
+
+type trendBucket struct {
+	Start        string  `json:"start" jsonschema:"First day of the bucket, YYYY-MM-DD in the timezone argument"`
+	Mentions     int     `json:"mentions" jsonschema:"Posts mentioning the keyword"`
+	AverageScore float64 `json:"average_score"`
+	Comments     int     `json:"comments" jsonschema:"Comments on those posts"`
+}
+
+// Output of reddit_keyword_trend
+type keywordTrendOutput struct {
+	Keyword    string        `json:"keyword"`
+	Subreddits []string      `json:"subreddits"`
+	Days       int           `json:"days"`
+	Bucket     string        `json:"bucket" jsonschema:"day or week"`
+	Timezone   string        `json:"timezone"`
+	Mentions   int           `json:"mentions" jsonschema:"Posts mentioning the keyword over the whole window"`
+	Buckets    []trendBucket `json:"buckets" jsonschema:"Mentions per day or week, oldest first; the last one is still in progress"`
+	Change     *float64      `json:"change,omitempty" jsonschema:"Mentions in the second half of the window over those in the first; 1.5 means half as many again. Left out when the first half had none"`
+	Trend      string        `json:"trend" jsonschema:"growing, declining, steady, or unclear with too few mentions to tell"`
+	Complete   bool          `json:"complete" jsonschema:"Search reached the start of the window in every subreddit; otherwise Reddit's result cap cut the older part short"`
+}
+
+// Count a keyword's mentions over time to tell whether interest is growing
+func handleRedditKeywordTrend(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	opts, err := newFormatOptions(args)
+	if err != nil {
+		return toolError(err), nil
+	}
+	keyword, _ := args["keyword"].(string)
+	keyword = strings.TrimSpace(keyword)
+	if keyword == "" {
+		return toolError(invalidInput("keyword is required")), nil
+	}
+	var subreddits []string
+	if list, ok := args["subreddits"].([]any); ok {
+		for _, v := range list {
+			if name, _ := v.(string); subredditKey(name) != "" && !slices.Contains(subreddits, subredditKey(name)) {
+				subreddits = append(subreddits, subredditKey(name))
+			}
+		}
+	}
+	if len(subreddits) == 0 {
+		return toolError(invalidInput("subreddits needs at least one subreddit")), nil
+	}
+	if len(subreddits) > maxTrendSubreddits {
+		return toolError(invalidInput("a trend covers at most %d subreddits", maxTrendSubreddits)), nil
+	}
+	days := defaultTrendDays
+	if n, ok := args["days"].(float64); ok {
+		days = min(max(int(n), 2), maxTrendDays)
+	}
+	bucket, _ := args["bucket"].(string)
+	if bucket == "" {
+		bucket = "day"
+		if days > 60 {
+			bucket = "week"
+		}
+	}
+	if bucket != "day" && bucket != "week" {
+		return toolError(invalidInput("bucket must be day or week")), nil
+	}
+
+	now := time.Now().In(opts.loc)
+	start := bucketStart(now.AddDate(0, 0, -days), bucket)
+	// Reddit narrows search to coarse periods; the smallest covering the window
+	period := "year"
+	switch {
+	case days <= 7:
+		period = "week"
+	case days <= 31:
+		period = "month"
+	}
+
+	type found struct {
+		posts    listing
+		complete bool
+		err      error
+	}
+	results := make([]found, len(subreddits))
+	var wg sync.WaitGroup
+	for i, subreddit := range subreddits {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			params := url.Values{}
+			params.Set("q", keyword)
+			params.Set("restrict_sr", "1")
+			params.Set("sort", "new")
+			params.Set("t", period)
+			params.Set("limit", fmt.Sprint(exportPageSize))
+			// Newest first, so the first page reaching past the start is the last needed
+			passed := func(page *listing) bool {
+				n := len(page.Children)
+				return n > 0 && time.Unix(int64(page.Children[n-1].Data.CreatedUTC), 0).Before(start)
+			}
+			posts, _, err := pageListing(ctx, fmt.Sprintf("/r/%s/search.json", subreddit), params, maxTrendPosts, "posts in r/"+subreddit, passed)
+			results[i] = found{posts, posts.After == "" || passed(&posts), err}
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return toolError(fmt.Errorf("request cancelled: %w", err)), nil
+	}
+
+	var all listing
+	var failures strings.Builder
+	failed := 0
+	out := &keywordTrendOutput{Keyword: keyword, Subreddits: subreddits, Days: days, Bucket: bucket, Timezone: opts.loc.String(), Complete: true}
+	for i, r := range results {
+		if r.err != nil {
+			failed++
+			fmt.Fprintf(&failures, "r/%s couldn't be searched: %v\n", subreddits[i], r.err)
+			continue
+		}
+		all.Children = append(all.Children, r.posts.Children...)
+		out.Complete = out.Complete && r.complete
+	}
+	if failed == len(results) {
+		return toolError(results[0].err), nil
+	}
+	leftOut := filterPosts(&all, opts)
+	countMentions(out, &all, start, now)
+	return newStructuredResult(out, formatKeywordTrend(out)+leftOut+failures.String()), nil
+}
+
+// The midnight starting the day, or the Monday starting the week, of t
+func bucketStart(t time.Time, bucket string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if bucket == "week" {
+		day = day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	}
+	return day
+}
+
+// Fill in the buckets and the trend from the posts found between start, the
+// beginning of the first bucket, and now
+func countMentions(out *keywordTrendOutput, posts *listing, start, now time.Time) {
+	loc := now.Location()
+	step := 1
+	if out.Bucket == "week" {
+		step = 7
+	}
+	out.Buckets = []trendBucket{}
+	var starts []time.Time
+	for t := start; t.Before(now); t = t.AddDate(0, 0, step) {
+		starts = append(starts, t)
+		out.Buckets = append(out.Buckets, trendBucket{Start: t.Format("2006-01-02")})
+	}
+
+	mid := start.Add(now.Sub(start) / 2)
+	var earlier, later int
+	scores := make([]int, len(out.Buckets))
+	for _, child := range posts.Children {
+		created := time.Unix(int64(child.Data.CreatedUTC), 0).In(loc)
+		if created.Before(start) || created.After(now) {
+			continue
+		}
+		i, found := slices.BinarySearchFunc(starts, created, func(s, t time.Time) int { return s.Compare(t) })
+		if !found {
+			i--
+		}
+		out.Buckets[i].Mentions++
+		out.Buckets[i].Comments += child.Data.NumComments
+		scores[i] += child.Data.Score
+		out.Mentions++
+		if created.Before(mid) {
+			earlier++
+		} else {
+			later++
+		}
+	}
+	for i := range out.Buckets {
+		if n := out.Buckets[i].Mentions; n > 0 {
+			out.Buckets[i].AverageScore = round1(float64(scores[i]) / float64(n))
+		}
+	}
+
+	if earlier > 0 {
+		change := math.Round(float64(later)/float64(earlier)*100) / 100
+		out.Change = &change
+	}
+	switch {
+	case earlier+later < 2*minTrendMentions:
+		out.Trend = "unclear"
+	case float64(later) >= 1.25*float64(earlier):
+		out.Trend = "growing"
+	case float64(later) <= 0.8*float64(earlier):
+		out.Trend = "declining"
+	default:
+		out.Trend = "steady"
+	}
+}
+
+func formatKeywordTrend(out *keywordTrendOutput) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Mentions of %q in r/%s over the last %d days: %d posts\n", out.Keyword, strings.Join(out.Subreddits, ", r/"), out.Days, out.Mentions)
+	switch {
+	case out.Trend == "unclear":
+		sb.WriteString("Too few mentions to call a trend.\n")
+	case out.Change != nil:
+		fmt.Fprintf(&sb, "Trend: %s; the second half of the window had %.0f%% of the first half's mentions\n", out.Trend, *out.Change*100)
+	default:
+		fmt.Fprintf(&sb, "Trend: %s; no mentions in the first half of the window\n", out.Trend)
+	}
+	if !out.Complete {
+		sb.WriteString("Reddit's search ran out before the start of the window, so the oldest buckets undercount.\n")
+	}
+
+	fmt.Fprintf(&sb, "\nBy %s (%s):\n", out.Bucket, out.Timezone)
+	for _, b := range out.Buckets {
+		fmt.Fprintf(&sb, "%s  %3d", b.Start, b.Mentions)
+		if b.Mentions > 0 {
+			fmt.Fprintf(&sb, " %s  average score %g, %d comments", strings.Repeat("#", min(b.Mentions, 50)), b.AverageScore, b.Comments)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
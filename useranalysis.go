@@ -0,0 +1,230 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// Posts and comments sampled by default, and at most; Reddit lists about
+	// the latest 1000 of an account's items
+	defaultUserSample = 200
+	maxUserSample     = 1000
+	// Subreddits and words listed in an analysis
+	maxUserSubreddits = 10
+	maxUserWords      = 20
+)
+
+// Reddit usernames: 3-20 letters, digits, dashes and underscores
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{3,20}$`)
+
+type userSubreddit struct {
+	Name         string  `json:"name"`
+	Posts        int     `json:"posts"`
+	Comments     int     `json:"comments"`
+	AverageScore float64 `json:"average_score"`
+}
+
+type weekdayCount struct {
+	Day   string `json:"day"`
+	Count int    `json:"count"`
+}
+
+type userWord struct {
+	Word  string `json:"word"`
+	Count int    `json:"count"`
+}
+
+// Output of reddit_user_analysis
+type userAnalysisOutput struct {
+	User                string          `json:"user"`
+	Posts               int             `json:"posts" jsonschema:"Posts in the sample"`
+	Comments            int             `json:"comments" jsonschema:"Comments in the sample"`
+	Oldest              string          `json:"oldest,omitempty" jsonschema:"Creation time of the oldest sampled item, in RFC 3339 format"`
+	Newest              string          `json:"newest,omitempty" jsonschema:"Creation time of the newest sampled item, in RFC 3339 format"`
+	Complete            bool            `json:"complete" jsonschema:"The sample holds everything Reddit lists for the account"`
+	AveragePostScore    float64         `json:"average_post_score"`
+	AverageCommentScore float64         `json:"average_comment_score"`
+	Subreddits          []userSubreddit `json:"subreddits" jsonschema:"Subreddits the user is most active in, most posts and comments first"`
+	Timezone            string          `json:"timezone" jsonschema:"Timezone the hours and weekdays are counted in"`
+	ByHour              []int           `json:"by_hour" jsonschema:"Posts and comments per hour of the day, from midnight"`
+	ByWeekday           []weekdayCount  `json:"by_weekday" jsonschema:"Posts and comments per day of the week, from Monday"`
+	Words               []userWord      `json:"words" jsonschema:"Words the user writes most in titles, posts and comments, stopwords left out"`
+}
+
+// Sum up a user's recent posts and comments: where they post, when, how
+// well it's received and what they write about
+func handleRedditUserAnalysis(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	opts, err := newFormatOptions(args)
+	if err != nil {
+		return toolError(err), nil
+	}
+	username, _ := args["username"].(string)
+	username = strings.TrimPrefix(strings.TrimPrefix(username, "/"), "u/")
+	if username == "" {
+		return toolError(invalidInput("username is required")), nil
+	}
+	if !usernamePattern.MatchString(username) {
+		return toolError(invalidInput("%q isn't a Reddit username", username)), nil
+	}
+	sample := defaultUserSample
+	if n, ok := args["limit"].(float64); ok {
+		sample = min(max(int(n), 1), maxUserSample)
+	}
+
+	endpoint := fmt.Sprintf("/user/%s/overview.json", username)
+	params := url.Values{}
+	params.Set("limit", fmt.Sprint(exportPageSize))
+	params.Set("sort", "new")
+	recent, _, err := pageListing(ctx, endpoint, params, sample, "posts and comments", nil)
+	if errors.Is(err, errNotFound) {
+		return toolError(invalidInput("no account u/%s; it may have been deleted or suspended", username)), nil
+	}
+	if err != nil {
+		return toolError(err), nil
+	}
+	out := analyzeUser(username, &recent, opts)
+	return newStructuredResult(out, formatUserAnalysis(out, opts)), nil
+}
+
+func analyzeUser(username string, recent *listing, opts formatOptions) *userAnalysisOutput {
+	out := &userAnalysisOutput{
+		User:      username,
+		Complete:  recent.After == "",
+		Timezone:  opts.loc.String(),
+		ByHour:    make([]int, 24),
+		ByWeekday: make([]weekdayCount, 7),
+	}
+	for i := range out.ByWeekday {
+		out.ByWeekday[i].Day = time.Weekday((i + 1) % 7).String()
+	}
+	type tally struct {
+		subreddit userSubreddit
+		score     int
+	}
+	subreddits := map[string]*tally{}
+	words := map[string]int{}
+	var postScore, commentScore int
+	var oldest, newest float64
+	for _, child := range recent.Children {
+		data := &child.Data
+		t := subreddits[data.Subreddit]
+		if t == nil {
+			t = &tally{subreddit: userSubreddit{Name: data.Subreddit}}
+			subreddits[data.Subreddit] = t
+		}
+		t.score += data.Score
+		text := data.Body
+		if child.Kind == "t3" {
+			out.Posts++
+			postScore += data.Score
+			t.subreddit.Posts++
+			text = data.Title + "\n" + data.Selftext
+		} else {
+			out.Comments++
+			commentScore += data.Score
+			t.subreddit.Comments++
+		}
+		if data.Removal == "" {
+			for _, word := range textTerms(text) {
+				words[word]++
+			}
+		}
+
+		if data.CreatedUTC > 0 {
+			created := time.Unix(int64(data.CreatedUTC), 0).In(opts.loc)
+			out.ByHour[created.Hour()]++
+			out.ByWeekday[(int(created.Weekday())+6)%7].Count++
+			if oldest == 0 || data.CreatedUTC < oldest {
+				oldest = data.CreatedUTC
+			}
+			newest = max(newest, data.CreatedUTC)
+		}
+	}
+	if oldest > 0 {
+		_, out.Oldest = createdTimes(oldest)
+		_, out.Newest = createdTimes(newest)
+	}
+	average := func(total, n int) float64 {
+		if n == 0 {
+			return 0
+		}
+		return math.Round(float64(total)/float64(n)*10) / 10
+	}
+	out.AveragePostScore = average(postScore, out.Posts)
+	out.AverageCommentScore = average(commentScore, out.Comments)
+
+	out.Subreddits = []userSubreddit{}
+	for _, t := range subreddits {
+		t.subreddit.AverageScore = average(t.score, t.subreddit.Posts+t.subreddit.Comments)
+		out.Subreddits = append(out.Subreddits, t.subreddit)
+	}
+	slices.SortFunc(out.Subreddits, func(a, b userSubreddit) int {
+		return cmp.Or(cmp.Compare(b.Posts+b.Comments, a.Posts+a.Comments), strings.Compare(a.Name, b.Name))
+	})
+	out.Subreddits = out.Subreddits[:min(maxUserSubreddits, len(out.Subreddits))]
+
+	ranked := slices.SortedFunc(maps.Keys(words), func(a, b string) int {
+		return cmp.Or(cmp.Compare(words[b], words[a]), strings.Compare(a, b))
+	})
+	out.Words = []userWord{}
+	for _, word := range ranked[:min(maxUserWords, len(ranked))] {
+		out.Words = append(out.Words, userWord{Word: word, Count: words[word]})
+	}
+	return out
+}
+
+func formatUserAnalysis(out *userAnalysisOutput, opts formatOptions) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Activity of %s: %d posts and %d comments", userLabel(out.User), out.Posts, out.Comments)
+	if out.Posts+out.Comments == 0 {
+		sb.WriteString("\nReddit lists nothing recent for this account.\n")
+		return sb.String()
+	}
+	oldest, _ := time.Parse(time.RFC3339, out.Oldest)
+	newest, _ := time.Parse(time.RFC3339, out.Newest)
+	fmt.Fprintf(&sb, " from %s to %s\n", formatUnixTime(oldest.Unix(), opts.loc), formatUnixTime(newest.Unix(), opts.loc))
+	if !out.Complete {
+		sb.WriteString("This is a sample of the latest items; raise limit to look further back.\n")
+	}
+	fmt.Fprintf(&sb, "Average score: %g per post, %g per comment\n\n", out.AveragePostScore, out.AverageCommentScore)
+
+	sb.WriteString("Top subreddits:\n")
+	for _, sub := range out.Subreddits {
+		fmt.Fprintf(&sb, "- r/%s: %d posts, %d comments, average score %g\n", sub.Name, sub.Posts, sub.Comments, sub.AverageScore)
+	}
+
+	fmt.Fprintf(&sb, "\nBy hour (%s):", out.Timezone)
+	for hour, n := range out.ByHour {
+		if hour%6 == 0 {
+			sb.WriteString("\n ")
+		}
+		fmt.Fprintf(&sb, " %02d:00 %d", hour, n)
+	}
+	sb.WriteString("\nBy weekday:")
+	for _, day := range out.ByWeekday {
+		fmt.Fprintf(&sb, " %s %d", day.Day[:3], day.Count)
+	}
+	sb.WriteString("\n")
+
+	if len(out.Words) > 0 {
+		words := make([]string, 0, len(out.Words))
+		for _, w := range out.Words {
+			words = append(words, fmt.Sprintf("%s (%d)", w.Word, w.Count))
+		}
+		fmt.Fprintf(&sb, "\nMost used words: %s\n", strings.Join(words, ", "))
+	}
+	return sb.String()
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+)
+
+// Build information, set with:
+//
+//	go build -ldflags "-X main.version=1.2.3 -X main.commit=$(git rev-parse --short HEAD) -X main.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	version   = "dev"
+	commit    = ""
+	buildDate = ""
+)
+
+// Fall back to the VCS info Go embeds when ldflags weren't used
+func init() {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	if version == "dev" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		version = info.Main.Version
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if commit == "" && len(setting.Value) >= 7 {
+				commit = setting.Value[:7]
+			}
+		case "vcs.time":
+			if buildDate == "" {
+				buildDate = setting.Value
+			}
+		}
+	}
+}
+
+// Full version string for --version and bug reports
+func versionString() string {
+	s := version
+	if commit != "" {
+		s += " (" + commit + ")"
+	}
+	if buildDate != "" {
+		s += " built " + buildDate
+	}
+	return s
+}
+
+// Commit and build date for the server info's description; "" when neither is known
+func buildDescription() string {
+	var parts []string
+	if commit != "" {
+		parts = append(parts, "commit "+commit)
+	}
+	if buildDate != "" {
+		parts = append(parts, "built "+buildDate)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// User-Agent sent to Reddit, which asks clients to identify themselves and their version
+func userAgent() string {
+	return fmt.Sprintf("mcp-reddit-tool/%s", version)
+}
@@ -0,0 +1,666 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// Watches added through tools, on top of those in the config file
+	maxWatches = 50
+	// Unfetched matches kept per watch; older ones are dropped first
+	maxWatchMatches = 100
+	// How long a matched post is remembered so it isn't reported twice
+	watchSeenFor = 7 * 24 * time.Hour
+)
+
+// URI template of a watch's pending matches, and the prefix of its URIs
+const (
+	watchTemplate  = "reddit://watch/{id}"
+	watchURIPrefix = "reddit://watch/"
+)
+
+// A watch rule: new posts in a subreddit whose title or body contains a
+// keyword or matches a pattern, scoring at least MinScore
+type watchRule struct {
+	Subreddit string `json:"subreddit"`
+	Keyword   string `json:"keyword,omitempty"`
+	Pattern   string `json:"pattern,omitempty"`
+	MinScore  int    `json:"min_score,omitempty"`
+
+	// Where to post each match, the body template and the key signing it
+	Webhook         string `json:"webhook,omitempty"`
+	WebhookTemplate string `json:"webhook_template,omitempty"`
+	WebhookSecret   string `json:"webhook_secret,omitempty"`
+}
+
+// Compile the rule's matcher; keywords match case-insensitively
+func (r watchRule) compile() (*regexp.Regexp, error) {
+	switch {
+	case r.Subreddit == "":
+		return nil, errors.New("a watch needs a subreddit")
+	case (r.Keyword == "") == (r.Pattern == ""):
+		return nil, errors.New("a watch needs either a keyword or a pattern")
+	case r.Keyword != "":
+		return regexp.MustCompile("(?i)" + regexp.QuoteMeta(r.Keyword)), r.checkWebhook()
+	}
+	re, err := regexp.Compile(r.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", r.Pattern, err)
+	}
+	return re, r.checkWebhook()
+}
+
+// The rule in words, e.g. `r/golang, keyword "generics", min score 10`
+func (r watchRule) String() string {
+	s := "r/" + subredditKey(r.Subreddit)
+	if r.Keyword != "" {
+		s += fmt.Sprintf(", keyword %q", r.Keyword)
+	} else {
+		s += fmt.Sprintf(", pattern /%s/", r.Pattern)
+	}
+	if r.MinScore > 0 {
+		s += fmt.Sprintf(", min score %d", r.MinScore)
+	}
+	if host := webhookHost(r.Webhook); host != "" {
+		s += ", posted to " + host
+	}
+	return s
+}
+
+// A watch: its rule, the posts it has matched and which of them are still unfetched
+type watch struct {
+	id         string
+	rule       watchRule
+	re         *regexp.Regexp
+	webhook    *template.Template // body template of the rule's webhook, if it has one
+	fromConfig bool
+	owner      string // who added it through a tool, see watchOwner
+	created    time.Time
+
+	seen        map[string]time.Time // matched post IDs, by when they were posted
+	pending     []item
+	recent      []item // latest matches, fetched or not, for the watch's feed
+	matched     int    // all matches since the watch was created
+	lastChecked time.Time
+}
+
+// Whether post is a new match for the watch
+func (w *watch) matches(post *item) bool {
+	if _, ok := w.seen[post.ID]; ok {
+		return false
+	}
+	// Only posts made since the watch was set up; older ones aren't news
+	if time.Unix(int64(post.CreatedUTC), 0).Before(w.created) || post.Score < w.rule.MinScore {
+		return false
+	}
+	return w.re.MatchString(post.Title) || w.re.MatchString(post.Selftext)
+}
+
+// Who a tool call adds watches for and sees the watches of: the client token it
+// authenticated with, or else its session; "" over stdio, which has one client.
+// Watches from the config file are everyone's.
+func watchOwner(ctx context.Context) string {
+	if owner := clientToken(ctx); owner != "" {
+		return owner
+	}
+	if id := listingSessionKey(ctx); id != "" {
+		return "session:" + id
+	}
+	return ""
+}
+
+// Whether owner may see and use the watch
+func (w *watch) visibleTo(owner string) bool {
+	return w.fromConfig || w.owner == owner
+}
+
+// Watches, their pending matches, and the sessions subscribed to each watch's resource
+type watchRegistry struct {
+	mu          sync.Mutex
+	watches     map[string]*watch
+	nextID      int
+	subscribers map[string]map[string]struct{} // session IDs by watch URI
+}
+
+var watches = &watchRegistry{watches: make(map[string]*watch), subscribers: make(map[string]map[string]struct{})}
+
+// URI of the resource holding a watch's pending matches
+func watchURI(id string) string {
+	return watchURIPrefix + id
+}
+
+// Add a watch for owner, returning it; config watches don't count towards maxWatches
+func (r *watchRegistry) add(rule watchRule, owner string, fromConfig bool) (*watch, error) {
+	re, err := rule.compile()
+	if err != nil {
+		return nil, invalidInput("%v", err)
+	}
+	rule.Subreddit = subredditKey(rule.Subreddit)
+	tmpl, _ := rule.webhookTemplate()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !fromConfig {
+		added := 0
+		for _, w := range r.watches {
+			if !w.fromConfig {
+				added++
+			}
+		}
+		if added >= maxWatches {
+			return nil, invalidInput("at most %d watches can be added; remove one first", maxWatches)
+		}
+	}
+	r.nextID++
+	w := &watch{
+		id:         "w" + strconv.Itoa(r.nextID),
+		rule:       rule,
+		re:         re,
+		webhook:    tmpl,
+		fromConfig: fromConfig,
+		owner:      owner,
+		created:    time.Now(),
+		seen:       make(map[string]time.Time),
+	}
+	r.watches[w.id] = w
+	return w, nil
+}
+
+// Remove a watch owner added through a tool
+func (r *watchRegistry) remove(id, owner string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	w, ok := r.watches[id]
+	switch {
+	case !ok || !w.visibleTo(owner):
+		return invalidInput("no watch %q; see watch_list for the IDs", id)
+	case w.fromConfig:
+		return invalidInput("watch %s is defined in the config file; remove it there and reload", id)
+	}
+	delete(r.watches, id)
+	return nil
+}
+
+// The watches owner sees ordered by ID, as copies safe to read without the lock
+func (r *watchRegistry) list(owner string) []watch {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := make([]watch, 0, len(r.watches))
+	for _, w := range r.watches {
+		if !w.visibleTo(owner) {
+			continue
+		}
+		c := *w
+		c.pending = slices.Clone(w.pending)
+		c.recent = slices.Clone(w.recent)
+		list = append(list, c)
+	}
+	sortWatches(list)
+	return list
+}
+
+// Order watches by ID, so w10 comes after w9
+func sortWatches(list []watch) {
+	slices.SortFunc(list, func(a, b watch) int {
+		if d := len(a.id) - len(b.id); d != 0 {
+			return d
+		}
+		return strings.Compare(a.id, b.id)
+	})
+}
+
+// Hand over the pending matches of one of owner's watches, or of all with id
+// "", and mark them fetched
+func (r *watchRegistry) take(id, owner string) ([]watch, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if w, ok := r.watches[id]; id != "" && (!ok || !w.visibleTo(owner)) {
+		return nil, invalidInput("no watch %q; see watch_list for the IDs", id)
+	}
+	var list []watch
+	now := time.Now()
+	for _, w := range r.watches {
+		if (id != "" && w.id != id) || !w.visibleTo(owner) {
+			continue
+		}
+		list = append(list, *w)
+		w.pending, w.lastChecked = nil, now
+	}
+	sortWatches(list)
+	return list, nil
+}
+
+// Replace the config file's watches, keeping the state of rules that didn't change
+func (r *watchRegistry) syncConfig(rules []watchRule) {
+	rules = slices.Clone(rules)
+	for i := range rules {
+		rules[i].Subreddit = subredditKey(rules[i].Subreddit)
+	}
+
+	r.mu.Lock()
+	keep := make(map[watchRule]bool)
+	for id, w := range r.watches {
+		if !w.fromConfig {
+			continue
+		}
+		if slices.Contains(rules, w.rule) {
+			keep[w.rule] = true
+		} else {
+			delete(r.watches, id)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, rule := range rules {
+		if keep[rule] {
+			continue
+		}
+		if _, err := r.add(rule, "", true); err != nil {
+			slog.Warn("Invalid watch in config", "watch", rule.String(), "error", err)
+		}
+	}
+}
+
+// Subreddits with at least one watch
+func (r *watchRegistry) subreddits() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var subs []string
+	for _, w := range r.watches {
+		if !slices.Contains(subs, w.rule.Subreddit) {
+			subs = append(subs, w.rule.Subreddit)
+		}
+	}
+	return subs
+}
+
+// Load the config file's watches and follow them across reloads
+func startWatches(s *server.MCPServer) {
+	watches.syncConfig(currentConfig().Watches)
+	onReload(func(old, cfg *config) {
+		watches.syncConfig(cfg.Watches)
+	})
+	addWatchResources(s)
+}
+
+// Poll the newest posts of watched subreddits for matches. The interval is
+// read from the live config on every pass; 0 pauses polling.
+func pollWatches(ctx context.Context, s *server.MCPServer) {
+	for {
+		interval := time.Duration(currentConfig().WatchPoll) * time.Second
+		if interval > 0 {
+			for _, subreddit := range watches.subreddits() {
+				if ctx.Err() != nil {
+					return
+				}
+				watches.poll(withCacheRefresh(ctx), s, subreddit)
+			}
+		} else {
+			interval = time.Minute
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Fetch a subreddit's newest posts, record new matches of its watches and
+// notify the sessions subscribed to the watches that matched
+func (r *watchRegistry) poll(ctx context.Context, s *server.MCPServer, subreddit string) {
+	var result listing
+	endpoint, params := subredditListingRequest(subreddit, "new", 100)
+	if err := makeRedditRequest(ctx, endpoint, params, &result); err != nil {
+		slog.Warn("Failed to poll watched subreddit", "subreddit", subreddit, "error", err)
+		return
+	}
+
+	var notify []struct{ sessionID, uri string }
+	var deliveries []*webhookDelivery
+	r.mu.Lock()
+	for _, w := range r.watches {
+		if w.rule.Subreddit != subreddit {
+			continue
+		}
+		for id, posted := range w.seen {
+			if time.Since(posted) > watchSeenFor {
+				delete(w.seen, id)
+			}
+		}
+		found := 0
+		// Oldest first, so pending matches read in the order they were posted
+		for i := len(result.Children) - 1; i >= 0; i-- {
+			post := &result.Children[i].Data
+			if !w.matches(post) {
+				continue
+			}
+			w.seen[post.ID] = time.Unix(int64(post.CreatedUTC), 0)
+			w.pending = append(w.pending, *post)
+			w.recent = append(w.recent, *post)
+			w.matched++
+			found++
+		}
+		if extra := len(w.pending) - maxWatchMatches; extra > 0 {
+			w.pending = slices.Delete(w.pending, 0, extra)
+		}
+		if extra := len(w.recent) - maxWatchMatches; extra > 0 {
+			w.recent = w.recent[extra:]
+		}
+		if found > 0 && w.rule.Webhook != "" {
+			client := publicWebhookClient
+			if w.fromConfig {
+				client = webhookClient
+			}
+			deliveries = append(deliveries, &webhookDelivery{
+				watchID: w.id,
+				rule:    w.rule,
+				tmpl:    w.webhook,
+				client:  client,
+				posts:   slices.Clone(w.pending[len(w.pending)-min(found, len(w.pending)):]),
+			})
+		}
+		if found > 0 {
+			for sessionID := range r.subscribers[watchURI(w.id)] {
+				notify = append(notify, struct{ sessionID, uri string }{sessionID, watchURI(w.id)})
+			}
+		}
+	}
+	r.mu.Unlock()
+
+	// Deliveries outlive the poll's context and don't hold up the next subreddit
+	for _, d := range deliveries {
+		go d.send(context.WithoutCancel(ctx))
+	}
+	for _, n := range notify {
+		err := s.SendNotificationToSpecificClient(n.sessionID, mcp.MethodNotificationResourceUpdated, map[string]any{"uri": n.uri})
+		if err != nil {
+			slog.Debug("Failed to notify watch subscriber", "uri", n.uri, "session", n.sessionID, "error", err)
+		}
+	}
+}
+
+// Track resources/subscribe and resources/unsubscribe for watch resources
+func addWatchHooks(hooks *server.Hooks) {
+	hooks.AddAfterSubscribe(func(ctx context.Context, id any, message *mcp.SubscribeRequest, result *mcp.EmptyResult) {
+		session := server.ClientSessionFromContext(ctx)
+		if session == nil || !strings.HasPrefix(message.Params.URI, watchURIPrefix) {
+			return
+		}
+		watches.mu.Lock()
+		defer watches.mu.Unlock()
+		sessions, ok := watches.subscribers[message.Params.URI]
+		if !ok {
+			sessions = make(map[string]struct{})
+			watches.subscribers[message.Params.URI] = sessions
+		}
+		sessions[session.SessionID()] = struct{}{}
+	})
+	hooks.AddAfterUnsubscribe(func(ctx context.Context, id any, message *mcp.UnsubscribeRequest, result *mcp.EmptyResult) {
+		if session := server.ClientSessionFromContext(ctx); session != nil {
+			watches.unsubscribe(session.SessionID(), message.Params.URI)
+		}
+	})
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		watches.mu.Lock()
+		defer watches.mu.Unlock()
+		for uri, sessions := range watches.subscribers {
+			delete(sessions, session.SessionID())
+			if len(sessions) == 0 {
+				delete(watches.subscribers, uri)
+			}
+		}
+		// Nobody else can reach the watches of a session without a client token
+		for id, w := range watches.watches {
+			if w.owner == "session:"+session.SessionID() {
+				delete(watches.watches, id)
+			}
+		}
+	})
+}
+
+func (r *watchRegistry) unsubscribe(sessionID, uri string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if sessions, ok := r.subscribers[uri]; ok {
+		delete(sessions, sessionID)
+		if len(sessions) == 0 {
+			delete(r.subscribers, uri)
+		}
+	}
+}
+
+// Register the resource template of watches' pending matches
+func addWatchResources(s *server.MCPServer) {
+	s.AddResourceTemplate(
+		mcp.NewResourceTemplate(watchTemplate, "Reddit watch matches",
+			mcp.WithTemplateDescription("Posts a watch has matched that haven't been fetched yet; subscribe to hear of new matches"),
+			mcp.WithTemplateMIMEType("text/plain"),
+		),
+		func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			id := resourceArg(request.Params.Arguments, "id")
+			for _, w := range watches.list(watchOwner(ctx)) {
+				if w.id == id {
+					list := []watch{w}
+					opts := defaultFormatOptions()
+					leftOut := filterWatchMatches(list, opts)
+					return textResource(request.Params.URI, formatWatchMatches(list, opts)+leftOut), nil
+				}
+			}
+			return nil, invalidInput("no watch %q; see watch_list for the IDs", id)
+		},
+	)
+}
+
+type watchOutput struct {
+	ID          string `json:"id"`
+	Subreddit   string `json:"subreddit"`
+	Keyword     string `json:"keyword,omitempty"`
+	Pattern     string `json:"pattern,omitempty" jsonschema:"Regular expression matched against titles and bodies"`
+	MinScore    int    `json:"min_score,omitempty"`
+	Webhook     string `json:"webhook_host,omitempty" jsonschema:"Host each match is posted to"`
+	FromConfig  bool   `json:"from_config,omitempty" jsonschema:"Defined in the config file; change or remove it there"`
+	Pending     int    `json:"pending" jsonschema:"Matches not yet fetched with watch_matches"`
+	Matched     int    `json:"matched" jsonschema:"Matches since the watch was set up"`
+	Resource    string `json:"resource" jsonschema:"Resource listing the pending matches; subscribe to it for updates"`
+	LastChecked string `json:"last_checked,omitempty" jsonschema:"When matches were last fetched, in RFC 3339 format"`
+	Feed        string `json:"feed,omitempty" jsonschema:"Path of the Atom feed of the watch's latest matches on this server's HTTP address; left out over stdio"`
+}
+
+// Output of reddit_watch_list
+type watchListOutput struct {
+	PollSeconds int           `json:"poll_seconds" jsonschema:"Seconds between polls of watched subreddits; 0 while polling is paused"`
+	Watches     []watchOutput `json:"watches"`
+}
+
+type watchMatchOutput struct {
+	WatchID string     `json:"watch_id"`
+	Post    postOutput `json:"post"`
+}
+
+// Output of reddit_watch_matches
+type watchMatchesOutput struct {
+	Matches []watchMatchOutput `json:"matches" jsonschema:"Posts matched since the last fetch, oldest first within each watch"`
+}
+
+func newWatchOutput(w *watch) watchOutput {
+	out := watchOutput{
+		ID:         w.id,
+		Subreddit:  w.rule.Subreddit,
+		Keyword:    w.rule.Keyword,
+		Pattern:    w.rule.Pattern,
+		MinScore:   w.rule.MinScore,
+		Webhook:    webhookHost(w.rule.Webhook),
+		FromConfig: w.fromConfig,
+		Pending:    len(w.pending),
+		Matched:    w.matched,
+		Resource:   watchURI(w.id),
+	}
+	if !w.lastChecked.IsZero() {
+		out.LastChecked = w.lastChecked.UTC().Format(time.RFC3339)
+	}
+	if cfg := currentConfig(); cfg.Transport != "stdio" {
+		out.Feed = feedPath(cfg, w.id)
+	}
+	return out
+}
+
+// Add a watch rule
+func handleRedditWatchAdd(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	rule := watchRule{}
+	rule.Subreddit, _ = args["subreddit"].(string)
+	rule.Keyword, _ = args["keyword"].(string)
+	rule.Pattern, _ = args["pattern"].(string)
+	if minScore, ok := args["min_score"].(float64); ok {
+		rule.MinScore = int(minScore)
+	}
+	rule.Webhook, _ = args["webhook"].(string)
+	rule.WebhookTemplate, _ = args["webhook_template"].(string)
+	rule.WebhookSecret, _ = args["webhook_secret"].(string)
+
+	w, err := watches.add(rule, watchOwner(ctx), false)
+	if err != nil {
+		return toolError(err), nil
+	}
+	text := fmt.Sprintf("Added watch %s: %s.\nNew matching posts are collected every %s; fetch them with %s {\"id\": %q}, or subscribe to %s.\n",
+		w.id, w.rule, pollInterval(), toolName(currentConfig(), "watch_matches"), w.id, watchURI(w.id))
+	return newStructuredResult(newWatchOutput(w), text), nil
+}
+
+// List the watches and how many matches each has waiting
+func handleRedditWatchList(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	out := &watchListOutput{PollSeconds: max(currentConfig().WatchPoll, 0), Watches: []watchOutput{}}
+	var sb strings.Builder
+	list := watches.list(watchOwner(ctx))
+	if len(list) == 0 {
+		fmt.Fprintf(&sb, "No watches. Add one with %s.\n", toolName(currentConfig(), "watch_add"))
+	} else {
+		noun := "watches"
+		if len(list) == 1 {
+			noun = "watch"
+		}
+		fmt.Fprintf(&sb, "%d %s, polled every %s:\n\n", len(list), noun, pollInterval())
+	}
+	for i := range list {
+		w := &list[i]
+		out.Watches = append(out.Watches, newWatchOutput(w))
+		fmt.Fprintf(&sb, "%s: %s\n", w.id, w.rule)
+		fmt.Fprintf(&sb, "   Pending matches: %d (of %d in total)", len(w.pending), w.matched)
+		if !w.lastChecked.IsZero() {
+			fmt.Fprintf(&sb, " | Last fetched: %s", relativeTime(w.lastChecked, time.Now()))
+		}
+		sb.WriteString("\n")
+		if feed := out.Watches[i].Feed; feed != "" {
+			fmt.Fprintf(&sb, "   Feed: %s\n", feed)
+		}
+		if w.fromConfig {
+			sb.WriteString("   From the config file\n")
+		}
+		sb.WriteString("\n")
+	}
+	return newStructuredResult(out, sb.String()), nil
+}
+
+// Remove a watch rule
+func handleRedditWatchRemove(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id, _ := request.GetArguments()["id"].(string)
+	if id == "" {
+		return toolError(invalidInput("id is required")), nil
+	}
+	if err := watches.remove(id, watchOwner(ctx)); err != nil {
+		return toolError(err), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Removed watch %s.\n", id)), nil
+}
+
+// Return the posts watches matched since they were last fetched
+func handleRedditWatchMatches(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	opts, err := newFormatOptions(args)
+	if err != nil {
+		return toolError(err), nil
+	}
+	id, _ := args["id"].(string)
+	list, err := watches.take(id, watchOwner(ctx))
+	if err != nil {
+		return toolError(err), nil
+	}
+	leftOut := filterWatchMatches(list, opts)
+
+	out := &watchMatchesOutput{Matches: []watchMatchOutput{}}
+	for _, w := range list {
+		for i := range w.pending {
+			out.Matches = append(out.Matches, watchMatchOutput{WatchID: w.id, Post: *newPostOutput(&w.pending[i], false)})
+		}
+	}
+	return newStructuredResult(out, formatWatchMatches(list, opts)+leftOut), nil
+}
+
+// Apply the listing filters, such as leaving out NSFW posts, to the matches
+func filterWatchMatches(list []watch, opts formatOptions) string {
+	result := &listing{}
+	for _, w := range list {
+		for _, post := range w.pending {
+			result.Children = append(result.Children, thing{Kind: "t3", Data: post})
+		}
+	}
+	note := filterPosts(result, opts)
+	kept := make(map[string]bool, len(result.Children))
+	for _, child := range result.Children {
+		kept[child.Data.ID] = true
+	}
+	for i := range list {
+		list[i].pending = slices.DeleteFunc(list[i].pending, func(post item) bool {
+			return !kept[post.ID]
+		})
+	}
+	return note
+}
+
+// Pending matches grouped by watch, in the search result format
+func formatWatchMatches(list []watch, opts formatOptions) string {
+	var sb strings.Builder
+	for _, w := range list {
+		if len(w.pending) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "Watch %s (%s):\n", w.id, w.rule)
+		result := &listing{}
+		for _, post := range w.pending {
+			result.Children = append(result.Children, thing{Kind: "t3", Data: post})
+		}
+		text, _ := formatSearchResults(result, opts)
+		sb.WriteString(text)
+	}
+	if sb.Len() == 0 {
+		return fmt.Sprintf("No new matches. Watched subreddits are polled every %s.\n", pollInterval())
+	}
+	return sb.String()
+}
+
+// The watch poll interval in words, e.g. "5 minutes"
+func pollInterval() string {
+	d := time.Duration(currentConfig().WatchPoll) * time.Second
+	if d <= 0 {
+		return "never (polling is paused)"
+	}
+	if span := timeSpan(d); span != "" {
+		return span
+	}
+	return fmt.Sprintf("%d seconds", int(d.Seconds()))
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchRuleCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    watchRule
+		wantErr bool
+	}{
+		{"keyword", watchRule{Subreddit: "golang", Keyword: "generics"}, false},
+		{"pattern", watchRule{Subreddit: "golang", Pattern: `go 1\.\d+`}, false},
+		{"no subreddit", watchRule{Keyword: "generics"}, true},
+		{"neither", watchRule{Subreddit: "golang"}, true},
+		{"both", watchRule{Subreddit: "golang", Keyword: "generics", Pattern: "go"}, true},
+		{"bad pattern", watchRule{Subreddit: "golang", Pattern: "go("}, true},
+		{"bad webhook", watchRule{Subreddit: "golang", Keyword: "go", Webhook: "ftp://example.com/hook"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.rule.compile()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("compile() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWatchMatches(t *testing.T) {
+	created := time.Unix(1_700_000_000, 0)
+	after := float64(created.Unix() + 60)
+	tests := []struct {
+		name string
+		rule watchRule
+		post item
+		want bool
+	}{
+		{"keyword in title", watchRule{Keyword: "Generics"}, item{ID: "a", Title: "Using generics", CreatedUTC: after}, true},
+		{"keyword in body", watchRule{Keyword: "generics"}, item{ID: "a", Title: "Question", Selftext: "About GENERICS", CreatedUTC: after}, true},
+		{"keyword missing", watchRule{Keyword: "generics"}, item{ID: "a", Title: "Question", CreatedUTC: after}, false},
+		{"keyword is literal", watchRule{Keyword: "c++"}, item{ID: "a", Title: "ccc", CreatedUTC: after}, false},
+		{"pattern", watchRule{Pattern: `^\[Release\]`}, item{ID: "a", Title: "[Release] v2", CreatedUTC: after}, true},
+		{"posted before the watch", watchRule{Keyword: "go"}, item{ID: "a", Title: "go", CreatedUTC: float64(created.Unix() - 60)}, false},
+		{"below min score", watchRule{Keyword: "go", MinScore: 10}, item{ID: "a", Title: "go", Score: 9, CreatedUTC: after}, false},
+		{"at min score", watchRule{Keyword: "go", MinScore: 10}, item{ID: "a", Title: "go", Score: 10, CreatedUTC: after}, true},
+		{"already seen", watchRule{Keyword: "go"}, item{ID: "seen", Title: "go", CreatedUTC: after}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.rule.Subreddit = "golang"
+			re, err := tt.rule.compile()
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := &watch{rule: tt.rule, re: re, created: created, seen: map[string]time.Time{"seen": created}}
+			if got := w.matches(&tt.post); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatchRegistryOwners(t *testing.T) {
+	r := &watchRegistry{watches: make(map[string]*watch), subscribers: make(map[string]map[string]struct{})}
+	rule := watchRule{Subreddit: "golang", Keyword: "generics"}
+	shared, _ := r.add(rule, "", true)
+	mine, _ := r.add(rule, "token:aa", false)
+	theirs, _ := r.add(rule, "session:s1", false)
+
+	ids := func(list []watch) []string {
+		var out []string
+		for _, w := range list {
+			out = append(out, w.id)
+		}
+		return out
+	}
+	tests := []struct {
+		owner string
+		want  []string
+	}{
+		{"token:aa", []string{shared.id, mine.id}},
+		{"session:s1", []string{shared.id, theirs.id}},
+		{"", []string{shared.id}},
+	}
+	for _, tt := range tests {
+		if got := ids(r.list(tt.owner)); strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("list(%q) = %v, want %v", tt.owner, got, tt.want)
+		}
+	}
+	if _, err := r.take(theirs.id, "token:aa"); err == nil {
+		t.Error("take() handed over another owner's watch")
+	}
+	if err := r.remove(theirs.id, "token:aa"); err == nil {
+		t.Error("remove() removed another owner's watch")
+	}
+	if err := r.remove(shared.id, "token:aa"); err == nil {
+		t.Error("remove() removed a config watch")
+	}
+	if err := r.remove(mine.id, "token:aa"); err != nil {
+		t.Errorf("remove() of an own watch: %v", err)
+	}
+}
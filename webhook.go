@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Each match is posted on its own, retried a few times when the endpoint is
+// unreachable or failing
+const (
+	webhookTimeout  = 10 * time.Second
+	webhookAttempts = 3
+)
+
+// Header carrying the hex HMAC-SHA256 of the body, keyed by the watch's
+// webhook secret, as "sha256=<hex>"
+const webhookSignatureHeader = "X-Signature-256"
+
+// Webhooks from the config file may reach any address, such as a relay on the
+// same host
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// Webhooks given to watch_add come from the client, so like link previews they
+// may only reach public addresses
+var publicWebhookClient = &http.Client{
+	Timeout: webhookTimeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: webhookTimeout,
+			Control: publicAddressOnly,
+		}).DialContext,
+		TLSHandshakeTimeout:   webhookTimeout,
+		ResponseHeaderTimeout: webhookTimeout,
+	},
+}
+
+// What a webhook template is executed with, and the default JSON body
+type webhookPayload struct {
+	Watch string     `json:"watch"`
+	Rule  string     `json:"rule"`
+	Post  postOutput `json:"post"`
+}
+
+// Templates can quote values for JSON bodies, e.g. {"text": {{json .Post.Title}}}
+var webhookFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// Check a rule's webhook URL and template
+func (r watchRule) checkWebhook() error {
+	if r.Webhook == "" {
+		if r.WebhookTemplate != "" || r.WebhookSecret != "" {
+			return errors.New("webhook_template and webhook_secret need a webhook")
+		}
+		return nil
+	}
+	u, err := url.Parse(r.Webhook)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("webhook must be an http or https URL, not %q", r.Webhook)
+	}
+	_, err = r.webhookTemplate()
+	return err
+}
+
+// The parsed body template, or nil to send the default JSON
+func (r watchRule) webhookTemplate() (*template.Template, error) {
+	if r.WebhookTemplate == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New("webhook").Funcs(webhookFuncs).Option("missingkey=error").Parse(r.WebhookTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// Host a webhook posts to, shown instead of the URL, which often embeds a token
+func webhookHost(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		return u.Host
+	}
+	return ""
+}
+
+// A webhook and the matches to post to it
+type webhookDelivery struct {
+	watchID string
+	rule    watchRule
+	tmpl    *template.Template
+	client  *http.Client
+	posts   []item
+}
+
+// Post each match to the watch's webhook in turn, logging the ones that
+// couldn't be delivered. Matches get the default filters, as in its feed:
+// NSFW posts are left out and spoilers hidden
+func (d *webhookDelivery) send(ctx context.Context) {
+	opts := defaultFormatOptions()
+	result := &listing{}
+	for _, post := range d.posts {
+		result.Children = append(result.Children, thing{Kind: "t3", Data: post})
+	}
+	filterPosts(result, opts)
+	for i := range result.Children {
+		post := &result.Children[i].Data
+		redactNSFW(post, opts)
+		redactSpoilers(post, opts)
+		payload := webhookPayload{Watch: d.watchID, Rule: d.rule.String(), Post: *newPostOutput(post, false)}
+		body, contentType, err := d.body(&payload)
+		if err != nil {
+			slog.Warn("Failed to render watch webhook", "watch", d.watchID, "error", err)
+			return
+		}
+		if err := d.post(ctx, body, contentType); err != nil {
+			slog.Warn("Failed to deliver watch webhook", "watch", d.watchID, "host", webhookHost(d.rule.Webhook), "post", payload.Post.ID, "error", err)
+		}
+	}
+}
+
+// The request body for one match; templated bodies that aren't JSON go as plain text
+func (d *webhookDelivery) body(payload *webhookPayload) ([]byte, string, error) {
+	if d.tmpl == nil {
+		b, err := json.Marshal(payload)
+		return b, "application/json", err
+	}
+	var buf bytes.Buffer
+	if err := d.tmpl.Execute(&buf, payload); err != nil {
+		return nil, "", err
+	}
+	if json.Valid(buf.Bytes()) {
+		return buf.Bytes(), "application/json", nil
+	}
+	return buf.Bytes(), "text/plain; charset=utf-8", nil
+}
+
+// Post body, retrying network errors, 429s and 5xx responses with backoff
+func (d *webhookDelivery) post(ctx context.Context, body []byte, contentType string) error {
+	var err error
+	for attempt := range webhookAttempts {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(1<<attempt) * time.Second):
+			}
+		}
+		var retry bool
+		if retry, err = d.attempt(ctx, body, contentType); err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+func (d *webhookDelivery) attempt(ctx context.Context, body []byte, contentType string) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.rule.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", userAgent())
+	req.Header.Set("X-Watch-ID", d.watchID)
+	if d.rule.WebhookSecret != "" {
+		mac := hmac.New(sha256.New, []byte(d.rule.WebhookSecret))
+		mac.Write(body)
+		req.Header.Set(webhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		// Refused addresses won't become public on a retry
+		return !strings.Contains(err.Error(), "is not public"), err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	return false, nil
+}
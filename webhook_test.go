@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWebhookDeliveryFilters(t *testing.T) {
+	var mu sync.Mutex
+	var got []webhookPayload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload webhookPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("body %q isn't a payload: %v", body, err)
+		}
+		mu.Lock()
+		got = append(got, payload)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	d := &webhookDelivery{
+		watchID: "w1",
+		rule:    watchRule{Subreddit: "golang", Keyword: "go", Webhook: srv.URL},
+		client:  srv.Client(),
+		posts: []item{
+			{ID: "plain", Title: "go"},
+			{ID: "nsfw", Title: "go", Over18: true},
+			{ID: "spoiler", Title: "go", Spoiler: true},
+		},
+	}
+	d.send(context.Background())
+
+	var ids []string
+	for _, payload := range got {
+		ids = append(ids, payload.Post.ID)
+	}
+	if strings.Join(ids, " ") != "plain spoiler" {
+		t.Errorf("delivered %v, want the NSFW post left out", ids)
+	}
+}